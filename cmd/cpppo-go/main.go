@@ -22,7 +22,7 @@ var (
 	dataType = flag.String("type", "DINT", "Data type (BOOL, SINT, INT, DINT, REAL)")
 	value    = flag.String("value", "", "Value to write (for write mode)")
 	register = flag.Int("register", 0, "Register number (for FANUC mode)")
-	regType  = flag.String("regtype", "R", "Register type (R, PR, DI, DO, etc.)")
+	regType  = flag.String("regtype", "R", "Register type (R, PR, DI, DO, AI, AO, GI, GO, UI, UO, SI, SO, RI, RO, WI, WO, F, M, UR, SR, VR)")
 	logType  = flag.String("logtype", "ALARM", "Log type to monitor (ALARM, ERROR, EVENT, etc.)")
 	fanucOpt = flag.Bool("fanuc", false, "Use FANUC-specific features")
 )
@@ -177,8 +177,23 @@ func runFanucMode(address string) {
 				// For other register types, convert the value
 				switch regTypeEnum {
 				case fanuc.RegisterTypeR:
+					// Whole numbers are stored as integers, as on the teach pendant
+					typedValue, err = convertValue(*value, "DINT")
+					if err != nil {
+						typedValue, err = convertValue(*value, "REAL")
+					}
+				case fanuc.RegisterTypeAO:
 					typedValue, err = convertValue(*value, "REAL")
-				case fanuc.RegisterTypeDI, fanuc.RegisterTypeDO:
+				case fanuc.RegisterTypeSR:
+					typedValue, err = convertValue(*value, "STRING")
+				case fanuc.RegisterTypeGO, fanuc.RegisterTypeGI:
+					typedValue, err = convertValue(*value, "DINT")
+				case fanuc.RegisterTypeDI, fanuc.RegisterTypeDO,
+					fanuc.RegisterTypeUI, fanuc.RegisterTypeUO,
+					fanuc.RegisterTypeSI, fanuc.RegisterTypeSO,
+					fanuc.RegisterTypeRI, fanuc.RegisterTypeRO,
+					fanuc.RegisterTypeWI, fanuc.RegisterTypeWO,
+					fanuc.RegisterTypeF, fanuc.RegisterTypeM:
 					typedValue, err = convertValue(*value, "BOOL")
 				default:
 					typedValue, err = convertValue(*value, *dataType)
@@ -343,6 +358,26 @@ func getRegisterType(regType string) fanuc.RegisterType {
 		return fanuc.RegisterTypeSR
 	case "VR":
 		return fanuc.RegisterTypeVR
+	case "UI":
+		return fanuc.RegisterTypeUI
+	case "UO":
+		return fanuc.RegisterTypeUO
+	case "SI":
+		return fanuc.RegisterTypeSI
+	case "SO":
+		return fanuc.RegisterTypeSO
+	case "RI":
+		return fanuc.RegisterTypeRI
+	case "RO":
+		return fanuc.RegisterTypeRO
+	case "WI":
+		return fanuc.RegisterTypeWI
+	case "WO":
+		return fanuc.RegisterTypeWO
+	case "F":
+		return fanuc.RegisterTypeF
+	case "M":
+		return fanuc.RegisterTypeM
 	default:
 		log.Fatalf("Unsupported register type: %s", regType)
 		return fanuc.RegisterTypeR
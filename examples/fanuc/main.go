@@ -65,7 +65,7 @@ func main() {
 	fmt.Println("Application shutdown complete")
 }
 
-// monitorRegisters continuously monitors and logs register values
+// monitorRegisters subscribes to registers and I/O and logs their changes
 func monitorRegisters(ctx context.Context, wg *sync.WaitGroup, client *fanuc.FanucClient) {
 	defer wg.Done()
 
@@ -73,50 +73,61 @@ func monitorRegisters(ctx context.Context, wg *sync.WaitGroup, client *fanuc.Fan
 
 	// These are common registers you might want to monitor
 	// Adjust based on your specific robot program
-	registerIndices := []int{1, 2, 3, 4, 5}
+	var subs []fanuc.Subscription
+	for i := 1; i <= 5; i++ {
+		subs = append(subs,
+			fanuc.Subscription{
+				Ref:      fanuc.RegisterRef{Type: fanuc.RegisterTypeR, Index: i},
+				Interval: time.Second,
+				Deadband: 0.01,
+			},
+			fanuc.Subscription{
+				Ref:      fanuc.RegisterRef{Type: fanuc.RegisterTypeDI, Index: i},
+				Interval: 100 * time.Millisecond,
+			},
+		)
+	}
+
+	changes, err := client.Subscribe(ctx, subs...)
+	if err != nil {
+		fmt.Printf("Error subscribing to registers: %v\n", err)
+		return
+	}
 
-	ticker := time.NewTicker(1 * time.Second)
-	defer ticker.Stop()
+	// Follow the robot's current position (CURPOS) as well
+	positions, err := client.StreamPosition(ctx, fanuc.StreamOptions{Interval: time.Second})
+	if err != nil {
+		fmt.Printf("Error streaming the current position: %v\n", err)
+		return
+	}
 
-	for {
+	for changes != nil || positions != nil {
 		select {
-		case <-ctx.Done():
-			fmt.Println("Register monitoring stopped")
-			return
-		case <-ticker.C:
-			// Read numeric registers (R)
-			for _, index := range registerIndices {
-				value, err := client.ReadRRegister(index)
-				if err != nil {
-					fmt.Printf("Error reading R[%d]: %v\n", index, err)
-					continue
-				}
-				fmt.Printf("R[%d] = %.2f\n", index, value)
+		case change, ok := <-changes:
+			if !ok {
+				changes = nil
+				continue
 			}
-
-			// Read a position register (PR)
-			prIndex := 1 // First position register
-			position, err := client.ReadPositionRegister(prIndex)
-			if err != nil {
-				fmt.Printf("Error reading PR[%d]: %v\n", prIndex, err)
-			} else {
-				fmt.Printf("PR[%d] = X:%.2f Y:%.2f Z:%.2f W:%.2f P:%.2f R:%.2f Config:%s\n",
-					prIndex, position.X, position.Y, position.Z, position.W, position.P, position.R, position.Config)
+			if change.Err != nil {
+				fmt.Printf("Error reading %s: %v\n", change.Ref, change.Err)
+				continue
 			}
-
-			// Read digital inputs/outputs
-			for i := 1; i <= 5; i++ {
-				di, err := client.ReadDIRegister(i)
-				if err != nil {
-					fmt.Printf("Error reading DI[%d]: %v\n", i, err)
-				} else {
-					fmt.Printf("DI[%d] = %v\n", i, di)
-				}
+			fmt.Printf("%s: %v -> %v\n", change.Ref, change.Old, change.New)
+		case sample, ok := <-positions:
+			if !ok {
+				positions = nil
+				continue
 			}
-
-			fmt.Println("-------------------------------------------")
+			if sample.Err != nil {
+				fmt.Printf("Error reading current position: %v\n", sample.Err)
+				continue
+			}
+			p := sample.Position
+			fmt.Printf("CURPOS = X:%.2f Y:%.2f Z:%.2f W:%.2f P:%.2f R:%.2f\n", p.X, p.Y, p.Z, p.W, p.P, p.R)
 		}
 	}
+
+	fmt.Println("Register monitoring stopped")
 }
 
 // monitorLogs reads and displays log entries from the Fanuc controller
@@ -189,7 +200,7 @@ func writeToPositionRegister(client *fanuc.FanucClient) {
 		W:      180.0,
 		P:      0.0,
 		R:      0.0,
-		Config: "N U T, 0, 0, 0", // Sample configuration string
+		Config: fanuc.Configuration{}, // N U T, 0, 0, 0
 	}
 
 	// Write to position register 1
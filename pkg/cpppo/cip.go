@@ -8,18 +8,28 @@ import (
 
 // CIP Service Codes
 const (
-	CIPServiceGetAttributeAll  = 0x01
-	CIPServiceGetAttributeList = 0x03
-	CIPServiceSetAttributeList = 0x04
-	CIPServiceReset            = 0x05
-	CIPServiceStart            = 0x06
-	CIPServiceStop             = 0x07
-	CIPServiceCreate           = 0x08
-	CIPServiceDelete           = 0x09
-	CIPServiceMultipleService  = 0x0A
-	CIPServiceReadTag          = 0x4C
-	CIPServiceWriteTag         = 0x4D
-	CIPServiceReadModify       = 0x4E
+	CIPServiceGetAttributeAll    = 0x01
+	CIPServiceGetAttributeList   = 0x03
+	CIPServiceSetAttributeList   = 0x04
+	CIPServiceReset              = 0x05
+	CIPServiceStart              = 0x06
+	CIPServiceStop               = 0x07
+	CIPServiceCreate             = 0x08
+	CIPServiceDelete             = 0x09
+	CIPServiceMultipleService    = 0x0A
+	CIPServiceGetAttributeSingle = 0x0E
+	CIPServiceSetAttributeSingle = 0x10
+	CIPServiceReadTag            = 0x4C
+	CIPServiceWriteTag           = 0x4D
+	CIPServiceReadModify         = 0x4E
+
+	CIPServiceGetInstanceAttributeList = 0x55
+)
+
+// CIP Object Classes
+const (
+	CIPClassMessageRouter = 0x02
+	CIPClassSymbol        = 0x6B
 )
 
 // CIP Path Types
@@ -31,6 +41,17 @@ const (
 	CIPPathTypeANSI     = 0x92
 )
 
+// CIP Logical Segments
+const (
+	CIPSegmentClass8      = 0x20
+	CIPSegmentClass16     = 0x21
+	CIPSegmentInstance8   = 0x24
+	CIPSegmentInstance16  = 0x25
+	CIPSegmentInstance32  = 0x26
+	CIPSegmentAttribute8  = 0x30
+	CIPSegmentAttribute16 = 0x31
+)
+
 // CIP Data Types
 const (
 	CIPDataTypeBOOL   = 0xC1
@@ -52,6 +73,25 @@ func (e CIPError) Error() string {
 	return fmt.Sprintf("CIP Error: %#x - %s", e.Code, e.ExtendedMsg)
 }
 
+// DataTypeMismatchError is returned when a read reply carries a different
+// data type than the one requested. Actual tells the caller how to read the
+// tag instead, and Value decodes what the reply carried.
+type DataTypeMismatchError struct {
+	Expected byte
+	Actual   byte
+	Data     []byte // Value in the reply, encoded as Actual
+}
+
+func (e DataTypeMismatchError) Error() string {
+	return fmt.Sprintf("data type mismatch: expected %#x, got %#x", e.Expected, e.Actual)
+}
+
+// Value decodes the value in the reply as its actual data type, so the tag
+// need not be read again
+func (e DataTypeMismatchError) Value() (interface{}, error) {
+	return DecodeValue(e.Actual, e.Data)
+}
+
 // CIPStatusToError converts a CIP status code to an error
 func CIPStatusToError(status byte) error {
 	if status == 0 {
@@ -179,10 +219,55 @@ func BuildCIPPath(tagName string) []byte {
 	return path
 }
 
+// BuildCIPLogicalPath creates a CIP path addressing an instance of a class
+func BuildCIPLogicalPath(class uint16, instance uint32) []byte {
+	var path []byte
+
+	// Class segment, 8-bit when it fits, otherwise padded 16-bit
+	if class <= 0xFF {
+		path = append(path, CIPSegmentClass8, byte(class))
+	} else {
+		path = append(path, CIPSegmentClass16, 0, byte(class), byte(class>>8))
+	}
+
+	// Instance segment, sized to the smallest format that holds the ID
+	switch {
+	case instance <= 0xFF:
+		path = append(path, CIPSegmentInstance8, byte(instance))
+	case instance <= 0xFFFF:
+		path = append(path, CIPSegmentInstance16, 0, byte(instance), byte(instance>>8))
+	default:
+		path = append(path, CIPSegmentInstance32, 0)
+		path = binary.LittleEndian.AppendUint32(path, instance)
+	}
+
+	return path
+}
+
+// BuildCIPAttributePath creates a CIP path addressing one attribute of an
+// instance of a class
+func BuildCIPAttributePath(class uint16, instance uint32, attribute uint16) []byte {
+	path := BuildCIPLogicalPath(class, instance)
+
+	if attribute <= 0xFF {
+		return append(path, CIPSegmentAttribute8, byte(attribute))
+	}
+	return append(path, CIPSegmentAttribute16, 0, byte(attribute), byte(attribute>>8))
+}
+
+// BuildCIPSymbolInstancePath creates a CIP path addressing a tag by its
+// Symbol Object instance ID instead of its name
+func BuildCIPSymbolInstancePath(instance uint32) []byte {
+	return BuildCIPLogicalPath(CIPClassSymbol, instance)
+}
+
 // BuildCIPReadRequest creates a CIP read request for a tag
 func BuildCIPReadRequest(tagName string, elements uint16) []byte {
-	path := BuildCIPPath(tagName)
+	return BuildCIPReadRequestPath(BuildCIPPath(tagName), elements)
+}
 
+// BuildCIPReadRequestPath creates a CIP read request for an already encoded path
+func BuildCIPReadRequestPath(path []byte, elements uint16) []byte {
 	// Create the request
 	request := make([]byte, 4+len(path))
 
@@ -203,8 +288,11 @@ func BuildCIPReadRequest(tagName string, elements uint16) []byte {
 
 // BuildCIPWriteRequest creates a CIP write request for a tag
 func BuildCIPWriteRequest(tagName string, dataType byte, data []byte) []byte {
-	path := BuildCIPPath(tagName)
+	return BuildCIPWriteRequestPath(BuildCIPPath(tagName), dataType, data)
+}
 
+// BuildCIPWriteRequestPath creates a CIP write request for an already encoded path
+func BuildCIPWriteRequestPath(path []byte, dataType byte, data []byte) []byte {
 	// Create the request
 	request := make([]byte, 4+len(path)+len(data))
 
@@ -229,6 +317,26 @@ func BuildCIPWriteRequest(tagName string, dataType byte, data []byte) []byte {
 	return request
 }
 
+// BuildCIPGetAttributeSingleRequest creates a Get Attribute Single request
+func BuildCIPGetAttributeSingleRequest(class uint16, instance uint32, attribute uint16) []byte {
+	path := BuildCIPAttributePath(class, instance, attribute)
+
+	// Service code, path size in words, path
+	request := []byte{CIPServiceGetAttributeSingle, byte(len(path) / 2)}
+	return append(request, path...)
+}
+
+// BuildCIPSetAttributeSingleRequest creates a Set Attribute Single request
+// carrying the raw attribute value
+func BuildCIPSetAttributeSingleRequest(class uint16, instance uint32, attribute uint16, data []byte) []byte {
+	path := BuildCIPAttributePath(class, instance, attribute)
+
+	// Service code, path size in words, path, attribute value
+	request := []byte{CIPServiceSetAttributeSingle, byte(len(path) / 2)}
+	request = append(request, path...)
+	return append(request, data...)
+}
+
 // ParseCIPResponse parses a CIP response
 func ParseCIPResponse(response []byte) ([]byte, error) {
 	if len(response) < 2 {
@@ -269,69 +377,8 @@ func ParseCIPReadResponse(response []byte, dataType byte) (interface{}, error) {
 	// Check that the data type matches what we expect
 	respDataType := data[0]
 	if respDataType != dataType {
-		return nil, fmt.Errorf("data type mismatch: expected %#x, got %#x", dataType, respDataType)
-	}
-
-	// Get the data based on the type
-	switch dataType {
-	case CIPDataTypeBOOL:
-		if len(data) < 3 {
-			return nil, errors.New("not enough data for BOOL")
-		}
-		return data[2] != 0, nil
-
-	case CIPDataTypeSINT:
-		if len(data) < 3 {
-			return nil, errors.New("not enough data for SINT")
-		}
-		return int8(data[2]), nil
-
-	case CIPDataTypeINT:
-		if len(data) < 4 {
-			return nil, errors.New("not enough data for INT")
-		}
-		return int16(binary.LittleEndian.Uint16(data[2:4])), nil
-
-	case CIPDataTypeDINT:
-		if len(data) < 6 {
-			return nil, errors.New("not enough data for DINT")
-		}
-		return int32(binary.LittleEndian.Uint32(data[2:6])), nil
-
-	case CIPDataTypeREAL:
-		if len(data) < 6 {
-			return nil, errors.New("not enough data for REAL")
-		}
-		bits := binary.LittleEndian.Uint32(data[2:6])
-		return float32FromUint32(bits), nil
-
-	case CIPDataTypeDWORD:
-		if len(data) < 6 {
-			return nil, errors.New("not enough data for DWORD")
-		}
-		return binary.LittleEndian.Uint32(data[2:6]), nil
-
-	case CIPDataTypeSTRING:
-		if len(data) < 4 {
-			return nil, errors.New("not enough data for STRING header")
-		}
-		length := binary.LittleEndian.Uint16(data[2:4])
-		if len(data) < int(4+length) {
-			return nil, errors.New("string data truncated")
-		}
-		return string(data[4 : 4+length]), nil
-
-	default:
-		return data[2:], nil
+		return nil, DataTypeMismatchError{Expected: dataType, Actual: respDataType, Data: data[2:]}
 	}
-}
-
-// Helper function to convert uint32 to float32 (IEEE 754)
-func float32FromUint32(bits uint32) float32 {
-	return float32FromUint32Go(bits)
-}
 
-// Go implementation for float32 conversion
-func float32FromUint32Go(bits uint32) float32 {
-	return float32(bits)
+	return DecodeValue(dataType, data[2:])
 }
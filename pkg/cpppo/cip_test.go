@@ -3,6 +3,7 @@ package cpppo
 import (
 	"bytes"
 	"encoding/binary"
+	"errors"
 	"testing"
 )
 
@@ -93,8 +94,9 @@ func TestBuildCIPWriteRequest(t *testing.T) {
 		t.Errorf("Expected service code %#x, got %#x", CIPServiceWriteTag, request[0])
 	}
 
-	// Calculate path length
-	pathLength := 2 // Symbolic segment byte and length byte
+	// Calculate path length, offset past the service code and path size
+	pathLength := 2 // Service code and path size
+	pathLength += 2 // Symbolic segment byte and length byte
 	pathLength += len(tag)
 	if len(tag)%2 != 0 {
 		pathLength++ // Add padding
@@ -178,9 +180,56 @@ func TestParseCIPReadResponse(t *testing.T) {
 	}
 
 	// Test data type mismatch
-	mismatchResp := []byte{0xCC, 0x00, CIPDataTypeREAL, 0x01, 0, 0, 0, 0} // Success, REAL, but expected DINT
+	mismatchResp := append([]byte{0xCC, 0x00, CIPDataTypeREAL, 0x01}, EncodeFloat32(2.5)...) // Success, REAL, but expected DINT
 	_, err = ParseCIPReadResponse(mismatchResp, CIPDataTypeDINT)
-	if err == nil {
-		t.Error("Expected error for data type mismatch, got nil")
+	var mismatch DataTypeMismatchError
+	if !errors.As(err, &mismatch) || mismatch.Actual != CIPDataTypeREAL {
+		t.Errorf("Expected data type mismatch reporting REAL, got %v", err)
+	}
+	if value, err := mismatch.Value(); err != nil || value != float32(2.5) {
+		t.Errorf("Expected the mismatched reply to decode as 2.5, got %v (%v)", value, err)
+	}
+}
+
+func TestBuildCIPLogicalPath(t *testing.T) {
+	tests := []struct {
+		class        uint16
+		instance     uint32
+		expectedPath []byte
+	}{
+		{CIPClassSymbol, 5, []byte{CIPSegmentClass8, 0x6B, CIPSegmentInstance8, 5}},
+		{CIPClassSymbol, 0x1234, []byte{CIPSegmentClass8, 0x6B, CIPSegmentInstance16, 0, 0x34, 0x12}},
+		{CIPClassSymbol, 0x12345678, []byte{CIPSegmentClass8, 0x6B, CIPSegmentInstance32, 0, 0x78, 0x56, 0x34, 0x12}},
+		{0x0300, 1, []byte{CIPSegmentClass16, 0, 0x00, 0x03, CIPSegmentInstance8, 1}},
+	}
+
+	for _, tc := range tests {
+		path := BuildCIPLogicalPath(tc.class, tc.instance)
+		if !bytes.Equal(path, tc.expectedPath) {
+			t.Errorf("For class %#x instance %#x, expected path %v, got %v", tc.class, tc.instance, tc.expectedPath, path)
+		}
+		if len(path)%2 != 0 {
+			t.Errorf("For class %#x instance %#x, path is not word aligned: %v", tc.class, tc.instance, path)
+		}
+	}
+}
+
+func TestBuildCIPAttributeRequests(t *testing.T) {
+	get := BuildCIPGetAttributeSingleRequest(0x6C, 5, 1)
+	expected := []byte{CIPServiceGetAttributeSingle, 3, CIPSegmentClass8, 0x6C, CIPSegmentInstance8, 5, CIPSegmentAttribute8, 1}
+	if !bytes.Equal(get, expected) {
+		t.Errorf("Expected Get Attribute Single request %v, got %v", expected, get)
+	}
+
+	set := BuildCIPSetAttributeSingleRequest(0x6C, 0x100, 0x200, []byte{0xAA, 0xBB})
+	expected = []byte{
+		CIPServiceSetAttributeSingle, 5,
+		CIPSegmentClass8, 0x6C,
+		CIPSegmentInstance16, 0, 0x00, 0x01,
+		CIPSegmentAttribute16, 0, 0x00, 0x02,
+		0xAA, 0xBB,
+	}
+	if !bytes.Equal(set, expected) {
+		t.Errorf("Expected Set Attribute Single request %v, got %v", expected, set)
 	}
 }
@@ -1,10 +1,10 @@
 package cpppo
 
 import (
+	"context"
 	"encoding/binary"
 	"errors"
 	"fmt"
-	"io"
 	"net"
 	"sync"
 	"time"
@@ -13,6 +13,7 @@ import (
 // Constants for EtherNet/IP protocol
 const (
 	EIPCommandNOP             = 0x0000
+	EIPCommandListServices    = 0x0004
 	EIPCommandListIdentity    = 0x0063
 	EIPCommandListInterfaces  = 0x0064
 	EIPCommandRegisterSession = 0x0065
@@ -35,12 +36,81 @@ type EIPHeader struct {
 	Options       uint32
 }
 
+// ErrConnectionLost is returned when a request fails because the connection
+// to the device broke; the next request redials if reconnection is enabled
+var ErrConnectionLost = errors.New("connection lost")
+
+// ErrClientClosed is returned for requests on a closed client
+var ErrClientClosed = errors.New("client closed")
+
+// ConnState describes the state of the connection to the device
+type ConnState int
+
+const (
+	StateDisconnected ConnState = iota // No connection, next request redials
+	StateConnecting                    // Dialing and registering a session
+	StateConnected                     // Connected and ready for requests
+	StateClosed                        // Closed by the application
+)
+
+// String returns the name of the connection state
+func (s ConnState) String() string {
+	switch s {
+	case StateDisconnected:
+		return "disconnected"
+	case StateConnecting:
+		return "connecting"
+	case StateConnected:
+		return "connected"
+	case StateClosed:
+		return "closed"
+	default:
+		return fmt.Sprintf("ConnState(%d)", int(s))
+	}
+}
+
+// ReconnectPolicy controls how a Client recovers from a broken connection
+type ReconnectPolicy struct {
+	MaxAttempts  int           // Redial attempts per reconnection, 0 disables reconnection
+	InitialDelay time.Duration // Delay after the first failed attempt
+	MaxDelay     time.Duration // Upper bound for the delay between attempts
+	Jitter       float64       // Fraction of each delay that is randomized (0..1)
+	RetryReads   bool          // Retry idempotent reads once after the connection is lost
+}
+
+// DefaultReconnectPolicy is the policy used by clients created with NewClient
+var DefaultReconnectPolicy = ReconnectPolicy{
+	MaxAttempts:  5,
+	InitialDelay: 100 * time.Millisecond,
+	MaxDelay:     5 * time.Second,
+	Jitter:       0.2,
+	RetryReads:   true,
+}
+
 // Client represents a CPPPO client
 type Client struct {
 	conn          net.Conn
+	address       string
 	sessionHandle uint32
 	timeout       time.Duration
 	mu            sync.Mutex
+
+	policy        ReconnectPolicy
+	wantSession   bool    // Re-register a session after reconnecting
+	redial        *redial // Reconnection in progress, if any
+	closed        bool
+	lastActivity  time.Time
+	keepalive     *keepalive
+	state         ConnState
+	stateHandlers []func(ConnState)
+	pendingStates []ConnState
+
+	framer              framer
+	sequence            uint64 // Sender context of the last request
+	discarded           int    // Late replies dropped since creation
+	silentTimeouts      int    // Consecutive timeouts with nothing received
+	unsolicitedHandlers []func(EIPHeader, []byte)
+	pendingUnsolicited  []unsolicitedPacket
 }
 
 // NewClient creates a new CPPPO client
@@ -61,338 +131,479 @@ func NewClient(address string, timeout time.Duration) (*Client, error) {
 
 	return &Client{
 		conn:    conn,
+		address: address,
 		timeout: timeout,
+		policy:  DefaultReconnectPolicy,
+		state:   StateConnected,
 	}, nil
 }
 
-// Close closes the connection
-func (c *Client) Close() error {
-	if c.sessionHandle != 0 {
-		err := c.unregisterSession()
-		if err != nil {
-			return err
-		}
-	}
-	return c.conn.Close()
+// SetReconnectPolicy replaces the policy used to recover broken connections
+func (c *Client) SetReconnectPolicy(policy ReconnectPolicy) {
+	c.mu.Lock()
+	defer c.unlock()
+	c.policy = policy
 }
 
-// RegisterSession registers a new session with the EIP server
-func (c *Client) RegisterSession() error {
+// ReconnectPolicy returns the policy used to recover broken connections
+func (c *Client) ReconnectPolicy() ReconnectPolicy {
 	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	if c.sessionHandle != 0 {
-		return nil // Already registered
-	}
-
-	header := EIPHeader{
-		Command: EIPCommandRegisterSession,
-		Length:  4, // Protocol version + options flag
-	}
+	defer c.unlock()
+	return c.policy
+}
 
-	// Buffer to hold the header and data
-	data := make([]byte, 24+4) // Header (24) + data (4)
-	
-	// Write header to buffer
-	binary.LittleEndian.PutUint16(data[0:2], header.Command)
-	binary.LittleEndian.PutUint16(data[2:4], header.Length)
-	binary.LittleEndian.PutUint32(data[4:8], header.SessionHandle)
-	binary.LittleEndian.PutUint32(data[8:12], header.Status)
-	copy(data[12:20], header.SenderContext[:])
-	binary.LittleEndian.PutUint32(data[20:24], header.Options)
+// OnStateChange registers a handler that is called whenever the connection
+// state changes. Handlers run outside the client lock and may use the client.
+func (c *Client) OnStateChange(handler func(ConnState)) {
+	c.mu.Lock()
+	defer c.unlock()
+	c.stateHandlers = append(c.stateHandlers, handler)
+}
 
-	// Protocol version (1.1) and options flag (0)
-	binary.LittleEndian.PutUint16(data[24:26], 1)
-	binary.LittleEndian.PutUint16(data[26:28], 0)
+// State returns the current connection state
+func (c *Client) State() ConnState {
+	c.mu.Lock()
+	defer c.unlock()
+	return c.state
+}
 
-	// Set deadline for write
-	if err := c.conn.SetWriteDeadline(time.Now().Add(c.timeout)); err != nil {
-		return fmt.Errorf("failed to set write deadline: %w", err)
+// setState records a state change for delivery once the lock is released
+func (c *Client) setState(state ConnState) {
+	if c.state == state {
+		return
 	}
+	c.state = state
+	c.pendingStates = append(c.pendingStates, state)
+}
 
-	// Send register session request
-	if _, err := c.conn.Write(data); err != nil {
-		return fmt.Errorf("failed to send register session request: %w", err)
+// unlock releases the client lock and then delivers queued state changes,
+// so handlers are free to call back into the client
+func (c *Client) unlock() {
+	states := c.pendingStates
+	c.pendingStates = nil
+	handlers := c.stateHandlers
+	packets := c.pendingUnsolicited
+	c.pendingUnsolicited = nil
+	unsolicitedHandlers := c.unsolicitedHandlers
+	c.mu.Unlock()
+
+	for _, state := range states {
+		for _, handler := range handlers {
+			handler(state)
+		}
 	}
 
-	// Set deadline for read
-	if err := c.conn.SetReadDeadline(time.Now().Add(c.timeout)); err != nil {
-		return fmt.Errorf("failed to set read deadline: %w", err)
+	for _, packet := range packets {
+		for _, handler := range unsolicitedHandlers {
+			handler(packet.header, packet.data)
+		}
 	}
+}
 
-	// Read response
-	respHeader := make([]byte, 28) // Header (24) + protocol version and flags (4)
-	if _, err := io.ReadFull(c.conn, respHeader); err != nil {
-		return fmt.Errorf("failed to read response: %w", err)
+// fail tears down a connection after an I/O error, so that the next request
+// starts from a fresh connection instead of a desynchronized stream
+func (c *Client) fail(err error) error {
+	if c.conn != nil {
+		c.conn.Close()
+		c.conn = nil
 	}
+	c.framer.reset()
+	c.sessionHandle = 0
+	c.silentTimeouts = 0
+	c.setState(StateDisconnected)
+	return fmt.Errorf("%w: %w", ErrConnectionLost, err)
+}
 
-	// Parse response header
-	respCmd := binary.LittleEndian.Uint16(respHeader[0:2])
-	respLen := binary.LittleEndian.Uint16(respHeader[2:4])
-	respSessionHandle := binary.LittleEndian.Uint32(respHeader[4:8])
-	respStatus := binary.LittleEndian.Uint32(respHeader[8:12])
+// redial is a reconnection in progress. Requests that find the connection
+// down while it runs wait for its outcome instead of dialing again.
+type redial struct {
+	done   chan struct{}      // Closed when the redial finishes
+	cancel context.CancelFunc // Stops the backoff, called by Close
+	err    error              // Outcome, set before done is closed
+}
 
-	if respCmd != EIPCommandRegisterSession {
-		return fmt.Errorf("unexpected response command: %d", respCmd)
-	}
+// ensureConnected redials the device, with backoff, if the connection broke.
+// The caller holds the lock; it is released while dialing and sleeping
+// between attempts, so State and Close stay responsive and Close can
+// interrupt the backoff.
+func (c *Client) ensureConnected() error {
+	for {
+		if c.closed {
+			return ErrClientClosed
+		}
+		if c.conn != nil {
+			return nil
+		}
+		if c.redial == nil {
+			break
+		}
 
-	if respStatus != 0 {
-		return fmt.Errorf("registration failed with status: %d", respStatus)
+		// Another request is already redialing
+		r := c.redial
+		c.unlock()
+		<-r.done
+		c.mu.Lock()
+		if r.err != nil {
+			return r.err
+		}
 	}
-
-	if respLen != 4 {
-		return fmt.Errorf("unexpected response length: %d", respLen)
+	if c.address == "" || c.policy.MaxAttempts <= 0 {
+		return fmt.Errorf("%w: reconnection disabled", ErrConnectionLost)
 	}
 
-	c.sessionHandle = respSessionHandle
-	return nil
-}
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &redial{done: make(chan struct{}), cancel: cancel}
+	c.redial = r
+	c.setState(StateConnecting)
+	policy, address, timeout := c.policy, c.address, c.timeout
+	c.unlock()
 
-// unregisterSession unregisters the session with the EIP server
-func (c *Client) unregisterSession() error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+	err := backoff(ctx, func() error {
+		var dialer net.Dialer
+		dialCtx, dialCancel := context.WithTimeout(ctx, timeout)
+		defer dialCancel()
+		conn, err := dialer.DialContext(dialCtx, "tcp", address)
+		if err != nil {
+			return err
+		}
 
-	if c.sessionHandle == 0 {
-		return nil // Not registered
-	}
+		// Hand the connection over and register the session under the lock
+		c.mu.Lock()
+		defer c.unlock()
+		if c.closed {
+			conn.Close()
+			return ErrClientClosed
+		}
+		c.conn = conn
+		if c.wantSession {
+			if err := c.registerSession(); err != nil {
+				if c.conn != nil {
+					c.conn.Close()
+					c.conn = nil
+				}
+				c.framer.reset()
+				c.setState(StateConnecting)
+				return err
+			}
+		}
+		return nil
+	}, policy.InitialDelay, policy.MaxDelay, policy.MaxAttempts, policy.Jitter)
+	cancel()
 
-	header := EIPHeader{
-		Command:       EIPCommandUnregister,
-		Length:        0,
-		SessionHandle: c.sessionHandle,
-	}
+	c.mu.Lock()
+	c.redial = nil
+	switch {
+	case c.closed:
+		err = ErrClientClosed
+	case err != nil:
+		c.setState(StateDisconnected)
+		err = fmt.Errorf("%w: failed to reconnect: %w", ErrConnectionLost, err)
+	default:
+		c.setState(StateConnected)
+	}
+	r.err = err
+	close(r.done)
+	return err
+}
 
-	// Buffer to hold the header
-	data := make([]byte, 24)
-	
-	// Write header to buffer
-	binary.LittleEndian.PutUint16(data[0:2], header.Command)
-	binary.LittleEndian.PutUint16(data[2:4], header.Length)
-	binary.LittleEndian.PutUint32(data[4:8], header.SessionHandle)
-	binary.LittleEndian.PutUint32(data[8:12], header.Status)
-	copy(data[12:20], header.SenderContext[:])
-	binary.LittleEndian.PutUint32(data[20:24], header.Options)
+// Reconnect drops the current connection and dials the device again,
+// re-registering the session if one was registered
+func (c *Client) Reconnect() error {
+	c.mu.Lock()
+	defer c.unlock()
 
-	// Set deadline for write
-	if err := c.conn.SetWriteDeadline(time.Now().Add(c.timeout)); err != nil {
-		return fmt.Errorf("failed to set write deadline: %w", err)
+	if c.closed {
+		return ErrClientClosed
 	}
-
-	// Send unregister session request
-	if _, err := c.conn.Write(data); err != nil {
-		return fmt.Errorf("failed to send unregister session request: %w", err)
+	if c.conn != nil {
+		c.fail(errors.New("reconnect requested"))
 	}
 
-	c.sessionHandle = 0
-	return nil
+	return c.ensureConnected()
 }
 
-// ListIdentity sends a List Identity request and returns the response
-func (c *Client) ListIdentity() ([]byte, error) {
+// retryReads reports whether idempotent reads should be retried after the
+// connection is lost
+func (c *Client) retryReads() bool {
 	c.mu.Lock()
-	defer c.mu.Unlock()
+	defer c.unlock()
+	return c.policy.RetryReads && !c.closed
+}
 
-	header := EIPHeader{
-		Command: EIPCommandListIdentity,
-		Length:  0,
+// Close closes the connection, interrupting any reconnection in progress
+func (c *Client) Close() error {
+	c.mu.Lock()
+	c.closed = true
+	if c.redial != nil {
+		c.redial.cancel()
 	}
+	c.unlock()
 
-	// Buffer to hold the header
-	data := make([]byte, 24)
-	
-	// Write header to buffer
-	binary.LittleEndian.PutUint16(data[0:2], header.Command)
-	binary.LittleEndian.PutUint16(data[2:4], header.Length)
-	binary.LittleEndian.PutUint32(data[4:8], header.SessionHandle)
-	binary.LittleEndian.PutUint32(data[8:12], header.Status)
-	copy(data[12:20], header.SenderContext[:])
-	binary.LittleEndian.PutUint32(data[20:24], header.Options)
+	c.StopKeepalive()
 
-	// Set deadline for write
-	if err := c.conn.SetWriteDeadline(time.Now().Add(c.timeout)); err != nil {
-		return nil, fmt.Errorf("failed to set write deadline: %w", err)
-	}
+	c.mu.Lock()
+	defer c.unlock()
 
-	// Send list identity request
-	if _, err := c.conn.Write(data); err != nil {
-		return nil, fmt.Errorf("failed to send list identity request: %w", err)
+	var err error
+	c.wantSession = false
+	if c.sessionHandle != 0 && c.conn != nil {
+		// The device closes the connection instead of replying
+		err = c.send(EIPCommandUnregister, nil)
+		c.sessionHandle = 0
 	}
 
-	// Set deadline for read
-	if err := c.conn.SetReadDeadline(time.Now().Add(c.timeout)); err != nil {
-		return nil, fmt.Errorf("failed to set read deadline: %w", err)
+	c.setState(StateClosed)
+	if c.conn == nil {
+		return err
 	}
-
-	// Read response header
-	respHeader := make([]byte, 24)
-	if _, err := io.ReadFull(c.conn, respHeader); err != nil {
-		return nil, fmt.Errorf("failed to read response header: %w", err)
+	if closeErr := c.conn.Close(); err == nil {
+		err = closeErr
 	}
+	c.conn = nil
+	return err
+}
 
-	// Parse response header
-	respCmd := binary.LittleEndian.Uint16(respHeader[0:2])
-	respLen := binary.LittleEndian.Uint16(respHeader[2:4])
-	respStatus := binary.LittleEndian.Uint32(respHeader[8:12])
+// RegisterSession registers a new session with the EIP server
+func (c *Client) RegisterSession() error {
+	c.mu.Lock()
+	defer c.unlock()
 
-	if respCmd != EIPCommandListIdentity {
-		return nil, fmt.Errorf("unexpected response command: %d", respCmd)
+	if c.sessionHandle != 0 {
+		return nil // Already registered
 	}
 
-	if respStatus != 0 {
-		return nil, fmt.Errorf("list identity failed with status: %d", respStatus)
+	if err := c.ensureConnected(); err != nil {
+		return err
 	}
 
-	// Read response data
-	respData := make([]byte, respLen)
-	if _, err := io.ReadFull(c.conn, respData); err != nil {
-		return nil, fmt.Errorf("failed to read response data: %w", err)
+	// A reconnection may already have registered the session
+	if c.sessionHandle != 0 {
+		return nil
 	}
 
-	return respData, nil
+	return c.registerSession()
 }
 
-// SendRRData sends a Send RR Data request and returns the response
-func (c *Client) SendRRData(interfaceHandle uint32, timeout uint16, data []byte) ([]byte, error) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+// registerSession performs the RegisterSession exchange; the caller holds the lock
+func (c *Client) registerSession() error {
+	// Protocol version (1) and options flag (0)
+	data := make([]byte, 4)
+	binary.LittleEndian.PutUint16(data[0:2], 1)
+	binary.LittleEndian.PutUint16(data[2:4], 0)
 
-	if c.sessionHandle == 0 {
-		return nil, errors.New("session not registered")
+	respHeader, respData, err := c.roundTrip(EIPCommandRegisterSession, data, false)
+	if err != nil {
+		return err
 	}
 
-	// Calculate the length of the data
-	dataLen := len(data)
-	
-	// Total data length = interface handle (4) + timeout (2) + data
-	totalLen := 6 + dataLen
+	if respHeader.Status != 0 {
+		return fmt.Errorf("registration failed with status: %d", respHeader.Status)
+	}
 
-	header := EIPHeader{
-		Command:       EIPCommandSendRRData,
-		Length:        uint16(totalLen),
-		SessionHandle: c.sessionHandle,
+	if len(respData) != 4 {
+		return fmt.Errorf("unexpected response length: %d", len(respData))
 	}
 
-	// Buffer to hold the header and data
-	buffer := make([]byte, 24+totalLen)
-	
-	// Write header to buffer
-	binary.LittleEndian.PutUint16(buffer[0:2], header.Command)
-	binary.LittleEndian.PutUint16(buffer[2:4], header.Length)
-	binary.LittleEndian.PutUint32(buffer[4:8], header.SessionHandle)
-	binary.LittleEndian.PutUint32(buffer[8:12], header.Status)
-	copy(buffer[12:20], header.SenderContext[:])
-	binary.LittleEndian.PutUint32(buffer[20:24], header.Options)
+	if respHeader.SessionHandle == 0 {
+		return errors.New("registration returned no session handle")
+	}
 
-	// Write interface handle and timeout
-	binary.LittleEndian.PutUint32(buffer[24:28], interfaceHandle)
-	binary.LittleEndian.PutUint16(buffer[28:30], timeout)
+	c.sessionHandle = respHeader.SessionHandle
+	c.wantSession = true
+	return nil
+}
 
-	// Copy data
-	copy(buffer[30:], data)
+// ListIdentity sends a List Identity request and returns the response
+func (c *Client) ListIdentity() ([]byte, error) {
+	c.mu.Lock()
+	defer c.unlock()
 
-	// Set deadline for write
-	if err := c.conn.SetWriteDeadline(time.Now().Add(c.timeout)); err != nil {
-		return nil, fmt.Errorf("failed to set write deadline: %w", err)
+	if err := c.ensureConnected(); err != nil {
+		return nil, err
 	}
 
-	// Send request
-	if _, err := c.conn.Write(buffer); err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
+	respHeader, respData, err := c.roundTrip(EIPCommandListIdentity, nil, false)
+	if err != nil {
+		return nil, err
 	}
 
-	// Set deadline for read
-	if err := c.conn.SetReadDeadline(time.Now().Add(c.timeout)); err != nil {
-		return nil, fmt.Errorf("failed to set read deadline: %w", err)
+	if respHeader.Status != 0 {
+		return nil, fmt.Errorf("list identity failed with status: %d", respHeader.Status)
 	}
 
-	// Read response header
-	respHeader := make([]byte, 24)
-	if _, err := io.ReadFull(c.conn, respHeader); err != nil {
-		return nil, fmt.Errorf("failed to read response header: %w", err)
-	}
+	return respData, nil
+}
+
+// SendRRData sends a Send RR Data request and returns the response
+func (c *Client) SendRRData(interfaceHandle uint32, timeout uint16, data []byte) ([]byte, error) {
+	c.mu.Lock()
+	defer c.unlock()
 
-	// Parse response header
-	respCmd := binary.LittleEndian.Uint16(respHeader[0:2])
-	respLen := binary.LittleEndian.Uint16(respHeader[2:4])
-	respStatus := binary.LittleEndian.Uint32(respHeader[8:12])
+	if err := c.ensureConnected(); err != nil {
+		return nil, err
+	}
 
-	if respCmd != EIPCommandSendRRData {
-		return nil, fmt.Errorf("unexpected response command: %d", respCmd)
+	if c.sessionHandle == 0 {
+		return nil, errors.New("session not registered")
 	}
 
-	if respStatus != 0 {
-		return nil, fmt.Errorf("request failed with status: %d", respStatus)
+	// Total data length = interface handle (4) + timeout (2) + data
+	if 6+len(data) > EIPMaxDataLength {
+		return nil, fmt.Errorf("request too large: %d bytes", len(data))
 	}
+	buffer := make([]byte, 6+len(data))
+	binary.LittleEndian.PutUint32(buffer[0:4], interfaceHandle)
+	binary.LittleEndian.PutUint16(buffer[4:6], timeout)
+	copy(buffer[6:], data)
 
-	// Read interface handle and timeout
-	respData := make([]byte, 6)
-	if _, err := io.ReadFull(c.conn, respData); err != nil {
-		return nil, fmt.Errorf("failed to read interface handle and timeout: %w", err)
+	respHeader, respData, err := c.roundTrip(EIPCommandSendRRData, buffer, true)
+	if err != nil {
+		return nil, err
 	}
 
-	// Read response data
-	respDataLen := int(respLen) - 6
-	if respDataLen <= 0 {
-		return []byte{}, nil
+	if respHeader.Status != 0 {
+		return nil, fmt.Errorf("request failed with status: %d", respHeader.Status)
 	}
 
-	respPayload := make([]byte, respDataLen)
-	if _, err := io.ReadFull(c.conn, respPayload); err != nil {
-		return nil, fmt.Errorf("failed to read response data: %w", err)
+	// Interface handle and timeout precede the response data
+	if len(respData) < 6 {
+		return nil, fmt.Errorf("response too short: %d bytes", len(respData))
 	}
 
-	return respPayload, nil
+	return respData[6:], nil
 }
 
 // SendUnitData sends a Send Unit Data request and returns the response
 func (c *Client) SendUnitData(interfaceHandle uint32, timeout uint16, data []byte) error {
 	c.mu.Lock()
-	defer c.mu.Unlock()
+	defer c.unlock()
+
+	if err := c.ensureConnected(); err != nil {
+		return err
+	}
 
 	if c.sessionHandle == 0 {
 		return errors.New("session not registered")
 	}
 
-	// Calculate the length of the data
-	dataLen := len(data)
-	
 	// Total data length = interface handle (4) + timeout (2) + data
-	totalLen := 6 + dataLen
-
-	header := EIPHeader{
-		Command:       EIPCommandSendUnitData,
-		Length:        uint16(totalLen),
-		SessionHandle: c.sessionHandle,
+	if 6+len(data) > EIPMaxDataLength {
+		return fmt.Errorf("request too large: %d bytes", len(data))
 	}
+	buffer := make([]byte, 6+len(data))
+	binary.LittleEndian.PutUint32(buffer[0:4], interfaceHandle)
+	binary.LittleEndian.PutUint16(buffer[4:6], timeout)
+	copy(buffer[6:], data)
 
-	// Buffer to hold the header and data
-	buffer := make([]byte, 24+totalLen)
-	
-	// Write header to buffer
-	binary.LittleEndian.PutUint16(buffer[0:2], header.Command)
-	binary.LittleEndian.PutUint16(buffer[2:4], header.Length)
-	binary.LittleEndian.PutUint32(buffer[4:8], header.SessionHandle)
-	binary.LittleEndian.PutUint32(buffer[8:12], header.Status)
-	copy(buffer[12:20], header.SenderContext[:])
-	binary.LittleEndian.PutUint32(buffer[20:24], header.Options)
+	return c.send(EIPCommandSendUnitData, buffer)
+}
 
-	// Write interface handle and timeout
-	binary.LittleEndian.PutUint32(buffer[24:28], interfaceHandle)
-	binary.LittleEndian.PutUint16(buffer[28:30], timeout)
+// send writes one encapsulation packet that expects no reply; the caller
+// holds the lock
+func (c *Client) send(command uint16, data []byte) error {
+	_, err := c.sendPacket(command, data)
+	return err
+}
 
-	// Copy data
-	copy(buffer[30:], data)
+// sendPacket writes one encapsulation packet with a fresh sender context and
+// returns the header that was sent; the caller holds the lock
+func (c *Client) sendPacket(command uint16, data []byte) (EIPHeader, error) {
+	c.sequence++
+	header := EIPHeader{
+		Command:       command,
+		SessionHandle: c.sessionHandle,
+		SenderContext: senderContext(c.sequence),
+	}
 
 	// Set deadline for write
 	if err := c.conn.SetWriteDeadline(time.Now().Add(c.timeout)); err != nil {
-		return fmt.Errorf("failed to set write deadline: %w", err)
+		return header, fmt.Errorf("failed to set write deadline: %w", err)
 	}
 
 	// Send request
-	if _, err := c.conn.Write(buffer); err != nil {
-		return fmt.Errorf("failed to send request: %w", err)
+	if _, err := c.conn.Write(header.encode(data)); err != nil {
+		return header, c.fail(fmt.Errorf("failed to send request: %w", err))
 	}
 
-	return nil
+	c.lastActivity = time.Now()
+	return header, nil
+}
+
+// maxSilentTimeouts is how many requests in a row may time out without the
+// device sending a single byte before the connection is dropped. A slow
+// device still gets its late replies discarded; a peer that went away
+// without closing the socket (cable pulled, power cycled behind a switch)
+// is detected even without a keepalive.
+const maxSilentTimeouts = 2
+
+// roundTrip sends a request and waits for the reply carrying the same
+// command and sender context (and session, if sessionBound). Late replies to
+// earlier requests are discarded and anything else is handed to the
+// unsolicited packet handlers. A timeout leaves the connection usable unless
+// the device has been silent for maxSilentTimeouts requests; any other read
+// failure drops it. The caller holds the lock.
+func (c *Client) roundTrip(command uint16, data []byte, sessionBound bool) (EIPHeader, []byte, error) {
+	request, err := c.sendPacket(command, data)
+	if err != nil {
+		return EIPHeader{}, nil, err
+	}
+
+	sent := time.Now()
+	deadline := sent.Add(c.timeout)
+	for {
+		header, payload, err := c.framer.readFrame(c.conn, deadline)
+		if err != nil {
+			if !isTimeout(err) {
+				return EIPHeader{}, nil, c.fail(fmt.Errorf("failed to read response: %w", err))
+			}
+
+			// Part of a reply arriving still shows the peer is alive
+			if c.framer.lastRead.After(sent) {
+				c.silentTimeouts = 0
+			} else if c.silentTimeouts++; c.silentTimeouts >= maxSilentTimeouts {
+				return EIPHeader{}, nil, c.fail(fmt.Errorf("%w: no data from the device for %d requests", ErrRequestTimeout, c.silentTimeouts))
+			}
+			return EIPHeader{}, nil, fmt.Errorf("%w: %w", ErrRequestTimeout, err)
+		}
+		c.lastActivity = time.Now()
+		c.silentTimeouts = 0
+
+		matches := header.Command == command &&
+			header.SenderContext == request.SenderContext &&
+			(!sessionBound || header.SessionHandle == c.sessionHandle)
+		if matches {
+			return header, payload, nil
+		}
+
+		// A reply to a request that already timed out
+		sequence := binary.LittleEndian.Uint64(header.SenderContext[:])
+		if sequence != 0 && sequence < c.sequence {
+			c.discarded++
+			continue
+		}
+
+		c.pendingUnsolicited = append(c.pendingUnsolicited, unsolicitedPacket{header, payload})
+	}
+}
+
+// unsolicitedPacket is a packet that did not answer any request
+type unsolicitedPacket struct {
+	header EIPHeader
+	data   []byte
+}
+
+// OnUnsolicited registers a handler for packets that do not answer a request
+// sent by this client. Handlers run outside the client lock.
+func (c *Client) OnUnsolicited(handler func(header EIPHeader, data []byte)) {
+	c.mu.Lock()
+	defer c.unlock()
+	c.unsolicitedHandlers = append(c.unsolicitedHandlers, handler)
+}
+
+// DiscardedReplies returns how many late replies to timed-out requests have
+// been discarded since the client was created
+func (c *Client) DiscardedReplies() int {
+	c.mu.Lock()
+	defer c.unlock()
+	return c.discarded
 }
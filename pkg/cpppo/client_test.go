@@ -1,6 +1,9 @@
 package cpppo
 
 import (
+	"encoding/binary"
+	"errors"
+	"io"
 	"net"
 	"testing"
 	"time"
@@ -27,6 +30,74 @@ func setupMockServer(t *testing.T, handler func(conn net.Conn)) (string, func())
 	}
 }
 
+// setupMockListener creates a mock TCP server that accepts any number of
+// connections, passing each one and its sequence number to the handler
+func setupMockListener(t *testing.T, handler func(n int, conn net.Conn)) (string, func()) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to create listener: %v", err)
+	}
+
+	go func() {
+		for n := 0; ; n++ {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func(n int) {
+				defer conn.Close()
+				handler(n, conn)
+			}(n)
+		}
+	}()
+
+	return listener.Addr().String(), func() {
+		listener.Close()
+	}
+}
+
+// setupEIPServer creates a mock EtherNet/IP server that registers sessions and
+// answers every Send RR Data request with the handler's CIP reply
+func setupEIPServer(t *testing.T, handler func(request []byte) []byte) (string, func()) {
+	return setupMockServer(t, func(conn net.Conn) {
+		serveEIP(conn, handler)
+	})
+}
+
+// serveEIP answers encapsulated requests on conn until it is closed
+func serveEIP(conn net.Conn, handler func(request []byte) []byte) {
+	for {
+		header := make([]byte, 24)
+		if _, err := io.ReadFull(conn, header); err != nil {
+			return
+		}
+
+		payload := make([]byte, binary.LittleEndian.Uint16(header[2:4]))
+		if _, err := io.ReadFull(conn, payload); err != nil {
+			return
+		}
+
+		var reply []byte
+		switch binary.LittleEndian.Uint16(header[0:2]) {
+		case EIPCommandRegisterSession:
+			binary.LittleEndian.PutUint32(header[4:8], 1)
+			reply = payload
+		case EIPCommandSendRRData:
+			reply = append(make([]byte, 6), handler(payload[6:])...)
+		case EIPCommandListIdentity:
+			reply = []byte{0, 0} // No identity items
+		default:
+			continue
+		}
+
+		// Echo the header (session and sender context) with the reply length
+		binary.LittleEndian.PutUint16(header[2:4], uint16(len(reply)))
+		if _, err := conn.Write(append(header, reply...)); err != nil {
+			return
+		}
+	}
+}
+
 func TestNewClient(t *testing.T) {
 	// Test with invalid address
 	_, err := NewClient("invalid:port", 1*time.Second)
@@ -79,6 +150,7 @@ func TestRegisterSession(t *testing.T) {
 		resp[6] = 0
 		resp[7] = 0 // Session handle (high byte)
 		// Status is 0 (success)
+		copy(resp[12:20], buf[12:20]) // Echo sender context
 		// Version is 1
 		resp[24] = 1
 		resp[25] = 0
@@ -133,6 +205,7 @@ func TestListIdentity(t *testing.T) {
 		resp[2] = 8 // Length (low byte)
 		resp[3] = 0 // Length (high byte)
 		// Status is 0 (success)
+		copy(resp[12:20], buf[12:20]) // Echo sender context
 
 		// Simple identity data (8 bytes)
 		resp[24] = 1 // Item count
@@ -172,9 +245,9 @@ func TestSendRRData(t *testing.T) {
 	// Mock server that handles the send RR data request
 	addr, cleanup := setupMockServer(t, func(conn net.Conn) {
 		// Read request header
-		buf := make([]byte, 30)
+		buf := make([]byte, 28)
 		n, err := conn.Read(buf)
-		if err != nil || n < 30 {
+		if err != nil || n < 28 {
 			t.Errorf("Failed to read request: %v", err)
 			return
 		}
@@ -192,6 +265,7 @@ func TestSendRRData(t *testing.T) {
 			resp[6] = 0
 			resp[7] = 0 // Session handle (high byte)
 			// Status is 0 (success)
+			copy(resp[12:20], buf[12:20]) // Echo sender context
 			resp[24] = 1
 			resp[25] = 0
 			resp[26] = 0
@@ -227,6 +301,7 @@ func TestSendRRData(t *testing.T) {
 		resp[6] = 0
 		resp[7] = 0 // Session handle (high byte)
 		// Status is 0 (success)
+		copy(resp[12:20], buf[12:20]) // Echo sender context
 
 		// Interface handle (4) + timeout (2) + sample data (4)
 		resp[24] = 0 // Interface handle
@@ -274,3 +349,151 @@ func TestSendRRData(t *testing.T) {
 		t.Errorf("Expected response data 'DATA', got '%s'", string(data))
 	}
 }
+
+// dropFirstConnection answers like serveEIP, except that the first connection
+// is closed as soon as it receives a Send RR Data request
+func dropFirstConnection(handler func(request []byte) []byte) func(n int, conn net.Conn) {
+	return func(n int, conn net.Conn) {
+		if n > 0 {
+			serveEIP(conn, handler)
+			return
+		}
+		serveEIP(conn, func(request []byte) []byte {
+			conn.Close()
+			return nil
+		})
+	}
+}
+
+func TestClientReconnect(t *testing.T) {
+	addr, cleanup := setupMockListener(t, dropFirstConnection(func(request []byte) []byte {
+		return []byte("DATA")
+	}))
+	defer cleanup()
+
+	client, err := NewClient(addr, 1*time.Second)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+	client.SetReconnectPolicy(ReconnectPolicy{MaxAttempts: 3, InitialDelay: 10 * time.Millisecond, MaxDelay: 50 * time.Millisecond, Jitter: 0.5})
+
+	states := make(chan ConnState, 10)
+	client.OnStateChange(func(state ConnState) {
+		states <- state
+	})
+
+	if err := client.RegisterSession(); err != nil {
+		t.Fatalf("Failed to register session: %v", err)
+	}
+
+	// The first request breaks the connection
+	_, err = client.SendRRData(0, 10, []byte("TEST"))
+	if !errors.Is(err, ErrConnectionLost) {
+		t.Fatalf("Expected ErrConnectionLost, got %v", err)
+	}
+	if client.State() != StateDisconnected {
+		t.Errorf("Expected state %v, got %v", StateDisconnected, client.State())
+	}
+
+	// The next request redials and re-registers the session
+	data, err := client.SendRRData(0, 10, []byte("TEST"))
+	if err != nil {
+		t.Fatalf("Expected request to succeed after reconnecting, got %v", err)
+	}
+	if string(data) != "DATA" {
+		t.Errorf("Expected response data 'DATA', got '%s'", string(data))
+	}
+
+	expected := []ConnState{StateDisconnected, StateConnecting, StateConnected}
+	for _, want := range expected {
+		select {
+		case got := <-states:
+			if got != want {
+				t.Errorf("Expected state %v, got %v", want, got)
+			}
+		default:
+			t.Fatalf("Missing state change %v", want)
+		}
+	}
+}
+
+func TestClientReconnectDisabled(t *testing.T) {
+	addr, cleanup := setupMockListener(t, dropFirstConnection(func(request []byte) []byte {
+		return []byte("DATA")
+	}))
+	defer cleanup()
+
+	client, err := NewClient(addr, 1*time.Second)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+	client.SetReconnectPolicy(ReconnectPolicy{})
+
+	if err := client.RegisterSession(); err != nil {
+		t.Fatalf("Failed to register session: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.SendRRData(0, 10, []byte("TEST")); !errors.Is(err, ErrConnectionLost) {
+			t.Errorf("Request %d: expected ErrConnectionLost, got %v", i, err)
+		}
+	}
+}
+
+func TestClientCloseDuringReconnect(t *testing.T) {
+	addr, cleanup := setupMockListener(t, dropFirstConnection(func(request []byte) []byte {
+		return []byte("DATA")
+	}))
+
+	client, err := NewClient(addr, 1*time.Second)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	client.SetReconnectPolicy(ReconnectPolicy{MaxAttempts: 100, InitialDelay: time.Second, MaxDelay: time.Second})
+
+	if err := client.RegisterSession(); err != nil {
+		t.Fatalf("Failed to register session: %v", err)
+	}
+	if _, err := client.SendRRData(0, 10, []byte("TEST")); !errors.Is(err, ErrConnectionLost) {
+		t.Fatalf("Expected ErrConnectionLost, got %v", err)
+	}
+
+	// With the device gone, the next request keeps redialing
+	cleanup()
+	result := make(chan error, 1)
+	go func() {
+		_, err := client.SendRRData(0, 10, []byte("TEST"))
+		result <- err
+	}()
+
+	// State answers while the backoff runs
+	deadline := time.Now().Add(time.Second)
+	for client.State() != StateConnecting {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected state %v, got %v", StateConnecting, client.State())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// Close interrupts the backoff
+	start := time.Now()
+	if err := client.Close(); err != nil {
+		t.Errorf("Close returned error: %v", err)
+	}
+	select {
+	case err := <-result:
+		if !errors.Is(err, ErrClientClosed) {
+			t.Errorf("Expected ErrClientClosed, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Request still redialing after Close")
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Close took %v", elapsed)
+	}
+	if client.State() != StateClosed {
+		t.Errorf("Expected state %v, got %v", StateClosed, client.State())
+	}
+}
@@ -0,0 +1,146 @@
+package cpppo
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// cipCodec converts values of one CIP data type to and from their wire form
+type cipCodec struct {
+	name   string // CIP type name, used in errors
+	goType string // Go type accepted by encode, used in errors
+	size   int    // Encoded size in bytes, 0 for variable length types
+
+	encode func(value interface{}) ([]byte, bool)
+	decode func(data []byte) (interface{}, error)
+}
+
+// cipCodecs holds the codec for every CIP data type this package understands
+var cipCodecs = map[byte]cipCodec{
+	CIPDataTypeBOOL: {
+		name: "BOOL", goType: "a bool", size: 1,
+		encode: func(value interface{}) ([]byte, bool) {
+			v, ok := value.(bool)
+			if !ok {
+				return nil, false
+			}
+			if v {
+				return []byte{1}, true
+			}
+			return []byte{0}, true
+		},
+		decode: func(data []byte) (interface{}, error) {
+			return data[0] != 0, nil
+		},
+	},
+	CIPDataTypeSINT: {
+		name: "SINT", goType: "an int8", size: 1,
+		encode: func(value interface{}) ([]byte, bool) {
+			v, ok := value.(int8)
+			return []byte{byte(v)}, ok
+		},
+		decode: func(data []byte) (interface{}, error) {
+			return int8(data[0]), nil
+		},
+	},
+	CIPDataTypeINT: {
+		name: "INT", goType: "an int16", size: 2,
+		encode: func(value interface{}) ([]byte, bool) {
+			v, ok := value.(int16)
+			return binary.LittleEndian.AppendUint16(nil, uint16(v)), ok
+		},
+		decode: func(data []byte) (interface{}, error) {
+			return int16(binary.LittleEndian.Uint16(data)), nil
+		},
+	},
+	CIPDataTypeDINT: {
+		name: "DINT", goType: "an int32", size: 4,
+		encode: func(value interface{}) ([]byte, bool) {
+			v, ok := value.(int32)
+			return binary.LittleEndian.AppendUint32(nil, uint32(v)), ok
+		},
+		decode: func(data []byte) (interface{}, error) {
+			return int32(binary.LittleEndian.Uint32(data)), nil
+		},
+	},
+	CIPDataTypeREAL: {
+		name: "REAL", goType: "a float32", size: 4,
+		encode: func(value interface{}) ([]byte, bool) {
+			v, ok := value.(float32)
+			return binary.LittleEndian.AppendUint32(nil, math.Float32bits(v)), ok
+		},
+		decode: func(data []byte) (interface{}, error) {
+			return math.Float32frombits(binary.LittleEndian.Uint32(data)), nil
+		},
+	},
+	CIPDataTypeDWORD: {
+		name: "DWORD", goType: "a uint32", size: 4,
+		encode: func(value interface{}) ([]byte, bool) {
+			v, ok := value.(uint32)
+			return binary.LittleEndian.AppendUint32(nil, v), ok
+		},
+		decode: func(data []byte) (interface{}, error) {
+			return binary.LittleEndian.Uint32(data), nil
+		},
+	},
+	CIPDataTypeSTRING: {
+		// A 16-bit length followed by the characters
+		name: "STRING", goType: "a string",
+		encode: func(value interface{}) ([]byte, bool) {
+			v, ok := value.(string)
+			if !ok || len(v) > math.MaxUint16 {
+				return nil, false
+			}
+			data := binary.LittleEndian.AppendUint16(nil, uint16(len(v)))
+			return append(data, v...), true
+		},
+		decode: func(data []byte) (interface{}, error) {
+			if len(data) < 2 {
+				return nil, errors.New("not enough data for STRING header")
+			}
+			length := int(binary.LittleEndian.Uint16(data))
+			if len(data) < 2+length {
+				return nil, errors.New("string data truncated")
+			}
+			return string(data[2 : 2+length]), nil
+		},
+	},
+}
+
+// EncodeValue encodes a Go value as the given CIP data type. The value must
+// have the Go type that DecodeValue returns for that data type.
+func EncodeValue(dataType byte, value interface{}) ([]byte, error) {
+	codec, ok := cipCodecs[dataType]
+	if !ok {
+		return nil, fmt.Errorf("unsupported data type: %#x", dataType)
+	}
+
+	data, ok := codec.encode(value)
+	if !ok {
+		if s, isString := value.(string); isString && len(s) > math.MaxUint16 {
+			return nil, fmt.Errorf("string too long: %d bytes", len(s))
+		}
+		return nil, fmt.Errorf("value is not %s", codec.goType)
+	}
+
+	return data, nil
+}
+
+// DecodeValue decodes a CIP value of the given data type. Data of a type
+// without a codec is returned as raw bytes.
+func DecodeValue(dataType byte, data []byte) (interface{}, error) {
+	codec, ok := cipCodecs[dataType]
+	if !ok {
+		raw := make([]byte, len(data))
+		copy(raw, data)
+		return raw, nil
+	}
+
+	if len(data) < codec.size {
+		return nil, fmt.Errorf("not enough data for %s", codec.name)
+	}
+
+	return codec.decode(data)
+}
@@ -0,0 +1,121 @@
+package cpppo
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+	"testing/quick"
+)
+
+// codecRoundTrip encodes value as dataType and decodes it again, returning
+// nil if either step fails
+func codecRoundTrip(dataType byte, value interface{}) interface{} {
+	data, err := EncodeValue(dataType, value)
+	if err != nil {
+		return nil
+	}
+	decoded, err := DecodeValue(dataType, data)
+	if err != nil {
+		return nil
+	}
+	return decoded
+}
+
+func TestCodecRoundTrip(t *testing.T) {
+	properties := map[string]interface{}{
+		"BOOL":   func(v bool) bool { return codecRoundTrip(CIPDataTypeBOOL, v) == v },
+		"SINT":   func(v int8) bool { return codecRoundTrip(CIPDataTypeSINT, v) == v },
+		"INT":    func(v int16) bool { return codecRoundTrip(CIPDataTypeINT, v) == v },
+		"DINT":   func(v int32) bool { return codecRoundTrip(CIPDataTypeDINT, v) == v },
+		"DWORD":  func(v uint32) bool { return codecRoundTrip(CIPDataTypeDWORD, v) == v },
+		"STRING": func(v string) bool { return codecRoundTrip(CIPDataTypeSTRING, v) == v },
+		"REAL": func(bits uint32) bool {
+			// Compare bit patterns so NaN payloads count as preserved
+			decoded, ok := codecRoundTrip(CIPDataTypeREAL, math.Float32frombits(bits)).(float32)
+			return ok && math.Float32bits(decoded) == bits
+		},
+	}
+
+	for name, property := range properties {
+		t.Run(name, func(t *testing.T) {
+			if err := quick.Check(property, nil); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestHelpersMatchCodec(t *testing.T) {
+	property := func(b bool, i16 int16, i32 int32, f32 float32) bool {
+		decodedBool, err := DecodeBool(EncodeBool(b))
+		if err != nil || decodedBool != b {
+			return false
+		}
+		decoded16, err := DecodeInt16(EncodeInt16(i16))
+		if err != nil || decoded16 != i16 {
+			return false
+		}
+		decoded32, err := DecodeInt32(EncodeInt32(i32))
+		if err != nil || decoded32 != i32 {
+			return false
+		}
+		decodedFloat, err := DecodeFloat32(EncodeFloat32(f32))
+		return err == nil && decodedFloat == f32
+	}
+
+	if err := quick.Check(property, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestParseCIPReadResponseREAL(t *testing.T) {
+	// 42.5 is 0x422A0000 in IEEE-754
+	resp := []byte{0xCC, 0x00, CIPDataTypeREAL, 0x00, 0x00, 0x00, 0x2A, 0x42}
+	value, err := ParseCIPReadResponse(resp, CIPDataTypeREAL)
+	if err != nil {
+		t.Fatalf("Failed to parse REAL response: %v", err)
+	}
+	if value != float32(42.5) {
+		t.Errorf("Expected 42.5, got %v", value)
+	}
+}
+
+func TestEncodeValueErrors(t *testing.T) {
+	tests := []struct {
+		dataType byte
+		value    interface{}
+		want     string
+	}{
+		{CIPDataTypeREAL, 42.5, "not a float32"},
+		{CIPDataTypeDINT, int16(1), "not an int32"},
+		{CIPDataTypeSTRING, strings.Repeat("x", math.MaxUint16+1), "too long"},
+		{0xFF, int32(1), "unsupported data type"},
+	}
+
+	for _, tc := range tests {
+		_, err := EncodeValue(tc.dataType, tc.value)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("EncodeValue(%#x, %T): expected error containing %q, got %v",
+				tc.dataType, tc.value, tc.want, err)
+		}
+	}
+}
+
+func TestDecodeValueShortData(t *testing.T) {
+	for dataType, codec := range cipCodecs {
+		if _, err := DecodeValue(dataType, nil); err == nil {
+			t.Errorf("Expected error decoding empty %s", codec.name)
+		}
+	}
+
+	if _, err := DecodeValue(CIPDataTypeSTRING, []byte{5, 0, 'a'}); err == nil {
+		t.Error("Expected error decoding truncated STRING")
+	}
+
+	// Types without a codec come back as raw bytes
+	raw, err := DecodeValue(0xA0, []byte{1, 2, 3})
+	if err != nil || !bytes.Equal(raw.([]byte), []byte{1, 2, 3}) {
+		t.Errorf("Expected raw bytes for unknown type, got %v, %v", raw, err)
+	}
+}
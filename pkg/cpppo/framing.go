@@ -0,0 +1,131 @@
+package cpppo
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// Encapsulation packet limits
+const (
+	EIPHeaderLength  = 24
+	EIPMaxDataLength = 65511 // Largest data portion allowed by the specification
+)
+
+// ErrFraming is returned when the byte stream from the device cannot be
+// split into encapsulation packets; the connection is dropped to resync
+var ErrFraming = errors.New("encapsulation framing error")
+
+// ErrRequestTimeout is returned when no reply arrived in time. The
+// connection stays usable; a late reply is discarded when it arrives. Once
+// maxSilentTimeouts requests in a row time out without the device sending
+// anything, the peer is taken to be gone and the connection is dropped.
+var ErrRequestTimeout = errors.New("request timed out")
+
+// encode serializes the header followed by data, setting the length field
+func (h EIPHeader) encode(data []byte) []byte {
+	packet := make([]byte, EIPHeaderLength+len(data))
+	binary.LittleEndian.PutUint16(packet[0:2], h.Command)
+	binary.LittleEndian.PutUint16(packet[2:4], uint16(len(data)))
+	binary.LittleEndian.PutUint32(packet[4:8], h.SessionHandle)
+	binary.LittleEndian.PutUint32(packet[8:12], h.Status)
+	copy(packet[12:20], h.SenderContext[:])
+	binary.LittleEndian.PutUint32(packet[20:24], h.Options)
+	copy(packet[EIPHeaderLength:], data)
+	return packet
+}
+
+// decodeEIPHeader parses an encapsulation header
+func decodeEIPHeader(b []byte) EIPHeader {
+	var h EIPHeader
+	h.Command = binary.LittleEndian.Uint16(b[0:2])
+	h.Length = binary.LittleEndian.Uint16(b[2:4])
+	h.SessionHandle = binary.LittleEndian.Uint32(b[4:8])
+	h.Status = binary.LittleEndian.Uint32(b[8:12])
+	copy(h.SenderContext[:], b[12:20])
+	h.Options = binary.LittleEndian.Uint32(b[20:24])
+	return h
+}
+
+// validateEIPHeader rejects headers that cannot start a valid packet, which
+// means the stream is no longer aligned on packet boundaries
+func validateEIPHeader(h EIPHeader) error {
+	switch h.Command {
+	case EIPCommandNOP, EIPCommandListIdentity, EIPCommandListInterfaces,
+		EIPCommandRegisterSession, EIPCommandUnregister, EIPCommandSendRRData,
+		EIPCommandSendUnitData, EIPCommandIndicateStatus, EIPCommandCancel,
+		EIPCommandListServices:
+	default:
+		return fmt.Errorf("%w: unknown command %#04x", ErrFraming, h.Command)
+	}
+
+	if h.Length > EIPMaxDataLength {
+		return fmt.Errorf("%w: length %d exceeds %d", ErrFraming, h.Length, EIPMaxDataLength)
+	}
+
+	return nil
+}
+
+// senderContext encodes a request sequence number as a sender context
+func senderContext(sequence uint64) [8]byte {
+	var context [8]byte
+	binary.LittleEndian.PutUint64(context[:], sequence)
+	return context
+}
+
+// framer splits the byte stream from the device into encapsulation packets.
+// Bytes of a packet that was only partly received when a read timed out are
+// kept, so the next read continues on the packet boundary.
+type framer struct {
+	pending  []byte
+	lastRead time.Time // When bytes last arrived from the device
+}
+
+// readFrame returns the next complete packet, reading from conn until the
+// deadline if necessary
+func (f *framer) readFrame(conn net.Conn, deadline time.Time) (EIPHeader, []byte, error) {
+	buf := make([]byte, 4096)
+
+	for {
+		if len(f.pending) >= EIPHeaderLength {
+			header := decodeEIPHeader(f.pending)
+			if err := validateEIPHeader(header); err != nil {
+				return EIPHeader{}, nil, err
+			}
+
+			end := EIPHeaderLength + int(header.Length)
+			if len(f.pending) >= end {
+				data := make([]byte, header.Length)
+				copy(data, f.pending[EIPHeaderLength:end])
+				f.pending = f.pending[end:]
+				return header, data, nil
+			}
+		}
+
+		if err := conn.SetReadDeadline(deadline); err != nil {
+			return EIPHeader{}, nil, fmt.Errorf("failed to set read deadline: %w", err)
+		}
+
+		n, err := conn.Read(buf)
+		if n > 0 {
+			f.pending = append(f.pending, buf[:n]...)
+			f.lastRead = time.Now()
+		}
+		if err != nil {
+			return EIPHeader{}, nil, err
+		}
+	}
+}
+
+// reset discards buffered bytes, for use with a new connection
+func (f *framer) reset() {
+	f.pending = nil
+}
+
+// isTimeout reports whether a read error is a deadline expiry
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
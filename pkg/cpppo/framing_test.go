@@ -0,0 +1,221 @@
+package cpppo
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// serveFrames registers sessions and passes every Send RR Data request to
+// respond, writing whatever raw bytes it returns back to the client
+func serveFrames(conn net.Conn, respond func(n int, request EIPHeader) []byte) {
+	for n := 0; ; {
+		buf := make([]byte, EIPHeaderLength)
+		if _, err := io.ReadFull(conn, buf); err != nil {
+			return
+		}
+		request := decodeEIPHeader(buf)
+		payload := make([]byte, request.Length)
+		if _, err := io.ReadFull(conn, payload); err != nil {
+			return
+		}
+
+		switch request.Command {
+		case EIPCommandRegisterSession:
+			request.SessionHandle = 1
+			conn.Write(request.encode(payload))
+		case EIPCommandSendRRData:
+			conn.Write(respond(n, request))
+			n++
+		}
+	}
+}
+
+// rrReply encodes a Send RR Data reply to request carrying data
+func rrReply(request EIPHeader, data []byte) []byte {
+	return request.encode(append(make([]byte, 6), data...))
+}
+
+// newFramingClient connects a registered client to a serveFrames server
+func newFramingClient(t *testing.T, timeout time.Duration, respond func(n int, request EIPHeader) []byte) (*Client, func()) {
+	addr, cleanup := setupMockServer(t, func(conn net.Conn) {
+		serveFrames(conn, respond)
+	})
+
+	client, err := NewClient(addr, timeout)
+	if err != nil {
+		cleanup()
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	if err := client.RegisterSession(); err != nil {
+		client.Close()
+		cleanup()
+		t.Fatalf("Failed to register session: %v", err)
+	}
+
+	return client, func() {
+		client.Close()
+		cleanup()
+	}
+}
+
+func TestLateReplySplitAcrossTimeout(t *testing.T) {
+	addr, cleanup := setupMockServer(t, func(conn net.Conn) {
+		serveFrames(conn, func(n int, request EIPHeader) []byte {
+			if n > 0 {
+				return rrReply(request, []byte("SECOND"))
+			}
+
+			// Send half the reply before the deadline and the rest after
+			reply := rrReply(request, []byte("FIRST"))
+			conn.Write(reply[:10])
+			time.Sleep(250 * time.Millisecond)
+			return reply[10:]
+		})
+	})
+	defer cleanup()
+
+	client, err := NewClient(addr, 200*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+	if err := client.RegisterSession(); err != nil {
+		t.Fatalf("Failed to register session: %v", err)
+	}
+
+	if _, err := client.SendRRData(0, 10, []byte("FIRST")); !errors.Is(err, ErrRequestTimeout) {
+		t.Fatalf("Expected ErrRequestTimeout, got %v", err)
+	}
+	if state := client.State(); state != StateConnected {
+		t.Fatalf("Expected connection to survive a timeout, state is %v", state)
+	}
+
+	// The rest of the late reply arrives ahead of the reply to this request
+	data, err := client.SendRRData(0, 10, []byte("SECOND"))
+	if err != nil {
+		t.Fatalf("Request after timeout failed: %v", err)
+	}
+	if string(data) != "SECOND" {
+		t.Errorf("Expected reply 'SECOND', got %q", data)
+	}
+	if n := client.DiscardedReplies(); n != 1 {
+		t.Errorf("Expected 1 discarded reply, got %d", n)
+	}
+}
+
+func TestSilentPeerDropsConnection(t *testing.T) {
+	// Only the second request is answered
+	client, cleanup := newFramingClient(t, 100*time.Millisecond, func(n int, request EIPHeader) []byte {
+		if n == 1 {
+			return rrReply(request, []byte("DATA"))
+		}
+		return nil
+	})
+	defer cleanup()
+
+	// Silent, answered, silent: the answer resets the count
+	for i := 0; i < 3; i++ {
+		_, err := client.SendRRData(0, 10, []byte("TEST"))
+		if i != 1 && !errors.Is(err, ErrRequestTimeout) {
+			t.Fatalf("Request %d: expected ErrRequestTimeout, got %v", i, err)
+		}
+		if errors.Is(err, ErrConnectionLost) {
+			t.Fatalf("Request %d: expected the connection to survive, got %v", i, err)
+		}
+		if state := client.State(); state != StateConnected {
+			t.Fatalf("Request %d: expected state Connected, got %v", i, state)
+		}
+	}
+
+	// A second silent timeout in a row means the peer is gone
+	_, err := client.SendRRData(0, 10, []byte("TEST"))
+	if !errors.Is(err, ErrConnectionLost) || !errors.Is(err, ErrRequestTimeout) {
+		t.Fatalf("Expected a lost connection after a timeout, got %v", err)
+	}
+	if state := client.State(); state != StateDisconnected {
+		t.Errorf("Expected state Disconnected, got %v", state)
+	}
+}
+
+func TestFramingErrorDropsConnection(t *testing.T) {
+	tests := []struct {
+		name   string
+		header func(request EIPHeader) []byte
+	}{
+		{"unknown command", func(request EIPHeader) []byte {
+			request.Command = 0x1234
+			return request.encode(nil)
+		}},
+		{"oversized length", func(request EIPHeader) []byte {
+			packet := request.encode(nil)
+			binary.LittleEndian.PutUint16(packet[2:4], 0xFFFF)
+			return packet
+		}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			client, cleanup := newFramingClient(t, time.Second, func(n int, request EIPHeader) []byte {
+				return tc.header(request)
+			})
+			defer cleanup()
+
+			_, err := client.SendRRData(0, 10, []byte("TEST"))
+			if !errors.Is(err, ErrFraming) {
+				t.Fatalf("Expected ErrFraming, got %v", err)
+			}
+			if !errors.Is(err, ErrConnectionLost) {
+				t.Errorf("Expected framing error to drop the connection, got %v", err)
+			}
+			if state := client.State(); state != StateDisconnected {
+				t.Errorf("Expected state Disconnected, got %v", state)
+			}
+		})
+	}
+}
+
+func TestSendRRDataShortReply(t *testing.T) {
+	client, cleanup := newFramingClient(t, time.Second, func(n int, request EIPHeader) []byte {
+		// Shorter than the interface handle and timeout
+		return request.encode([]byte{0, 0, 0, 0})
+	})
+	defer cleanup()
+
+	_, err := client.SendRRData(0, 10, []byte("TEST"))
+	if err == nil || !strings.Contains(err.Error(), "too short") {
+		t.Fatalf("Expected short reply error, got %v", err)
+	}
+}
+
+func TestUnsolicitedPacketRouted(t *testing.T) {
+	client, cleanup := newFramingClient(t, time.Second, func(n int, request EIPHeader) []byte {
+		// A status packet the client never asked for, then the reply
+		status := EIPHeader{Command: EIPCommandIndicateStatus, SessionHandle: 1}
+		return append(status.encode([]byte("STATUS")), rrReply(request, []byte("DATA"))...)
+	})
+	defer cleanup()
+
+	var got []string
+	client.OnUnsolicited(func(header EIPHeader, data []byte) {
+		if header.Command != EIPCommandIndicateStatus {
+			t.Errorf("Unexpected unsolicited command %#04x", header.Command)
+		}
+		got = append(got, string(data))
+	})
+
+	data, err := client.SendRRData(0, 10, []byte("TEST"))
+	if err != nil {
+		t.Fatalf("Failed to send RR data: %v", err)
+	}
+	if string(data) != "DATA" {
+		t.Errorf("Expected reply 'DATA', got %q", data)
+	}
+	if len(got) != 1 || got[0] != "STATUS" {
+		t.Errorf("Expected one unsolicited 'STATUS' packet, got %q", got)
+	}
+}
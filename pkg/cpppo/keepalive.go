@@ -0,0 +1,118 @@
+package cpppo
+
+import (
+	"time"
+)
+
+// KeepaliveMode selects the probe a Client sends on an idle connection
+type KeepaliveMode int
+
+const (
+	// KeepaliveNOP sends an encapsulation NOP. It has no reply, so it only
+	// detects a dead peer once the TCP stack reports the write as failed,
+	// but it costs the device nothing.
+	KeepaliveNOP KeepaliveMode = iota
+
+	// KeepaliveIdentity sends a List Identity request and waits for the
+	// reply, detecting a silent peer within one timeout.
+	KeepaliveIdentity
+)
+
+// keepalive is the state of a running keepalive loop
+type keepalive struct {
+	stop chan struct{}
+	done chan struct{}
+}
+
+// SendNOP sends an encapsulation NOP command. The device does not reply.
+func (c *Client) SendNOP() error {
+	c.mu.Lock()
+	defer c.unlock()
+
+	if err := c.ensureConnected(); err != nil {
+		return err
+	}
+
+	return c.send(EIPCommandNOP, nil)
+}
+
+// Ping checks that the device answers by sending a List Identity request
+func (c *Client) Ping() error {
+	_, err := c.ListIdentity()
+	return err
+}
+
+// StartKeepalive probes the connection whenever it has been idle for the
+// given interval. A failed probe tears the connection down and redials it
+// according to the reconnect policy. Any running keepalive is replaced.
+func (c *Client) StartKeepalive(interval time.Duration, mode KeepaliveMode) {
+	c.StopKeepalive()
+	if interval <= 0 {
+		return
+	}
+
+	ka := &keepalive{
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+
+	c.mu.Lock()
+	c.keepalive = ka
+	c.unlock()
+
+	go c.keepaliveLoop(ka, interval, mode)
+}
+
+// StopKeepalive stops the keepalive loop, if one is running
+func (c *Client) StopKeepalive() {
+	c.mu.Lock()
+	ka := c.keepalive
+	c.keepalive = nil
+	c.unlock()
+
+	if ka != nil {
+		close(ka.stop)
+		<-ka.done
+	}
+}
+
+// keepaliveLoop sends probes on idle connections until stopped
+func (c *Client) keepaliveLoop(ka *keepalive, interval time.Duration, mode KeepaliveMode) {
+	defer close(ka.done)
+
+	// Check twice per interval so an idle period is noticed promptly
+	ticker := time.NewTicker(interval / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ka.stop:
+			return
+		case <-ticker.C:
+			if c.idleFor() < interval {
+				continue
+			}
+
+			var err error
+			switch mode {
+			case KeepaliveIdentity:
+				err = c.Ping()
+			default:
+				err = c.SendNOP()
+			}
+
+			// A failed probe means the peer is gone even if the socket
+			// looks healthy; redial now rather than on the next request
+			if err != nil {
+				c.Reconnect()
+			}
+		}
+	}
+}
+
+// idleFor returns how long the connection has gone without traffic
+func (c *Client) idleFor() time.Duration {
+	c.mu.Lock()
+	defer c.unlock()
+	return time.Since(c.lastActivity)
+}
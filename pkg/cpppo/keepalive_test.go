@@ -0,0 +1,92 @@
+package cpppo
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestKeepaliveSendsNOPWhenIdle(t *testing.T) {
+	nops := make(chan struct{}, 10)
+
+	addr, cleanup := setupMockServer(t, func(conn net.Conn) {
+		for {
+			header := make([]byte, 24)
+			if _, err := io.ReadFull(conn, header); err != nil {
+				return
+			}
+			if binary.LittleEndian.Uint16(header[0:2]) == EIPCommandNOP {
+				nops <- struct{}{}
+			}
+		}
+	})
+	defer cleanup()
+
+	client, err := NewClient(addr, 1*time.Second)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	client.StartKeepalive(20*time.Millisecond, KeepaliveNOP)
+
+	select {
+	case <-nops:
+	case <-time.After(1 * time.Second):
+		t.Fatal("Expected a NOP on the idle connection")
+	}
+
+	// No further probes once stopped
+	client.StopKeepalive()
+	for len(nops) > 0 {
+		<-nops
+	}
+	time.Sleep(50 * time.Millisecond)
+	if len(nops) != 0 {
+		t.Errorf("Expected no NOPs after StopKeepalive, got %d", len(nops))
+	}
+}
+
+func TestKeepaliveReconnectsDeadPeer(t *testing.T) {
+	addr, cleanup := setupMockListener(t, func(n int, conn net.Conn) {
+		if n > 0 {
+			serveEIP(conn, nil)
+			return
+		}
+
+		// The first peer goes silent: it swallows every request
+		io.Copy(io.Discard, conn)
+	})
+	defer cleanup()
+
+	client, err := NewClient(addr, 50*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+	client.SetReconnectPolicy(ReconnectPolicy{MaxAttempts: 3, InitialDelay: 10 * time.Millisecond, MaxDelay: 50 * time.Millisecond})
+
+	states := make(chan ConnState, 10)
+	client.OnStateChange(func(state ConnState) {
+		states <- state
+	})
+
+	client.StartKeepalive(20*time.Millisecond, KeepaliveIdentity)
+
+	timeout := time.After(2 * time.Second)
+	for sawDisconnect := false; ; {
+		select {
+		case state := <-states:
+			if state == StateDisconnected {
+				sawDisconnect = true
+			}
+			if state == StateConnected && sawDisconnect {
+				return // Redialed after the probe timed out
+			}
+		case <-timeout:
+			t.Fatal("Expected keepalive to detect the silent peer and reconnect")
+		}
+	}
+}
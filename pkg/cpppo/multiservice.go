@@ -0,0 +1,122 @@
+package cpppo
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Status reported by a Multiple Service Packet reply when at least one of
+// the embedded services failed
+const CIPStatusEmbeddedServiceError = 0x1E
+
+// MaxUnconnectedMessageSize is the largest request or reply an unconnected
+// (Send RR Data) message may carry. Batches must be split to stay within it.
+const MaxUnconnectedMessageSize = 504
+
+// ErrBatchTooLarge is returned by ReadTags and WriteTags when the encoded
+// Multiple Service Packet exceeds MaxUnconnectedMessageSize
+var ErrBatchTooLarge = errors.New("batch too large for one message")
+
+// TagRead names one tag to read in a batch
+type TagRead struct {
+	Name     string
+	DataType byte
+}
+
+// TagWrite names one tag to write in a batch and the value to write
+type TagWrite struct {
+	Name     string
+	DataType byte
+	Value    interface{}
+}
+
+// TagResult is the outcome of one read in a batch
+type TagResult struct {
+	Value interface{}
+	Err   error
+}
+
+// AttributeRef names one attribute of a CIP object instance to read in a
+// batch
+type AttributeRef struct {
+	Class     uint16
+	Instance  uint32
+	Attribute uint16
+}
+
+// AttributeResult is the outcome of one attribute read in a batch
+type AttributeResult struct {
+	Data []byte // Raw attribute value
+	Err  error
+}
+
+// BuildCIPMultipleServiceRequest wraps several CIP requests in one Multiple
+// Service Packet addressed to the Message Router. The controller executes
+// them in order and answers them in a single reply.
+func BuildCIPMultipleServiceRequest(requests [][]byte) []byte {
+	path := BuildCIPLogicalPath(CIPClassMessageRouter, 1)
+
+	// Service code, path size in words, path
+	request := []byte{CIPServiceMultipleService, byte(len(path) / 2)}
+	request = append(request, path...)
+
+	// Service count and offsets, both relative to the start of the count
+	offset := 2 + 2*len(requests)
+	request = binary.LittleEndian.AppendUint16(request, uint16(len(requests)))
+	for _, r := range requests {
+		request = binary.LittleEndian.AppendUint16(request, uint16(offset))
+		offset += len(r)
+	}
+
+	for _, r := range requests {
+		request = append(request, r...)
+	}
+
+	return request
+}
+
+// ParseCIPMultipleServiceResponse splits a Multiple Service Packet reply into
+// the replies to the embedded requests, each of which still carries its own
+// service code and status
+func ParseCIPMultipleServiceResponse(response []byte) ([][]byte, error) {
+	if len(response) < 2 {
+		return nil, errors.New("response too short")
+	}
+
+	if response[0] != CIPServiceMultipleService|0x80 {
+		return nil, fmt.Errorf("unexpected service in reply: %#x", response[0])
+	}
+
+	// An embedded service error is reported per reply below
+	if status := response[1]; status != 0 && status != CIPStatusEmbeddedServiceError {
+		return nil, CIPStatusToError(status)
+	}
+
+	data := response[2:]
+	if len(data) < 2 {
+		return nil, errors.New("multiple service reply missing count")
+	}
+
+	count := int(binary.LittleEndian.Uint16(data[0:2]))
+	if len(data) < 2+2*count {
+		return nil, errors.New("multiple service reply offsets truncated")
+	}
+
+	offsets := make([]int, count+1)
+	for i := 0; i < count; i++ {
+		offsets[i] = int(binary.LittleEndian.Uint16(data[2+2*i:]))
+	}
+	offsets[count] = len(data)
+
+	replies := make([][]byte, count)
+	for i := 0; i < count; i++ {
+		start, end := offsets[i], offsets[i+1]
+		if start < 2+2*count || start > end || end > len(data) {
+			return nil, fmt.Errorf("invalid offset for reply %d", i)
+		}
+		replies[i] = data[start:end]
+	}
+
+	return replies, nil
+}
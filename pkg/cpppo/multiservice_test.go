@@ -0,0 +1,254 @@
+package cpppo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// answerMultiple answers a Multiple Service Packet by passing each embedded
+// request to handle
+func answerMultiple(request []byte, handle func(request []byte) []byte) []byte {
+	data := request[2+int(request[1])*2:]
+	count := int(binary.LittleEndian.Uint16(data))
+
+	var replies [][]byte
+	status := byte(0)
+	for i := 0; i < count; i++ {
+		start := int(binary.LittleEndian.Uint16(data[2+2*i:]))
+		end := len(data)
+		if i+1 < count {
+			end = int(binary.LittleEndian.Uint16(data[4+2*i:]))
+		}
+		reply := handle(data[start:end])
+		if reply[1] != 0 {
+			status = CIPStatusEmbeddedServiceError
+		}
+		replies = append(replies, reply)
+	}
+
+	// Reuse the request encoder for the count, offsets and replies
+	body := BuildCIPMultipleServiceRequest(replies)[2+len(BuildCIPLogicalPath(CIPClassMessageRouter, 1)):]
+	return append([]byte{CIPServiceMultipleService | 0x80, status}, body...)
+}
+
+// symbolicTagName extracts the tag name from a request with a symbolic path
+func symbolicTagName(request []byte) (string, []byte) {
+	pathEnd := 2 + int(request[1])*2
+	return string(request[4 : 4+int(request[3])]), request[pathEnd:]
+}
+
+func TestMultipleServiceRoundTrip(t *testing.T) {
+	requests := [][]byte{
+		BuildCIPReadRequest("A", 1),
+		BuildCIPReadRequest("LongerTag", 1),
+		{CIPServiceGetAttributeSingle, 0},
+	}
+
+	packet := BuildCIPMultipleServiceRequest(requests)
+	if packet[0] != CIPServiceMultipleService {
+		t.Fatalf("Expected service %#x, got %#x", CIPServiceMultipleService, packet[0])
+	}
+
+	// Echo every embedded request back as its reply
+	response := answerMultiple(packet, func(request []byte) []byte {
+		return append([]byte{request[0] | 0x80, 0}, request...)
+	})
+
+	replies, err := ParseCIPMultipleServiceResponse(response)
+	if err != nil {
+		t.Fatalf("Failed to parse reply: %v", err)
+	}
+	if len(replies) != len(requests) {
+		t.Fatalf("Expected %d replies, got %d", len(requests), len(replies))
+	}
+	for i, reply := range replies {
+		if !bytes.Equal(reply[2:], requests[i]) {
+			t.Errorf("Reply %d: expected %v, got %v", i, requests[i], reply[2:])
+		}
+	}
+
+	if _, err := ParseCIPMultipleServiceResponse([]byte{CIPServiceMultipleService | 0x80, 0, 2, 0, 0xFF, 0}); err == nil {
+		t.Error("Expected error for truncated offsets")
+	}
+	if _, err := ParseCIPMultipleServiceResponse([]byte{CIPServiceMultipleService | 0x80, 0x08}); err == nil {
+		t.Error("Expected error for failed packet")
+	}
+}
+
+func TestPLCClientReadWriteTags(t *testing.T) {
+	var mu sync.Mutex
+	packets := 0
+	tags := map[string][]byte{
+		"X": EncodeFloat32(1.5),
+		"Y": EncodeFloat32(-2),
+	}
+
+	addr, cleanup := setupEIPServer(t, func(request []byte) []byte {
+		mu.Lock()
+		defer mu.Unlock()
+		packets++
+
+		return answerMultiple(request, func(request []byte) []byte {
+			name, rest := symbolicTagName(request)
+			switch request[0] {
+			case CIPServiceReadTag:
+				value, ok := tags[name]
+				if !ok {
+					return []byte{CIPServiceReadTag | 0x80, 0x05}
+				}
+				return append([]byte{CIPServiceReadTag | 0x80, 0, CIPDataTypeREAL, 1}, value...)
+			case CIPServiceWriteTag:
+				tags[name] = append([]byte(nil), rest[2:]...)
+				return []byte{CIPServiceWriteTag | 0x80, 0}
+			}
+			return []byte{request[0] | 0x80, 0x08}
+		})
+	})
+	defer cleanup()
+
+	plc, err := NewPLCClient(addr, 1*time.Second)
+	if err != nil {
+		t.Fatalf("NewPLCClient returned error: %v", err)
+	}
+	defer plc.Close()
+
+	errs, err := plc.WriteTags([]TagWrite{
+		{"X", CIPDataTypeREAL, float32(10)},
+		{"Z", CIPDataTypeREAL, float32(30)},
+	})
+	if err != nil {
+		t.Fatalf("WriteTags returned error: %v", err)
+	}
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("Write %d failed: %v", i, err)
+		}
+	}
+
+	results, err := plc.ReadTags([]TagRead{
+		{"X", CIPDataTypeREAL},
+		{"Missing", CIPDataTypeREAL},
+		{"Z", CIPDataTypeREAL},
+	})
+	if err != nil {
+		t.Fatalf("ReadTags returned error: %v", err)
+	}
+	if results[0].Value != float32(10) || results[0].Err != nil {
+		t.Errorf("Expected X = 10, got %+v", results[0])
+	}
+	if results[1].Err == nil {
+		t.Errorf("Expected error reading missing tag, got %+v", results[1])
+	}
+	if results[2].Value != float32(30) || results[2].Err != nil {
+		t.Errorf("Expected Z = 30, got %+v", results[2])
+	}
+
+	// A value that cannot be encoded stops the batch before anything is sent
+	if _, err := plc.WriteTags([]TagWrite{{"X", CIPDataTypeREAL, "text"}}); err == nil {
+		t.Error("Expected encode error")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if packets != 2 {
+		t.Errorf("Expected one packet per batch, got %d", packets)
+	}
+}
+
+func TestPLCClientWriteTagsStaleSymbols(t *testing.T) {
+	var mu sync.Mutex
+	written := map[string]int{}
+	var batchSizes []int
+
+	addr, cleanup := setupEIPServer(t, func(request []byte) []byte {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if request[0] == CIPServiceGetInstanceAttributeList {
+			reply := []byte{CIPServiceGetInstanceAttributeList | 0x80, 0x00}
+			reply = append(reply, symbolRecord(3, "A", CIPDataTypeDINT)...)
+			return append(reply, symbolRecord(4, "B", CIPDataTypeDINT)...)
+		}
+
+		batchSizes = append(batchSizes, int(binary.LittleEndian.Uint16(request[2+int(request[1])*2:])))
+		return answerMultiple(request, func(request []byte) []byte {
+			name := ""
+			if request[2] == CIPSegmentClass8 {
+				// Instance 4 no longer exists on the controller
+				if request[5] == 4 {
+					return []byte{CIPServiceWriteTag | 0x80, 0x05}
+				}
+				name = "A"
+			} else {
+				name, _ = symbolicTagName(request)
+			}
+			written[name]++
+			return []byte{CIPServiceWriteTag | 0x80, 0}
+		})
+	})
+	defer cleanup()
+
+	plc, err := NewPLCClient(addr, 1*time.Second)
+	if err != nil {
+		t.Fatalf("NewPLCClient returned error: %v", err)
+	}
+	defer plc.Close()
+	if _, err := plc.BrowseTags(); err != nil {
+		t.Fatalf("BrowseTags returned error: %v", err)
+	}
+
+	errs, err := plc.WriteTags([]TagWrite{
+		{"A", CIPDataTypeDINT, int32(1)},
+		{"B", CIPDataTypeDINT, int32(2)},
+		{"C", CIPDataTypeDINT, int32(3)},
+	})
+	if err != nil {
+		t.Fatalf("WriteTags returned error: %v", err)
+	}
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("Write %d failed: %v", i, err)
+		}
+	}
+
+	// Only the stale write is sent again
+	mu.Lock()
+	defer mu.Unlock()
+	if written["A"] != 1 || written["B"] != 1 || written["C"] != 1 {
+		t.Errorf("Expected every tag written once, got %v", written)
+	}
+	if len(batchSizes) != 2 || batchSizes[0] != 3 || batchSizes[1] != 1 {
+		t.Errorf("Expected batches of 3 and 1 writes, got %v", batchSizes)
+	}
+}
+
+func TestPLCClientBatchTooLarge(t *testing.T) {
+	packets := 0
+	addr, cleanup := setupEIPServer(t, func(request []byte) []byte {
+		packets++
+		return []byte{request[0] | 0x80, 0x08}
+	})
+	defer cleanup()
+
+	plc, err := NewPLCClient(addr, 1*time.Second)
+	if err != nil {
+		t.Fatalf("NewPLCClient returned error: %v", err)
+	}
+	defer plc.Close()
+
+	var reads []TagRead
+	for i := 0; i < 20; i++ {
+		reads = append(reads, TagRead{fmt.Sprintf("Program:MainProgram.Station%02d", i), CIPDataTypeDINT})
+	}
+	if _, err := plc.ReadTags(reads); !errors.Is(err, ErrBatchTooLarge) {
+		t.Errorf("Expected ErrBatchTooLarge, got %v", err)
+	}
+	if packets != 0 {
+		t.Errorf("Expected nothing sent, got %d packets", packets)
+	}
+}
@@ -1,15 +1,15 @@
 package cpppo
 
 import (
-	"encoding/binary"
+	"errors"
 	"fmt"
-	"math"
 	"time"
 )
 
 // PLCClient provides a higher-level interface for PLC communication
 type PLCClient struct {
-	client *Client
+	client  *Client
+	symbols *symbolTable
 }
 
 // NewPLCClient creates a new PLC client
@@ -24,9 +24,38 @@ func NewPLCClient(address string, timeout time.Duration) (*PLCClient, error) {
 		return nil, err
 	}
 
-	return &PLCClient{
-		client: client,
-	}, nil
+	plc := &PLCClient{
+		client:  client,
+		symbols: newSymbolTable(),
+	}
+
+	// Instance IDs may not survive whatever broke the connection (such as a
+	// program download), so resolve tags by name again once it breaks. The
+	// table is dropped before the failed request returns, so a retry builds
+	// its path by name.
+	client.OnStateChange(func(state ConnState) {
+		if state == StateDisconnected || state == StateConnected {
+			plc.symbols.invalidate()
+		}
+	})
+
+	return plc, nil
+}
+
+// SetReconnectPolicy replaces the policy used to recover broken connections
+func (p *PLCClient) SetReconnectPolicy(policy ReconnectPolicy) {
+	p.client.SetReconnectPolicy(policy)
+}
+
+// OnStateChange registers a handler that is called whenever the connection
+// state changes
+func (p *PLCClient) OnStateChange(handler func(ConnState)) {
+	p.client.OnStateChange(handler)
+}
+
+// State returns the current connection state
+func (p *PLCClient) State() ConnState {
+	return p.client.State()
 }
 
 // Close closes the PLC client
@@ -34,13 +63,82 @@ func (p *PLCClient) Close() error {
 	return p.client.Close()
 }
 
+// BrowseTags reads the controller's Symbol Object and remembers the instance
+// ID of every tag, so later reads and writes of those tags can use the
+// shorter instance path instead of the symbolic name
+func (p *PLCClient) BrowseTags() ([]SymbolInfo, error) {
+	var symbols []SymbolInfo
+	start := uint32(0)
+
+	for {
+		response, err := p.client.SendRRData(0, 10, BuildCIPBrowseRequest(start))
+		if err != nil {
+			return nil, err
+		}
+
+		batch, more, err := ParseCIPBrowseResponse(response)
+		if err != nil {
+			return nil, err
+		}
+		symbols = append(symbols, batch...)
+
+		if !more || len(batch) == 0 {
+			break
+		}
+
+		// Continue after the last instance the controller sent
+		start = batch[len(batch)-1].Instance + 1
+	}
+
+	if p.symbols == nil {
+		p.symbols = newSymbolTable()
+	}
+	p.symbols.replace(symbols)
+
+	return symbols, nil
+}
+
+// InvalidateSymbols forgets all resolved instance IDs, so every tag is
+// addressed by name until BrowseTags is called again
+func (p *PLCClient) InvalidateSymbols() {
+	p.symbols.invalidate()
+}
+
+// ResolvedSymbols returns the number of tags addressed by instance ID
+func (p *PLCClient) ResolvedSymbols() int {
+	return p.symbols.len()
+}
+
 // ReadTag reads a tag from the PLC
 func (p *PLCClient) ReadTag(tagName string, dataType byte) (interface{}, error) {
+	return retryRead(p, func() (interface{}, error) {
+		return p.readTag(tagName, dataType)
+	})
+}
+
+// readTag reads a tag once, preferring its instance path
+func (p *PLCClient) readTag(tagName string, dataType byte) (interface{}, error) {
+	// Prefer the instance path when the tag has been resolved
+	if instance, ok := p.symbols.lookup(tagName); ok {
+		value, err := p.readTagPath(BuildCIPSymbolInstancePath(instance), dataType)
+		if !isStaleSymbolError(err) {
+			return value, err
+		}
+
+		// The instance table no longer matches the controller
+		p.symbols.invalidate()
+	}
+
+	return p.readTagPath(BuildCIPPath(tagName), dataType)
+}
+
+// readTagPath reads a tag addressed by an encoded CIP path
+func (p *PLCClient) readTagPath(path []byte, dataType byte) (interface{}, error) {
 	// Build CIP read request
-	request := BuildCIPReadRequest(tagName, 1)
+	request := BuildCIPReadRequestPath(path, 1)
 
 	// Send request
-	response, err := p.client.SendRRData(0, 10, request)
+	response, err := p.send(request)
 	if err != nil {
 		return nil, err
 	}
@@ -49,60 +147,272 @@ func (p *PLCClient) ReadTag(tagName string, dataType byte) (interface{}, error)
 	return ParseCIPReadResponse(response, dataType)
 }
 
-// WriteTag writes a value to a tag in the PLC
-func (p *PLCClient) WriteTag(tagName string, dataType byte, value interface{}) error {
-	var data []byte
-
-	// Convert the value to the appropriate binary format based on data type
-	switch dataType {
-	case CIPDataTypeBOOL:
-		boolValue, ok := value.(bool)
-		if !ok {
-			return fmt.Errorf("value is not a bool")
+// send sends a request once
+func (p *PLCClient) send(request []byte) ([]byte, error) {
+	return p.client.SendRRData(0, 10, request)
+}
+
+// sendIdempotent sends a request that is safe to repeat, retrying it once on
+// a fresh connection if the connection was lost. The request is sent again
+// as built, so it must not address tags by instance ID; tag reads retry
+// through retryRead instead.
+func (p *PLCClient) sendIdempotent(request []byte) ([]byte, error) {
+	response, err := p.send(request)
+	if errors.Is(err, ErrConnectionLost) && p.client.retryReads() {
+		response, err = p.send(request)
+	}
+	return response, err
+}
+
+// retryRead runs read again on a fresh connection if the connection was
+// lost. Losing the connection drops the instance table, so the retry
+// addresses tags by name rather than by IDs that may now name other tags.
+func retryRead[T any](p *PLCClient, read func() (T, error)) (T, error) {
+	value, err := read()
+	if errors.Is(err, ErrConnectionLost) && p.client.retryReads() {
+		value, err = read()
+	}
+	return value, err
+}
+
+// tagPath returns the encoded path for a tag, preferring its instance path
+// when useSymbols is set and the tag has been resolved
+func (p *PLCClient) tagPath(tagName string, useSymbols bool) ([]byte, bool) {
+	if useSymbols {
+		if instance, ok := p.symbols.lookup(tagName); ok {
+			return BuildCIPSymbolInstancePath(instance), true
 		}
-		if boolValue {
-			data = []byte{1}
-		} else {
-			data = []byte{0}
+	}
+	return BuildCIPPath(tagName), false
+}
+
+// ReadTags reads several tags in one Multiple Service Packet, so every value
+// comes from the same controller scan. The error is set only if the batch as
+// a whole failed, including a batch over MaxUnconnectedMessageSize
+// (ErrBatchTooLarge); a tag that could not be read has its own error in the
+// results.
+func (p *PLCClient) ReadTags(reads []TagRead) ([]TagResult, error) {
+	if len(reads) == 0 {
+		return nil, nil
+	}
+
+	return retryRead(p, func() ([]TagResult, error) {
+		results, stale, err := p.readTags(reads, true)
+		if err == nil && stale {
+			// The instance table no longer matches the controller
+			p.symbols.invalidate()
+			results, _, err = p.readTags(reads, false)
 		}
+		return results, err
+	})
+}
+
+// readTags sends one batch of reads and reports whether any instance path
+// was rejected as stale
+func (p *PLCClient) readTags(reads []TagRead, useSymbols bool) ([]TagResult, bool, error) {
+	requests := make([][]byte, len(reads))
+	resolved := make([]bool, len(reads))
+	for i, read := range reads {
+		var path []byte
+		path, resolved[i] = p.tagPath(read.Name, useSymbols)
+		requests[i] = BuildCIPReadRequestPath(path, 1)
+	}
+
+	replies, err := p.sendMultiple(requests, p.send)
+	if err != nil {
+		return nil, false, err
+	}
+
+	results := make([]TagResult, len(reads))
+	stale := false
+	for i, reply := range replies {
+		results[i].Value, results[i].Err = ParseCIPReadResponse(reply, reads[i].DataType)
+		stale = stale || (resolved[i] && isStaleSymbolError(results[i].Err))
+	}
+
+	return results, stale, nil
+}
+
+// WriteTags writes several tags in one Multiple Service Packet, so the
+// controller applies them together. Values are encoded before anything is
+// sent; if one cannot be encoded nothing is written. The error is set only
+// if the batch as a whole failed, including a batch over
+// MaxUnconnectedMessageSize (ErrBatchTooLarge); a tag that could not be
+// written has its own error in the returned slice. Writes rejected because
+// a resolved instance ID went stale are sent again by name on their own.
+func (p *PLCClient) WriteTags(writes []TagWrite) ([]error, error) {
+	if len(writes) == 0 {
+		return nil, nil
+	}
 
-	case CIPDataTypeSINT:
-		intValue, ok := value.(int8)
-		if !ok {
-			return fmt.Errorf("value is not an int8")
+	data := make([][]byte, len(writes))
+	for i, write := range writes {
+		encoded, err := EncodeValue(write.DataType, write.Value)
+		if err != nil {
+			return nil, fmt.Errorf("tag %s: %w", write.Name, err)
 		}
-		data = []byte{byte(intValue)}
+		data[i] = encoded
+	}
 
-	case CIPDataTypeINT:
-		intValue, ok := value.(int16)
-		if !ok {
-			return fmt.Errorf("value is not an int16")
+	errs, stale, err := p.writeTags(writes, data, true)
+	if err != nil || len(stale) == 0 {
+		return errs, err
+	}
+
+	// The instance table no longer matches the controller. Send only the
+	// rejected writes again, by name, so writes that already took effect
+	// are not repeated.
+	p.symbols.invalidate()
+	retryWrites := make([]TagWrite, len(stale))
+	retryData := make([][]byte, len(stale))
+	for j, i := range stale {
+		retryWrites[j], retryData[j] = writes[i], data[i]
+	}
+
+	retryErrs, _, err := p.writeTags(retryWrites, retryData, false)
+	for j, i := range stale {
+		if err != nil {
+			errs[i] = err
+		} else {
+			errs[i] = retryErrs[j]
 		}
-		data = make([]byte, 2)
-		binary.LittleEndian.PutUint16(data, uint16(intValue))
+	}
+	return errs, nil
+}
+
+// writeTags sends one batch of writes and returns the indexes of the
+// writes whose instance path was rejected as stale
+func (p *PLCClient) writeTags(writes []TagWrite, data [][]byte, useSymbols bool) ([]error, []int, error) {
+	requests := make([][]byte, len(writes))
+	resolved := make([]bool, len(writes))
+	for i, write := range writes {
+		var path []byte
+		path, resolved[i] = p.tagPath(write.Name, useSymbols)
+		requests[i] = BuildCIPWriteRequestPath(path, write.DataType, data[i])
+	}
+
+	replies, err := p.sendMultiple(requests, p.send)
+	if err != nil {
+		return nil, nil, err
+	}
 
-	case CIPDataTypeDINT:
-		intValue, ok := value.(int32)
-		if !ok {
-			return fmt.Errorf("value is not an int32")
+	errs := make([]error, len(writes))
+	var stale []int
+	for i, reply := range replies {
+		_, errs[i] = ParseCIPResponse(reply)
+		if resolved[i] && isStaleSymbolError(errs[i]) {
+			stale = append(stale, i)
 		}
-		data = make([]byte, 4)
-		binary.LittleEndian.PutUint32(data, uint32(intValue))
+	}
+
+	return errs, stale, nil
+}
+
+// sendMultiple sends requests in one Multiple Service Packet and returns one
+// reply per request. A packet over MaxUnconnectedMessageSize is rejected
+// rather than split, since splitting would lose the single-scan guarantee.
+func (p *PLCClient) sendMultiple(requests [][]byte, send func([]byte) ([]byte, error)) ([][]byte, error) {
+	packet := BuildCIPMultipleServiceRequest(requests)
+	if len(packet) > MaxUnconnectedMessageSize {
+		return nil, fmt.Errorf("%w: %d requests encode to %d bytes, limit is %d",
+			ErrBatchTooLarge, len(requests), len(packet), MaxUnconnectedMessageSize)
+	}
+
+	response, err := send(packet)
+	if err != nil {
+		return nil, err
+	}
+
+	replies, err := ParseCIPMultipleServiceResponse(response)
+	if err != nil {
+		return nil, err
+	}
+	if len(replies) != len(requests) {
+		return nil, fmt.Errorf("expected %d replies, got %d", len(requests), len(replies))
+	}
+
+	return replies, nil
+}
+
+// WriteTag writes a value to a tag in the PLC
+func (p *PLCClient) WriteTag(tagName string, dataType byte, value interface{}) error {
+	data, err := EncodeValue(dataType, value)
+	if err != nil {
+		return err
+	}
 
-	case CIPDataTypeREAL:
-		floatValue, ok := value.(float32)
-		if !ok {
-			return fmt.Errorf("value is not a float32")
+	// Prefer the instance path when the tag has been resolved
+	if instance, ok := p.symbols.lookup(tagName); ok {
+		err := p.writeTagPath(BuildCIPSymbolInstancePath(instance), dataType, data)
+		if !isStaleSymbolError(err) {
+			return err
 		}
-		data = make([]byte, 4)
-		binary.LittleEndian.PutUint32(data, math.Float32bits(floatValue))
 
-	default:
-		return fmt.Errorf("unsupported data type: %#x", dataType)
+		// The instance table no longer matches the controller
+		p.symbols.invalidate()
+	}
+
+	return p.writeTagPath(BuildCIPPath(tagName), dataType, data)
+}
+
+// GetAttributeSingle reads one attribute of a CIP object instance and
+// returns its raw value
+func (p *PLCClient) GetAttributeSingle(class uint16, instance uint32, attribute uint16) ([]byte, error) {
+	request := BuildCIPGetAttributeSingleRequest(class, instance, attribute)
+
+	response, err := p.sendIdempotent(request)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseCIPResponse(response)
+}
+
+// GetAttributesSingle reads several attributes in one Multiple Service
+// Packet, so every value comes from the same controller scan. The error is
+// set only if the batch as a whole failed, including a batch over
+// MaxUnconnectedMessageSize (ErrBatchTooLarge); an attribute that could not
+// be read has its own error in the results.
+func (p *PLCClient) GetAttributesSingle(refs []AttributeRef) ([]AttributeResult, error) {
+	if len(refs) == 0 {
+		return nil, nil
+	}
+
+	requests := make([][]byte, len(refs))
+	for i, ref := range refs {
+		requests[i] = BuildCIPGetAttributeSingleRequest(ref.Class, ref.Instance, ref.Attribute)
+	}
+
+	replies, err := p.sendMultiple(requests, p.sendIdempotent)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]AttributeResult, len(refs))
+	for i, reply := range replies {
+		results[i].Data, results[i].Err = ParseCIPResponse(reply)
+	}
+	return results, nil
+}
+
+// SetAttributeSingle writes the raw value of one attribute of a CIP object
+// instance
+func (p *PLCClient) SetAttributeSingle(class uint16, instance uint32, attribute uint16, data []byte) error {
+	request := BuildCIPSetAttributeSingleRequest(class, instance, attribute, data)
+
+	response, err := p.client.SendRRData(0, 10, request)
+	if err != nil {
+		return err
 	}
 
+	_, err = ParseCIPResponse(response)
+	return err
+}
+
+// writeTagPath writes encoded data to a tag addressed by an encoded CIP path
+func (p *PLCClient) writeTagPath(path []byte, dataType byte, data []byte) error {
 	// Build CIP write request
-	request := BuildCIPWriteRequest(tagName, dataType, data)
+	request := BuildCIPWriteRequestPath(path, dataType, data)
 
 	// Send request
 	response, err := p.client.SendRRData(0, 10, request)
@@ -4,6 +4,7 @@ import (
 	"encoding/binary"
 	"errors"
 	"net"
+	"sync"
 	"testing"
 	"time"
 )
@@ -202,6 +203,7 @@ func TestNewPLCClient(t *testing.T) {
 		resp[6] = 0
 		resp[7] = 0 // Session handle (high byte)
 		// Status is 0 (success)
+		copy(resp[12:20], buf[12:20]) // Echo sender context
 		// Version is 1
 		resp[24] = 1
 		resp[25] = 0
@@ -227,3 +229,95 @@ func TestNewPLCClient(t *testing.T) {
 		t.Errorf("Expected session handle 1, got %d", plc.client.sessionHandle)
 	}
 }
+
+func TestPLCClientRetriesReadAfterReconnect(t *testing.T) {
+	addr, cleanup := setupMockListener(t, dropFirstConnection(func(request []byte) []byte {
+		return []byte{CIPServiceReadTag | 0x80, 0x00, CIPDataTypeDINT, 0x01, 42, 0, 0, 0}
+	}))
+	defer cleanup()
+
+	plc, err := NewPLCClient(addr, 1*time.Second)
+	if err != nil {
+		t.Fatalf("NewPLCClient returned error: %v", err)
+	}
+	defer plc.Close()
+	plc.SetReconnectPolicy(ReconnectPolicy{MaxAttempts: 3, InitialDelay: 10 * time.Millisecond, MaxDelay: 50 * time.Millisecond, RetryReads: true})
+
+	value, err := plc.ReadTag("Counter", CIPDataTypeDINT)
+	if err != nil {
+		t.Fatalf("Expected read to be retried transparently, got %v", err)
+	}
+	if value != int32(42) {
+		t.Errorf("Expected value 42, got %v", value)
+	}
+	if plc.State() != StateConnected {
+		t.Errorf("Expected state %v, got %v", StateConnected, plc.State())
+	}
+}
+
+func TestPLCClientAttributeSingle(t *testing.T) {
+	attributes := map[string][]byte{}
+	var mu sync.Mutex
+	var answer func(request []byte) []byte
+	answer = func(request []byte) []byte {
+		if request[0] == CIPServiceMultipleService {
+			return answerMultiple(request, answer)
+		}
+
+		// Key attributes by their encoded path
+		pathLen := int(request[1]) * 2
+		key := string(request[2 : 2+pathLen])
+		switch request[0] {
+		case CIPServiceSetAttributeSingle:
+			attributes[key] = append([]byte(nil), request[2+pathLen:]...)
+			return []byte{CIPServiceSetAttributeSingle | 0x80, 0x00}
+		case CIPServiceGetAttributeSingle:
+			value, ok := attributes[key]
+			if !ok {
+				return []byte{CIPServiceGetAttributeSingle | 0x80, 0x05}
+			}
+			return append([]byte{CIPServiceGetAttributeSingle | 0x80, 0x00}, value...)
+		}
+		return []byte{request[0] | 0x80, 0x08}
+	}
+	addr, cleanup := setupEIPServer(t, func(request []byte) []byte {
+		mu.Lock()
+		defer mu.Unlock()
+		return answer(request)
+	})
+	defer cleanup()
+
+	plc, err := NewPLCClient(addr, 1*time.Second)
+	if err != nil {
+		t.Fatalf("NewPLCClient returned error: %v", err)
+	}
+	defer plc.Close()
+
+	if _, err := plc.GetAttributeSingle(0x6C, 1, 1); err == nil {
+		t.Error("Expected error reading an attribute that does not exist")
+	}
+
+	if err := plc.SetAttributeSingle(0x6C, 1, 1, EncodeFloat32(42.5)); err != nil {
+		t.Fatalf("SetAttributeSingle returned error: %v", err)
+	}
+
+	data, err := plc.GetAttributeSingle(0x6C, 1, 1)
+	if err != nil {
+		t.Fatalf("GetAttributeSingle returned error: %v", err)
+	}
+	if value, err := DecodeFloat32(data); err != nil || value != 42.5 {
+		t.Errorf("Expected 42.5, got %v (%v)", value, err)
+	}
+
+	// Several attributes in one packet, each with its own outcome
+	results, err := plc.GetAttributesSingle([]AttributeRef{{0x6C, 1, 1}, {0x6C, 2, 1}})
+	if err != nil {
+		t.Fatalf("GetAttributesSingle returned error: %v", err)
+	}
+	if len(results) != 2 || string(results[0].Data) != string(EncodeFloat32(42.5)) || results[0].Err != nil {
+		t.Errorf("Expected 42.5 for the first attribute, got %+v", results)
+	}
+	if len(results) == 2 && results[1].Err == nil {
+		t.Error("Expected error for the attribute that does not exist")
+	}
+}
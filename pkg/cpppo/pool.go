@@ -0,0 +1,280 @@
+package cpppo
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrPoolClosed is returned when checking out a session from a closed pool
+var ErrPoolClosed = errors.New("pool closed")
+
+// PoolConfig configures a PLCPool
+type PoolConfig struct {
+	MaxConns            int           // Maximum sessions open to the controller at once
+	MinConns            int           // Sessions kept open even when idle
+	IdleTimeout         time.Duration // Close sessions idle for longer than this, 0 keeps them
+	HealthCheckInterval time.Duration // How often idle sessions are checked, 0 disables checks
+}
+
+// DefaultPoolConfig is a conservative configuration; Logix controllers
+// typically allow 32 or more CIP connections in total
+var DefaultPoolConfig = PoolConfig{
+	MaxConns:            4,
+	MinConns:            1,
+	IdleTimeout:         5 * time.Minute,
+	HealthCheckInterval: 30 * time.Second,
+}
+
+// PoolStats reports how many sessions a pool holds
+type PoolStats struct {
+	Open  int // Sessions open to the controller
+	Idle  int // Open sessions waiting to be checked out
+	InUse int // Open sessions currently checked out
+}
+
+// pooledConn is an idle session and the time it was returned to the pool
+type pooledConn struct {
+	plc      *PLCClient
+	lastUsed time.Time
+}
+
+// PLCPool shares a bounded set of registered sessions to one controller
+// between goroutines
+type PLCPool struct {
+	address string
+	timeout time.Duration
+	config  PoolConfig
+
+	slots chan struct{}    // One token per open session
+	idle  chan *pooledConn // Sessions ready to be checked out
+
+	mu     sync.Mutex
+	closed bool
+	stop   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewPLCPool creates a pool of sessions to the controller at address and
+// opens MinConns sessions up front
+func NewPLCPool(address string, timeout time.Duration, config PoolConfig) (*PLCPool, error) {
+	if config.MaxConns <= 0 {
+		config.MaxConns = DefaultPoolConfig.MaxConns
+	}
+	if config.MinConns > config.MaxConns {
+		config.MinConns = config.MaxConns
+	}
+
+	p := &PLCPool{
+		address: address,
+		timeout: timeout,
+		config:  config,
+		slots:   make(chan struct{}, config.MaxConns),
+		idle:    make(chan *pooledConn, config.MaxConns),
+		stop:    make(chan struct{}),
+	}
+
+	// Warm up the minimum number of sessions
+	for i := 0; i < config.MinConns; i++ {
+		p.slots <- struct{}{}
+		plc, err := NewPLCClient(address, timeout)
+		if err != nil {
+			<-p.slots
+			p.Close()
+			return nil, err
+		}
+		p.idle <- &pooledConn{plc: plc, lastUsed: time.Now()}
+	}
+
+	if config.HealthCheckInterval > 0 {
+		p.wg.Add(1)
+		go p.healthLoop()
+	}
+
+	return p, nil
+}
+
+// Get checks out a session, opening a new one if the pool is below MaxConns
+// and waiting for one to be returned otherwise. The session must be handed
+// back with Put.
+func (p *PLCPool) Get(ctx context.Context) (*PLCClient, error) {
+	if p.isClosed() {
+		return nil, ErrPoolClosed
+	}
+
+	// Prefer an idle session over opening a new one
+	select {
+	case pc := <-p.idle:
+		return pc.plc, nil
+	default:
+	}
+
+	select {
+	case pc := <-p.idle:
+		return pc.plc, nil
+	case p.slots <- struct{}{}:
+		plc, err := NewPLCClient(p.address, p.timeout)
+		if err != nil {
+			<-p.slots
+			return nil, err
+		}
+		return plc, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Put returns a session to the pool. Sessions whose connection is down, or
+// that are returned after the pool was closed, are closed instead.
+func (p *PLCPool) Put(plc *PLCClient) {
+	if plc == nil {
+		return
+	}
+
+	connected := plc.State() == StateConnected
+
+	// Check and send under the lock, so Close cannot drain idle in between
+	// and miss this session. idle has room for every open session, so the
+	// send does not block.
+	p.mu.Lock()
+	if connected && !p.closed {
+		p.idle <- &pooledConn{plc: plc, lastUsed: time.Now()}
+		p.mu.Unlock()
+		return
+	}
+	p.mu.Unlock()
+
+	p.discard(plc)
+}
+
+// discard closes a session and frees its slot
+func (p *PLCPool) discard(plc *PLCClient) {
+	plc.Close()
+	<-p.slots
+}
+
+// ReadTag reads a tag using a pooled session
+func (p *PLCPool) ReadTag(tagName string, dataType byte) (interface{}, error) {
+	return p.ReadTagContext(context.Background(), tagName, dataType)
+}
+
+// ReadTagContext reads a tag using a pooled session, giving up if no session
+// becomes available before ctx is done
+func (p *PLCPool) ReadTagContext(ctx context.Context, tagName string, dataType byte) (interface{}, error) {
+	plc, err := p.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer p.Put(plc)
+
+	return plc.ReadTag(tagName, dataType)
+}
+
+// WriteTag writes a tag using a pooled session
+func (p *PLCPool) WriteTag(tagName string, dataType byte, value interface{}) error {
+	return p.WriteTagContext(context.Background(), tagName, dataType, value)
+}
+
+// WriteTagContext writes a tag using a pooled session, giving up if no
+// session becomes available before ctx is done
+func (p *PLCPool) WriteTagContext(ctx context.Context, tagName string, dataType byte, value interface{}) error {
+	plc, err := p.Get(ctx)
+	if err != nil {
+		return err
+	}
+	defer p.Put(plc)
+
+	return plc.WriteTag(tagName, dataType, value)
+}
+
+// Stats returns the current number of open, idle and checked out sessions
+func (p *PLCPool) Stats() PoolStats {
+	open := len(p.slots)
+	idle := len(p.idle)
+	return PoolStats{
+		Open:  open,
+		Idle:  idle,
+		InUse: open - idle,
+	}
+}
+
+// Close closes every idle session and stops health checks. Sessions that
+// are checked out are closed when they are returned.
+func (p *PLCPool) Close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	close(p.stop)
+	p.mu.Unlock()
+
+	p.wg.Wait()
+
+	for {
+		select {
+		case pc := <-p.idle:
+			p.discard(pc.plc)
+		default:
+			return nil
+		}
+	}
+}
+
+// isClosed reports whether Close has been called
+func (p *PLCPool) isClosed() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.closed
+}
+
+// healthLoop periodically checks idle sessions until the pool is closed
+func (p *PLCPool) healthLoop() {
+	defer p.wg.Done()
+	ticker := time.NewTicker(p.config.HealthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			p.checkIdle()
+		}
+	}
+}
+
+// checkIdle evicts sessions that have been idle too long and closes those
+// whose connection no longer answers
+func (p *PLCPool) checkIdle() {
+	// Take the idle sessions out so nobody checks them out mid-check
+	var idle []*pooledConn
+drain:
+	for {
+		select {
+		case pc := <-p.idle:
+			idle = append(idle, pc)
+		default:
+			break drain
+		}
+	}
+
+	now := time.Now()
+	for _, pc := range idle {
+		expired := p.config.IdleTimeout > 0 && now.Sub(pc.lastUsed) > p.config.IdleTimeout
+		if expired && len(p.slots) > p.config.MinConns {
+			p.discard(pc.plc)
+			continue
+		}
+
+		// An identity request proves the controller is still answering
+		if err := pc.plc.client.Ping(); err != nil {
+			p.discard(pc.plc)
+			continue
+		}
+
+		p.idle <- pc
+	}
+}
@@ -0,0 +1,194 @@
+package cpppo
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// setupPoolServer creates an EIP server that accepts many sessions and
+// records the highest number of connections open at once
+func setupPoolServer(t *testing.T) (string, *int32, func()) {
+	var open, peak int32
+	addr, cleanup := setupMockListener(t, func(n int, conn net.Conn) {
+		current := atomic.AddInt32(&open, 1)
+		defer atomic.AddInt32(&open, -1)
+		for {
+			old := atomic.LoadInt32(&peak)
+			if current <= old || atomic.CompareAndSwapInt32(&peak, old, current) {
+				break
+			}
+		}
+
+		serveEIP(conn, func(request []byte) []byte {
+			time.Sleep(5 * time.Millisecond)
+			return []byte{CIPServiceReadTag | 0x80, 0x00, CIPDataTypeDINT, 0x01, 42, 0, 0, 0}
+		})
+	})
+	return addr, &peak, cleanup
+}
+
+func TestPLCPoolLimitsConnections(t *testing.T) {
+	addr, peak, cleanup := setupPoolServer(t)
+	defer cleanup()
+
+	pool, err := NewPLCPool(addr, 1*time.Second, PoolConfig{MaxConns: 2})
+	if err != nil {
+		t.Fatalf("NewPLCPool returned error: %v", err)
+	}
+	defer pool.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value, err := pool.ReadTag("Counter", CIPDataTypeDINT)
+			if err != nil {
+				t.Errorf("ReadTag returned error: %v", err)
+				return
+			}
+			if value != int32(42) {
+				t.Errorf("Expected value 42, got %v", value)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := atomic.LoadInt32(peak); got > 2 {
+		t.Errorf("Expected at most 2 concurrent connections, got %d", got)
+	}
+
+	stats := pool.Stats()
+	if stats.Open > 2 || stats.InUse != 0 || stats.Idle != stats.Open {
+		t.Errorf("Unexpected pool stats after all reads: %+v", stats)
+	}
+}
+
+func TestPLCPoolCheckoutTimeout(t *testing.T) {
+	addr, _, cleanup := setupPoolServer(t)
+	defer cleanup()
+
+	pool, err := NewPLCPool(addr, 1*time.Second, PoolConfig{MaxConns: 1})
+	if err != nil {
+		t.Fatalf("NewPLCPool returned error: %v", err)
+	}
+	defer pool.Close()
+
+	plc, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("Get returned error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := pool.Get(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected checkout to time out, got %v", err)
+	}
+
+	// Returning the session makes it available again
+	pool.Put(plc)
+	plc, err = pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("Get after Put returned error: %v", err)
+	}
+	pool.Put(plc)
+}
+
+func TestPLCPoolIdleEviction(t *testing.T) {
+	addr, _, cleanup := setupPoolServer(t)
+	defer cleanup()
+
+	pool, err := NewPLCPool(addr, 1*time.Second, PoolConfig{
+		MaxConns:            3,
+		MinConns:            1,
+		IdleTimeout:         20 * time.Millisecond,
+		HealthCheckInterval: 10 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("NewPLCPool returned error: %v", err)
+	}
+	defer pool.Close()
+
+	// Open three sessions at once, then return them
+	var sessions []*PLCClient
+	for i := 0; i < 3; i++ {
+		plc, err := pool.Get(context.Background())
+		if err != nil {
+			t.Fatalf("Get returned error: %v", err)
+		}
+		sessions = append(sessions, plc)
+	}
+	for _, plc := range sessions {
+		pool.Put(plc)
+	}
+
+	deadline := time.Now().Add(1 * time.Second)
+	for pool.Stats().Open > 1 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if stats := pool.Stats(); stats.Open != 1 {
+		t.Errorf("Expected idle sessions to be evicted down to 1, got %+v", stats)
+	}
+}
+
+func TestPLCPoolClosed(t *testing.T) {
+	addr, _, cleanup := setupPoolServer(t)
+	defer cleanup()
+
+	pool, err := NewPLCPool(addr, 1*time.Second, PoolConfig{MaxConns: 1, MinConns: 1})
+	if err != nil {
+		t.Fatalf("NewPLCPool returned error: %v", err)
+	}
+	pool.Close()
+
+	if _, err := pool.Get(context.Background()); !errors.Is(err, ErrPoolClosed) {
+		t.Errorf("Expected ErrPoolClosed, got %v", err)
+	}
+	if stats := pool.Stats(); stats.Open != 0 {
+		t.Errorf("Expected no open sessions after Close, got %+v", stats)
+	}
+}
+
+func TestPLCPoolPutDuringClose(t *testing.T) {
+	addr, _, cleanup := setupPoolServer(t)
+	defer cleanup()
+
+	for i := 0; i < 20; i++ {
+		pool, err := NewPLCPool(addr, 1*time.Second, PoolConfig{MaxConns: 4})
+		if err != nil {
+			t.Fatalf("NewPLCPool returned error: %v", err)
+		}
+
+		var sessions []*PLCClient
+		for j := 0; j < 4; j++ {
+			plc, err := pool.Get(context.Background())
+			if err != nil {
+				t.Fatalf("Get returned error: %v", err)
+			}
+			sessions = append(sessions, plc)
+		}
+
+		// Sessions returned while the pool closes are either drained by
+		// Close or closed by Put, never left idle
+		var wg sync.WaitGroup
+		for _, plc := range sessions {
+			wg.Add(1)
+			go func(plc *PLCClient) {
+				defer wg.Done()
+				pool.Put(plc)
+			}(plc)
+		}
+		pool.Close()
+		wg.Wait()
+
+		if stats := pool.Stats(); stats.Open != 0 {
+			t.Fatalf("Expected no open sessions after Close, got %+v", stats)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package cpppo
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Symbol Object attributes requested while browsing
+const (
+	SymbolAttributeName = 0x01
+	SymbolAttributeType = 0x02
+)
+
+// SymbolInfo describes a tag reported by the controller's Symbol Object
+type SymbolInfo struct {
+	Instance uint32 // Symbol Object instance ID
+	Name     string // Tag name
+	Type     uint16 // Symbol type word (data type and array/structure flags)
+}
+
+// BuildCIPBrowseRequest creates a Get Instance Attribute List request for the
+// Symbol Object, starting at the given instance ID
+func BuildCIPBrowseRequest(startInstance uint32) []byte {
+	path := BuildCIPSymbolInstancePath(startInstance)
+
+	request := make([]byte, 2+len(path), 2+len(path)+6)
+	request[0] = CIPServiceGetInstanceAttributeList
+	request[1] = byte(len(path) / 2)
+	copy(request[2:], path)
+
+	// Attribute count followed by the attribute IDs
+	request = binary.LittleEndian.AppendUint16(request, 2)
+	request = binary.LittleEndian.AppendUint16(request, SymbolAttributeName)
+	request = binary.LittleEndian.AppendUint16(request, SymbolAttributeType)
+
+	return request
+}
+
+// ParseCIPBrowseResponse parses a Get Instance Attribute List response for
+// the Symbol Object. The returned flag reports whether the controller has
+// more instances to send (partial transfer).
+func ParseCIPBrowseResponse(response []byte) ([]SymbolInfo, bool, error) {
+	if len(response) < 2 {
+		return nil, false, errors.New("response too short")
+	}
+
+	if response[0]&0x80 == 0 {
+		return nil, false, errors.New("not a response")
+	}
+
+	// Partial transfer means the reply is valid but incomplete
+	more := response[1] == 0x06
+	if !more {
+		if err := CIPStatusToError(response[1]); err != nil {
+			return nil, false, err
+		}
+	}
+
+	data := response[2:]
+	var symbols []SymbolInfo
+
+	for len(data) > 0 {
+		if len(data) < 6 {
+			return nil, false, errors.New("symbol record truncated")
+		}
+
+		instance := binary.LittleEndian.Uint32(data[0:4])
+		nameLen := int(binary.LittleEndian.Uint16(data[4:6]))
+		if len(data) < 6+nameLen+2 {
+			return nil, false, fmt.Errorf("symbol record for instance %d truncated", instance)
+		}
+
+		symbols = append(symbols, SymbolInfo{
+			Instance: instance,
+			Name:     string(data[6 : 6+nameLen]),
+			Type:     binary.LittleEndian.Uint16(data[6+nameLen : 8+nameLen]),
+		})
+
+		data = data[8+nameLen:]
+	}
+
+	return symbols, more, nil
+}
+
+// symbolTable maps tag names to Symbol Object instance IDs
+type symbolTable struct {
+	mu        sync.RWMutex
+	instances map[string]uint32
+}
+
+// newSymbolTable creates an empty symbol table
+func newSymbolTable() *symbolTable {
+	return &symbolTable{
+		instances: make(map[string]uint32),
+	}
+}
+
+// symbolKey normalizes a tag name; Logix tag names are case-insensitive
+func symbolKey(tagName string) string {
+	return strings.ToUpper(tagName)
+}
+
+// lookup returns the instance ID for a tag, if it has been resolved
+func (s *symbolTable) lookup(tagName string) (uint32, bool) {
+	if s == nil {
+		return 0, false
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	instance, ok := s.instances[symbolKey(tagName)]
+	return instance, ok
+}
+
+// replace swaps the table contents for a freshly browsed symbol list
+func (s *symbolTable) replace(symbols []SymbolInfo) {
+	instances := make(map[string]uint32, len(symbols))
+	for _, symbol := range symbols {
+		instances[symbolKey(symbol.Name)] = symbol.Instance
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.instances = instances
+}
+
+// invalidate drops every resolved instance ID
+func (s *symbolTable) invalidate() {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.instances = make(map[string]uint32)
+}
+
+// len returns the number of resolved tags
+func (s *symbolTable) len() int {
+	if s == nil {
+		return 0
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.instances)
+}
+
+// isStaleSymbolError reports whether an error suggests that the instance ID
+// used for a request no longer refers to the expected tag
+func isStaleSymbolError(err error) bool {
+	var cipErr CIPError
+	if !errors.As(err, &cipErr) {
+		return false
+	}
+
+	switch cipErr.Code {
+	case 0x04, 0x05, 0x16: // Path segment error, destination unknown, object does not exist
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,196 @@
+package cpppo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// symbolRecord encodes one Get Instance Attribute List record
+func symbolRecord(instance uint32, name string, symbolType uint16) []byte {
+	record := binary.LittleEndian.AppendUint32(nil, instance)
+	record = binary.LittleEndian.AppendUint16(record, uint16(len(name)))
+	record = append(record, name...)
+	return binary.LittleEndian.AppendUint16(record, symbolType)
+}
+
+func TestBuildCIPBrowseRequest(t *testing.T) {
+	request := BuildCIPBrowseRequest(0)
+
+	expected := []byte{
+		CIPServiceGetInstanceAttributeList, 2, // Service and path size
+		CIPSegmentClass8, CIPClassSymbol, CIPSegmentInstance8, 0, // Symbol class, instance 0
+		2, 0, // Attribute count
+		SymbolAttributeName, 0,
+		SymbolAttributeType, 0,
+	}
+	if !bytes.Equal(request, expected) {
+		t.Errorf("Expected browse request %v, got %v", expected, request)
+	}
+}
+
+func TestParseCIPBrowseResponse(t *testing.T) {
+	response := []byte{CIPServiceGetInstanceAttributeList | 0x80, 0x06}
+	response = append(response, symbolRecord(3, "Counter", CIPDataTypeDINT)...)
+	response = append(response, symbolRecord(7, "SetPoint", CIPDataTypeREAL)...)
+
+	symbols, more, err := ParseCIPBrowseResponse(response)
+	if err != nil {
+		t.Fatalf("Failed to parse browse response: %v", err)
+	}
+	if !more {
+		t.Error("Expected partial transfer to report more symbols")
+	}
+	if len(symbols) != 2 {
+		t.Fatalf("Expected 2 symbols, got %d", len(symbols))
+	}
+	if symbols[1].Instance != 7 || symbols[1].Name != "SetPoint" || symbols[1].Type != CIPDataTypeREAL {
+		t.Errorf("Unexpected second symbol: %+v", symbols[1])
+	}
+
+	// Truncated record
+	_, _, err = ParseCIPBrowseResponse(response[:len(response)-3])
+	if err == nil {
+		t.Error("Expected error for truncated record, got nil")
+	}
+}
+
+func TestPLCClientSymbolInstanceAddressing(t *testing.T) {
+	instancePath := BuildCIPReadRequestPath(BuildCIPSymbolInstancePath(3), 1)
+	symbolicPath := BuildCIPReadRequest("Counter", 1)
+	stale := false
+	var requests [][]byte
+
+	addr, cleanup := setupEIPServer(t, func(request []byte) []byte {
+		requests = append(requests, request)
+
+		switch {
+		case request[0] == CIPServiceGetInstanceAttributeList:
+			reply := []byte{CIPServiceGetInstanceAttributeList | 0x80, 0x00}
+			return append(reply, symbolRecord(3, "Counter", CIPDataTypeDINT)...)
+		case bytes.Equal(request, instancePath) && stale:
+			return []byte{CIPServiceReadTag | 0x80, 0x05} // Path destination unknown
+		default:
+			return []byte{CIPServiceReadTag | 0x80, 0x00, CIPDataTypeDINT, 0x01, 42, 0, 0, 0}
+		}
+	})
+	defer cleanup()
+
+	plc, err := NewPLCClient(addr, 1*time.Second)
+	if err != nil {
+		t.Fatalf("NewPLCClient returned error: %v", err)
+	}
+	defer plc.Close()
+
+	symbols, err := plc.BrowseTags()
+	if err != nil {
+		t.Fatalf("BrowseTags returned error: %v", err)
+	}
+	if len(symbols) != 1 || plc.ResolvedSymbols() != 1 {
+		t.Fatalf("Expected 1 resolved symbol, got %d", plc.ResolvedSymbols())
+	}
+
+	// Resolved tags use the instance path, case-insensitively
+	if _, err := plc.ReadTag("COUNTER", CIPDataTypeDINT); err != nil {
+		t.Fatalf("ReadTag returned error: %v", err)
+	}
+	if !bytes.Equal(requests[len(requests)-1], instancePath) {
+		t.Errorf("Expected instance path request %v, got %v", instancePath, requests[len(requests)-1])
+	}
+
+	// A stale instance falls back to the symbolic name and drops the table
+	stale = true
+	value, err := plc.ReadTag("Counter", CIPDataTypeDINT)
+	if err != nil {
+		t.Fatalf("ReadTag after invalidation returned error: %v", err)
+	}
+	if value != int32(42) {
+		t.Errorf("Expected value 42, got %v", value)
+	}
+	if !bytes.Equal(requests[len(requests)-1], symbolicPath) {
+		t.Errorf("Expected symbolic request %v, got %v", symbolicPath, requests[len(requests)-1])
+	}
+	if plc.ResolvedSymbols() != 0 {
+		t.Errorf("Expected symbol table to be invalidated, got %d entries", plc.ResolvedSymbols())
+	}
+}
+
+// TestPLCClientReadRetryAfterReconnect tests that a read retried on a fresh
+// connection addresses tags by name, since the instance IDs browsed before
+// may name other tags afterwards
+func TestPLCClientReadRetryAfterReconnect(t *testing.T) {
+	var mu sync.Mutex
+	drop := false
+
+	addr, cleanup := setupMockListener(t, func(n int, conn net.Conn) {
+		// Counter is instance 3 on the first connection and moves up by one
+		// on every reconnect; its old instance then holds another tag
+		instance := uint32(3 + n)
+		counter := BuildCIPReadRequestPath(BuildCIPSymbolInstancePath(instance), 1)
+		byName := BuildCIPReadRequest("Counter", 1)
+
+		var answer func(request []byte) []byte
+		answer = func(request []byte) []byte {
+			switch {
+			case request[0] == CIPServiceMultipleService:
+				return answerMultiple(request, answer)
+			case request[0] == CIPServiceGetInstanceAttributeList:
+				reply := []byte{CIPServiceGetInstanceAttributeList | 0x80, 0x00}
+				return append(reply, symbolRecord(instance, "Counter", CIPDataTypeDINT)...)
+			case bytes.Equal(request, counter) || bytes.Equal(request, byName):
+				return []byte{CIPServiceReadTag | 0x80, 0x00, CIPDataTypeDINT, 0x01, 42, 0, 0, 0}
+			default:
+				return []byte{CIPServiceReadTag | 0x80, 0x00, CIPDataTypeDINT, 0x01, 7, 0, 0, 0}
+			}
+		}
+		serveEIP(conn, func(request []byte) []byte {
+			mu.Lock()
+			defer mu.Unlock()
+			if drop {
+				drop = false
+				conn.Close()
+				return nil
+			}
+			return answer(request)
+		})
+	})
+	defer cleanup()
+
+	plc, err := NewPLCClient(addr, 1*time.Second)
+	if err != nil {
+		t.Fatalf("NewPLCClient returned error: %v", err)
+	}
+	defer plc.Close()
+	plc.SetReconnectPolicy(ReconnectPolicy{MaxAttempts: 3, InitialDelay: 10 * time.Millisecond, MaxDelay: 50 * time.Millisecond, RetryReads: true})
+
+	// dropNext breaks the connection on the next request
+	dropNext := func() {
+		mu.Lock()
+		defer mu.Unlock()
+		drop = true
+	}
+
+	if _, err := plc.BrowseTags(); err != nil {
+		t.Fatalf("BrowseTags returned error: %v", err)
+	}
+	dropNext()
+	value, err := plc.ReadTag("Counter", CIPDataTypeDINT)
+	if err != nil || value != int32(42) {
+		t.Errorf("Expected Counter = 42 after the reconnect, got %v (%v)", value, err)
+	}
+
+	if _, err := plc.BrowseTags(); err != nil {
+		t.Fatalf("BrowseTags returned error: %v", err)
+	}
+	dropNext()
+	results, err := plc.ReadTags([]TagRead{{Name: "Counter", DataType: CIPDataTypeDINT}})
+	if err != nil || len(results) != 1 || results[0].Value != int32(42) {
+		t.Errorf("Expected Counter = 42 in the batch after the reconnect, got %+v (%v)", results, err)
+	}
+	if plc.ResolvedSymbols() != 0 {
+		t.Errorf("Expected the reconnect to drop the instance table, got %d entries", plc.ResolvedSymbols())
+	}
+}
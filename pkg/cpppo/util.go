@@ -1,10 +1,11 @@
 package cpppo
 
 import (
-	"encoding/binary"
+	"context"
 	"errors"
 	"fmt"
-	"math"
+	"io"
+	"math/rand"
 	"net"
 	"strings"
 	"time"
@@ -14,6 +15,19 @@ import (
 
 // ExponentialBackoff implements an exponential backoff retry mechanism
 func ExponentialBackoff(operation func() error, initialDelay, maxDelay time.Duration, maxRetries int) error {
+	return ExponentialBackoffWithJitter(operation, initialDelay, maxDelay, maxRetries, 0)
+}
+
+// ExponentialBackoffWithJitter implements an exponential backoff retry
+// mechanism where each delay is randomized by up to the given fraction (0..1),
+// so that many clients recovering at once do not redial in lockstep
+func ExponentialBackoffWithJitter(operation func() error, initialDelay, maxDelay time.Duration, maxRetries int, jitter float64) error {
+	return backoff(context.Background(), operation, initialDelay, maxDelay, maxRetries, jitter)
+}
+
+// backoff is ExponentialBackoffWithJitter that gives up, returning the
+// context's error, as soon as ctx is done
+func backoff(ctx context.Context, operation func() error, initialDelay, maxDelay time.Duration, maxRetries int, jitter float64) error {
 	var err error
 	delay := initialDelay
 
@@ -23,23 +37,60 @@ func ExponentialBackoff(operation func() error, initialDelay, maxDelay time.Dura
 			return nil
 		}
 
-		// Check if this is a network error that we should retry
-		if netErr, ok := err.(net.Error); ok && (netErr.Timeout() || isConnectionError(netErr)) {
-			time.Sleep(delay)
-			delay *= 2
-			if delay > maxDelay {
-				delay = maxDelay
-			}
-			continue
+		// Not a temporary network error, so don't retry
+		if !isRetryableError(err) {
+			return err
 		}
 
-		// Not a temporary network error, so don't retry
-		return err
+		// No point in waiting after the last attempt
+		if i == maxRetries-1 {
+			break
+		}
+
+		timer := time.NewTimer(jitterDelay(delay, jitter))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		delay *= 2
+		if delay > maxDelay {
+			delay = maxDelay
+		}
 	}
 
 	return fmt.Errorf("operation failed after %d retries: %w", maxRetries, err)
 }
 
+// jitterDelay randomizes a delay by up to the given fraction in either direction
+func jitterDelay(delay time.Duration, jitter float64) time.Duration {
+	if jitter <= 0 || delay <= 0 {
+		return delay
+	}
+	if jitter > 1 {
+		jitter = 1
+	}
+
+	spread := float64(delay) * jitter
+	return delay + time.Duration((rand.Float64()*2-1)*spread)
+}
+
+// isRetryableError reports whether an error is a network failure that may
+// go away by retrying
+func isRetryableError(err error) bool {
+	if errors.Is(err, ErrConnectionLost) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return netErr.Timeout() || isConnectionError(netErr)
+	}
+
+	return false
+}
+
 // Check if the error is a connection error that should be retried
 func isConnectionError(err error) bool {
 	return strings.Contains(err.Error(), "connection") ||
@@ -66,62 +117,60 @@ func ParseIPAddress(address string, defaultPort int) string {
 
 // EncodeBool encodes a boolean value for CIP
 func EncodeBool(value bool) []byte {
-	if value {
-		return []byte{1}
-	}
-	return []byte{0}
+	data, _ := EncodeValue(CIPDataTypeBOOL, value)
+	return data
 }
 
 // EncodeInt16 encodes an int16 value for CIP
 func EncodeInt16(value int16) []byte {
-	data := make([]byte, 2)
-	binary.LittleEndian.PutUint16(data, uint16(value))
+	data, _ := EncodeValue(CIPDataTypeINT, value)
 	return data
 }
 
 // EncodeInt32 encodes an int32 value for CIP
 func EncodeInt32(value int32) []byte {
-	data := make([]byte, 4)
-	binary.LittleEndian.PutUint32(data, uint32(value))
+	data, _ := EncodeValue(CIPDataTypeDINT, value)
 	return data
 }
 
 // EncodeFloat32 encodes a float32 value for CIP
 func EncodeFloat32(value float32) []byte {
-	data := make([]byte, 4)
-	binary.LittleEndian.PutUint32(data, math.Float32bits(value))
+	data, _ := EncodeValue(CIPDataTypeREAL, value)
 	return data
 }
 
 // DecodeBool decodes a CIP boolean value
 func DecodeBool(data []byte) (bool, error) {
-	if len(data) < 1 {
-		return false, errors.New("not enough data to decode bool")
+	value, err := DecodeValue(CIPDataTypeBOOL, data)
+	if err != nil {
+		return false, err
 	}
-	return data[0] != 0, nil
+	return value.(bool), nil
 }
 
 // DecodeInt16 decodes a CIP int16 value
 func DecodeInt16(data []byte) (int16, error) {
-	if len(data) < 2 {
-		return 0, errors.New("not enough data to decode int16")
+	value, err := DecodeValue(CIPDataTypeINT, data)
+	if err != nil {
+		return 0, err
 	}
-	return int16(binary.LittleEndian.Uint16(data)), nil
+	return value.(int16), nil
 }
 
 // DecodeInt32 decodes a CIP int32 value
 func DecodeInt32(data []byte) (int32, error) {
-	if len(data) < 4 {
-		return 0, errors.New("not enough data to decode int32")
+	value, err := DecodeValue(CIPDataTypeDINT, data)
+	if err != nil {
+		return 0, err
 	}
-	return int32(binary.LittleEndian.Uint32(data)), nil
+	return value.(int32), nil
 }
 
 // DecodeFloat32 decodes a CIP float32 value
 func DecodeFloat32(data []byte) (float32, error) {
-	if len(data) < 4 {
-		return 0, errors.New("not enough data to decode float32")
+	value, err := DecodeValue(CIPDataTypeREAL, data)
+	if err != nil {
+		return 0, err
 	}
-	bits := binary.LittleEndian.Uint32(data)
-	return math.Float32frombits(bits), nil
+	return value.(float32), nil
 }
@@ -0,0 +1,389 @@
+package fanuc
+
+import (
+	"fmt"
+
+	"github.com/carun/cpppo-go/pkg/cpppo"
+)
+
+// Batches are split so that both the Multiple Service Packet and its reply
+// fit in cpppo.MaxUnconnectedMessageSize. The reply to a packet starts with
+// the reply service, status and service count; each service in either
+// direction adds a 2 byte offset, and each reply its own 4 byte header.
+const (
+	batchReplyOverhead  = 6
+	batchOffsetSize     = 2
+	serviceReplyHeader  = 4
+	tagReplyTypeSize    = 2 // Data type in front of a read tag's value
+	maxBatchMessageSize = cpppo.MaxUnconnectedMessageSize
+)
+
+// batchRequestOverhead is the size of an empty Multiple Service Packet
+var batchRequestOverhead = len(cpppo.BuildCIPMultipleServiceRequest(nil))
+
+// RegisterRef names one register
+type RegisterRef struct {
+	Type  RegisterType
+	Index int
+}
+
+// String returns the register's tag name, such as "R[1]"
+func (r RegisterRef) String() string {
+	return buildRegisterTag(r.Type, r.Index)
+}
+
+// RegisterSet is a list of registers of any types, read together
+type RegisterSet []RegisterRef
+
+// RegisterResult is the outcome of reading one register of a set
+type RegisterResult struct {
+	Ref   RegisterRef
+	Value interface{}
+	Err   error
+}
+
+// registerRange returns the set of count registers starting at start
+func registerRange(regType RegisterType, start, count int) (RegisterSet, error) {
+	if start < 1 {
+		return nil, fmt.Errorf("invalid start index %d", start)
+	}
+	if count < 0 {
+		return nil, fmt.Errorf("invalid register count %d", count)
+	}
+
+	set := make(RegisterSet, count)
+	for i := range set {
+		set[i] = RegisterRef{Type: regType, Index: start + i}
+	}
+	return set, nil
+}
+
+// ReadRegisters reads count registers of one type starting at start. Values
+// are the same as ReadRegister returns; a register that cannot be read has
+// its own error in the result. The error is set only if the read as a whole
+// failed.
+func (f *FanucClient) ReadRegisters(regType RegisterType, start, count int) ([]RegisterResult, error) {
+	set, err := registerRange(regType, start, count)
+	if err != nil {
+		return nil, err
+	}
+	return f.ReadRegisterSet(set)
+}
+
+// WriteRegisters writes values to consecutive registers of one type
+// starting at start. Every value is checked as the single register writes
+// check it before anything is sent; if one is invalid nothing is written. A register that cannot be
+// written has its own error in the returned slice.
+func (f *FanucClient) WriteRegisters(regType RegisterType, start int, values []interface{}) ([]error, error) {
+	set, err := registerRange(regType, start, len(values))
+	if err != nil {
+		return nil, err
+	}
+
+	errs := make([]error, len(set))
+	var writes []cpppo.TagWrite
+	var batched []int
+	for i, ref := range set {
+		if ref.Type == RegisterTypePR {
+			position, ok := values[i].(*Position)
+			if !ok {
+				return nil, fmt.Errorf("%s: value must be a Position for PR registers", ref)
+			}
+			if _, err := position.validate(); err != nil {
+				return nil, fmt.Errorf("%s: %w", ref, err)
+			}
+			continue
+		}
+		if ref.Type == RegisterTypeUR {
+			frame, ok := values[i].(*Frame)
+			if !ok {
+				return nil, fmt.Errorf("%s: value must be a Frame for UR registers", ref)
+			}
+			if _, err := f.frameWrites(userFrames, 1, ref.Index, frame); err != nil {
+				return nil, fmt.Errorf("%s: %w", ref, err)
+			}
+			continue
+		}
+
+		if ref.Type == RegisterTypeVR {
+			return nil, fmt.Errorf("%s: %w", ref, ErrVisionRegisterReadOnly)
+		}
+
+		dataType, value, err := registerWriteValue(ref.Type, values[i])
+		if err == nil {
+			_, err = cpppo.EncodeValue(dataType, value)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", ref, err)
+		}
+
+		if f.batchable(ref.Type) {
+			writes = append(writes, cpppo.TagWrite{Name: ref.String(), DataType: dataType, Value: value})
+			batched = append(batched, i)
+		}
+	}
+
+	// Registers that cannot be batched are written one by one
+	for i, ref := range set {
+		if !f.batchable(ref.Type) {
+			errs[i] = f.WriteRegister(ref.Type, ref.Index, values[i])
+		}
+	}
+
+	batchErrs, err := f.writeBatches(writes)
+	if err != nil {
+		return nil, err
+	}
+	for j, err := range batchErrs {
+		errs[batched[j]] = err
+	}
+
+	return errs, nil
+}
+
+// ReadRegisterSet reads every register of the set, batching as many as the
+// client allows into each request. Results are in the order of the set.
+func (f *FanucClient) ReadRegisterSet(set RegisterSet) ([]RegisterResult, error) {
+	results := make([]RegisterResult, len(set))
+	var reads []cpppo.TagRead
+	var batched []int
+	var attributes []cpppo.AttributeRef
+	var attributeBatched []int
+	for i, ref := range set {
+		results[i].Ref = ref
+		if class := f.attributeBatchClass(ref.Type); class != 0 {
+			attribute, err := registerAttributeID(ref.Index)
+			if err != nil {
+				results[i].Err = err
+				continue
+			}
+			attributes = append(attributes, cpppo.AttributeRef{Class: class, Instance: registerInstance, Attribute: attribute})
+			attributeBatched = append(attributeBatched, i)
+			continue
+		}
+		if !f.batchable(ref.Type) {
+			results[i].Value, results[i].Err = f.ReadRegister(ref.Type, ref.Index)
+			continue
+		}
+
+		reads = append(reads, cpppo.TagRead{Name: ref.String(), DataType: getRegisterDataType(ref.Type)})
+		batched = append(batched, i)
+	}
+
+	tagResults, err := f.readBatches(reads)
+	if err != nil {
+		return nil, err
+	}
+	for j, result := range tagResults {
+		results[batched[j]].Value, results[batched[j]].Err = result.Value, result.Err
+	}
+
+	// R registers holding integers answer the REAL read with their value
+	// as a DINT
+	for _, i := range batched {
+		if results[i].Ref.Type == RegisterTypeR && results[i].Err != nil {
+			results[i].Value, results[i].Err = storedNumeric(results[i].Err)
+		}
+	}
+
+	if err := f.readAttributeBatches(attributes, attributeBatched, results); err != nil {
+		return nil, err
+	}
+
+	// String registers are checked on read as on write
+	for i := range results {
+		if results[i].Ref.Type == RegisterTypeSR {
+			results[i].Value, results[i].Err = storedString(results[i].Value, results[i].Err)
+		}
+	}
+
+	return results, nil
+}
+
+// readBatches reads tags in as few Multiple Service Packets as their size
+// allows. Results are in the order of reads.
+func (f *FanucClient) readBatches(reads []cpppo.TagRead) ([]cpppo.TagResult, error) {
+	ends := sizedBatches(len(reads), func(i int) int {
+		return len(cpppo.BuildCIPReadRequest(reads[i].Name, 1))
+	}, func(i int) int {
+		return serviceReplyHeader + tagReplyTypeSize + maxValueSize(reads[i].DataType)
+	})
+
+	results := make([]cpppo.TagResult, 0, len(reads))
+	start := 0
+	for _, end := range ends {
+		batch, err := f.readComponents(reads[start:end])
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, batch...)
+		start = end
+	}
+	return results, nil
+}
+
+// writeBatches writes tags in as few Multiple Service Packets as their size
+// allows. Errors are in the order of writes. Every value must encode as its
+// data type.
+func (f *FanucClient) writeBatches(writes []cpppo.TagWrite) ([]error, error) {
+	ends := sizedBatches(len(writes), func(i int) int {
+		data, _ := cpppo.EncodeValue(writes[i].DataType, writes[i].Value)
+		return len(cpppo.BuildCIPWriteRequest(writes[i].Name, writes[i].DataType, data))
+	}, func(int) int {
+		return serviceReplyHeader
+	})
+
+	errs := make([]error, 0, len(writes))
+	start := 0
+	for _, end := range ends {
+		batch, err := f.writeComponents(writes[start:end])
+		if err != nil {
+			return nil, err
+		}
+		errs = append(errs, batch...)
+		start = end
+	}
+	return errs, nil
+}
+
+// readAttributeBatches reads register attributes in as few Multiple Service
+// Packets as their size allows and stores each decoded value at the index
+// given by batched
+func (f *FanucClient) readAttributeBatches(refs []cpppo.AttributeRef, batched []int, results []RegisterResult) error {
+	if len(refs) == 0 {
+		return nil
+	}
+	client := f.PLCClient.(AttributeBatchClient)
+
+	dataType := func(i int) byte {
+		return getRegisterDataType(results[batched[i]].Ref.Type)
+	}
+	ends := sizedBatches(len(refs), func(i int) int {
+		return len(cpppo.BuildCIPGetAttributeSingleRequest(refs[i].Class, refs[i].Instance, refs[i].Attribute))
+	}, func(i int) int {
+		return serviceReplyHeader + maxValueSize(dataType(i))
+	})
+
+	start := 0
+	for _, end := range ends {
+		batch, err := client.GetAttributesSingle(refs[start:end])
+		if err == nil && len(batch) != end-start {
+			err = fmt.Errorf("expected %d results, got %d", end-start, len(batch))
+		}
+		if err != nil {
+			return err
+		}
+		for j, result := range batch {
+			i := start + j
+			if result.Err != nil {
+				results[batched[i]].Err = result.Err
+				continue
+			}
+			results[batched[i]].Value, results[batched[i]].Err = cpppo.DecodeValue(dataType(i), result.Data)
+		}
+		start = end
+	}
+	return nil
+}
+
+// sizedBatches splits n services into consecutive batches whose request and
+// reply both fit in maxBatchMessageSize, given the encoded size of each
+// request and the largest size of its reply. It returns the end index of
+// each batch. A service too large on its own is sent alone, so the
+// controller reports the error for it.
+func sizedBatches(n int, requestSize, replySize func(i int) int) []int {
+	var ends []int
+	start := 0
+	request, reply := batchRequestOverhead, batchReplyOverhead
+	for i := 0; i < n; i++ {
+		req := batchOffsetSize + requestSize(i)
+		rep := batchOffsetSize + replySize(i)
+		if i > start && (request+req > maxBatchMessageSize || reply+rep > maxBatchMessageSize) {
+			ends = append(ends, i)
+			start = i
+			request, reply = batchRequestOverhead, batchReplyOverhead
+		}
+		request += req
+		reply += rep
+	}
+	if n > 0 {
+		ends = append(ends, n)
+	}
+	return ends
+}
+
+// maxValueSize returns the largest encoded size of a register value of
+// dataType
+func maxValueSize(dataType byte) int {
+	switch dataType {
+	case cpppo.CIPDataTypeBOOL, cpppo.CIPDataTypeSINT:
+		return 1
+	case cpppo.CIPDataTypeINT:
+		return 2
+	case cpppo.CIPDataTypeSTRING:
+		return 2 + MaxStringRegisterLength
+	default:
+		return 4
+	}
+}
+
+// attributeBatchClass returns the CIP class through which registers of
+// regType are read in batches of attribute reads, or 0 if they are not.
+// R and SR registers are batched this way in AccessAttribute mode when the
+// client can read several attributes at once.
+func (f *FanucClient) attributeBatchClass(regType RegisterType) uint16 {
+	if _, ok := f.PLCClient.(AttributeBatchClient); !ok || f.Access != AccessAttribute {
+		return 0
+	}
+	if regType == RegisterTypeR {
+		return f.numericClass(NumericReal)
+	}
+	return f.registerClass(regType)
+}
+
+// batchable reports whether registers of regType can be transferred as tags
+// in a batch. Position registers, frames, vision registers and registers
+// addressed through CIP objects go through ReadRegister and WriteRegister
+// instead.
+func (f *FanucClient) batchable(regType RegisterType) bool {
+	if _, ok := f.PLCClient.(BatchClient); !ok {
+		return false
+	}
+
+	switch {
+	case regType == RegisterTypePR, regType == RegisterTypeUR, regType == RegisterTypeVR:
+		return false
+	case regType == RegisterTypeR:
+		return f.Access != AccessAttribute || f.numericClass(NumericReal) == 0
+	default:
+		return f.registerClass(regType) == 0
+	}
+}
+
+// registerWriteValue returns the data type and value to write to a register
+// of regType, checking and converting it the same way WriteRegister does
+func registerWriteValue(regType RegisterType, value interface{}) (byte, interface{}, error) {
+	if regType == RegisterTypeR {
+		v, err := toNumericValue(value)
+		if err != nil {
+			return 0, nil, err
+		}
+		return v.dataType(), v.Value(), nil
+	}
+
+	if regType == RegisterTypeSR {
+		if err := checkStringRegister(value); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	dataType := getRegisterDataType(regType)
+	if dataType == cpppo.CIPDataTypeDINT {
+		v, err := toInt32(value)
+		if err != nil {
+			return 0, nil, err
+		}
+		return dataType, v, nil
+	}
+	return dataType, value, nil
+}
@@ -0,0 +1,281 @@
+package fanuc
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/carun/cpppo-go/pkg/cpppo"
+)
+
+// typedBatchClient adds batched reads and writes to typedTagClient
+type typedBatchClient struct {
+	*typedTagClient
+	batches []int
+	largest int // Largest Multiple Service Packet sent
+}
+
+// record counts a batch and the size of its Multiple Service Packet
+func (c *typedBatchClient) record(requests [][]byte) {
+	c.batches = append(c.batches, len(requests))
+	c.largest = max(c.largest, len(cpppo.BuildCIPMultipleServiceRequest(requests)))
+}
+
+func (c *typedBatchClient) ReadTags(reads []cpppo.TagRead) ([]cpppo.TagResult, error) {
+	requests := make([][]byte, len(reads))
+	for i, read := range reads {
+		requests[i] = cpppo.BuildCIPReadRequest(read.Name, 1)
+	}
+	c.record(requests)
+	results := make([]cpppo.TagResult, len(reads))
+	for i, read := range reads {
+		if _, ok := c.tags[read.Name]; !ok {
+			results[i].Err = cpppo.CIPError{Code: 0x05, ExtendedMsg: "Path destination unknown"}
+			continue
+		}
+		results[i].Value, results[i].Err = c.ReadTag(read.Name, read.DataType)
+	}
+	return results, nil
+}
+
+func (c *typedBatchClient) WriteTags(writes []cpppo.TagWrite) ([]error, error) {
+	requests := make([][]byte, len(writes))
+	for i, write := range writes {
+		data, _ := cpppo.EncodeValue(write.DataType, write.Value)
+		requests[i] = cpppo.BuildCIPWriteRequest(write.Name, write.DataType, data)
+	}
+	c.record(requests)
+	errs := make([]error, len(writes))
+	for i, write := range writes {
+		errs[i] = c.WriteTag(write.Name, write.DataType, write.Value)
+	}
+	return errs, nil
+}
+
+// TestReadRegisters tests range reads in chunked batches
+func TestReadRegisters(t *testing.T) {
+	mock := &typedBatchClient{typedTagClient: &typedTagClient{tags: map[string]typedTag{}}}
+	client := &FanucClient{PLCClient: mock}
+
+	for i := 1; i <= 100; i++ {
+		mock.tags[fmt.Sprintf("R[%d]", i)] = typedTag{cpppo.CIPDataTypeREAL, float32(i) / 2}
+	}
+	mock.tags["R[3]"] = typedTag{cpppo.CIPDataTypeDINT, int32(7)}
+	delete(mock.tags, "R[5]")
+
+	results, err := client.ReadRegisters(RegisterTypeR, 1, 100)
+	if err != nil {
+		t.Fatalf("ReadRegisters returned error: %v", err)
+	}
+	if len(results) != 100 {
+		t.Fatalf("Expected 100 results, got %d", len(results))
+	}
+
+	if results[0].Ref != (RegisterRef{RegisterTypeR, 1}) || results[0].Value != float32(0.5) {
+		t.Errorf("Expected R[1] = 0.5, got %+v", results[0])
+	}
+	if results[2].Value != int32(7) || results[2].Err != nil {
+		t.Errorf("Expected R[3] = int32(7), got %+v", results[2])
+	}
+	if results[4].Err == nil {
+		t.Errorf("Expected error for missing R[5], got %+v", results[4])
+	}
+	if results[99].Value != float32(50) {
+		t.Errorf("Expected R[100] = 50, got %+v", results[99])
+	}
+
+	// Reads of R[10] and above take 12 bytes, so 35 fill a packet; R[3] is
+	// decoded from its reply without a second read
+	expected := []int{36, 35, 29}
+	if fmt.Sprint(mock.batches) != fmt.Sprint(expected) {
+		t.Errorf("Expected batches %v, got %v", expected, mock.batches)
+	}
+	if mock.largest > cpppo.MaxUnconnectedMessageSize {
+		t.Errorf("Expected packets of at most %d bytes, got %d", cpppo.MaxUnconnectedMessageSize, mock.largest)
+	}
+
+	if _, err := client.ReadRegisters(RegisterTypeR, 0, 1); err == nil {
+		t.Error("Expected error for index 0")
+	}
+}
+
+// TestWriteRegisters tests range writes
+func TestWriteRegisters(t *testing.T) {
+	mock := &typedBatchClient{typedTagClient: &typedTagClient{tags: map[string]typedTag{}}}
+	client := &FanucClient{PLCClient: mock}
+
+	errs, err := client.WriteRegisters(RegisterTypeR, 10, []interface{}{3, float32(1.5), IntegerValue(-1)})
+	if err != nil {
+		t.Fatalf("WriteRegisters returned error: %v", err)
+	}
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("Write %d failed: %v", i, err)
+		}
+	}
+	if mock.tags["R[10]"] != (typedTag{cpppo.CIPDataTypeDINT, int32(3)}) ||
+		mock.tags["R[11]"] != (typedTag{cpppo.CIPDataTypeREAL, float32(1.5)}) ||
+		mock.tags["R[12]"] != (typedTag{cpppo.CIPDataTypeDINT, int32(-1)}) {
+		t.Errorf("Unexpected registers %v", mock.tags)
+	}
+
+	// One invalid value stops the whole write
+	mock.batches = nil
+	if _, err := client.WriteRegisters(RegisterTypeGO, 1, []interface{}{1, "two"}); err == nil {
+		t.Error("Expected error for invalid value")
+	}
+	if len(mock.batches) != 0 {
+		t.Errorf("Expected nothing to be sent, got %v", mock.batches)
+	}
+
+	// Positions and frames are checked as single writes check them, before
+	// the valid values ahead of them are written
+	mock.tags = map[string]typedTag{}
+	nan := float32(math.NaN())
+	invalid := map[RegisterType][]interface{}{
+		RegisterTypePR: {&Position{X: 1}, &Position{X: nan}},
+		RegisterTypeUR: {&Frame{X: 1}, &Frame{R: nan}},
+	}
+	client.SysVarWrites = SysVarAllowList{"$MNUFRAME[*"}
+	for regType, values := range invalid {
+		if _, err := client.WriteRegisters(regType, 1, values); err == nil {
+			t.Errorf("Expected error for an invalid value of type %d", regType)
+		}
+	}
+	client.SysVarWrites = nil
+	if _, err := client.WriteRegisters(RegisterTypeUR, 1, []interface{}{&Frame{X: 1}}); !errors.Is(err, ErrSysVarWriteDenied) {
+		t.Errorf("Expected ErrSysVarWriteDenied, got %v", err)
+	}
+	if len(mock.batches) != 0 || len(mock.tags) != 0 {
+		t.Errorf("Expected nothing to be sent, got %v", mock.tags)
+	}
+}
+
+// TestStringRegistersBatchSize tests that batches of long SR values are
+// split by size
+func TestStringRegistersBatchSize(t *testing.T) {
+	mock := &typedBatchClient{typedTagClient: &typedTagClient{tags: map[string]typedTag{}}}
+	client := &FanucClient{PLCClient: mock}
+
+	long := strings.Repeat("X", 200)
+	errs, err := client.WriteRegisters(RegisterTypeSR, 1, []interface{}{long, long, long, "short"})
+	if err != nil {
+		t.Fatalf("WriteRegisters returned error: %v", err)
+	}
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("Write %d failed: %v", i, err)
+		}
+	}
+
+	// Two 200 character writes fill a packet
+	if fmt.Sprint(mock.batches) != "[2 2]" {
+		t.Errorf("Expected batches [2 2], got %v", mock.batches)
+	}
+	if mock.largest > cpppo.MaxUnconnectedMessageSize {
+		t.Errorf("Expected packets of at most %d bytes, got %d", cpppo.MaxUnconnectedMessageSize, mock.largest)
+	}
+
+	// A reply may hold a full length string, so reads go one per packet
+	mock.batches = nil
+	results, err := client.ReadRegisters(RegisterTypeSR, 1, 4)
+	if err != nil {
+		t.Fatalf("ReadRegisters returned error: %v", err)
+	}
+	if results[2].Value != long || results[3].Value != "short" {
+		t.Errorf("Unexpected results %+v", results)
+	}
+	if fmt.Sprint(mock.batches) != "[1 1 1 1]" {
+		t.Errorf("Expected batches [1 1 1 1], got %v", mock.batches)
+	}
+}
+
+// attributeBatchClient adds batched attribute reads to mockPLCClient
+type attributeBatchClient struct {
+	*mockPLCClient
+	batches []int
+}
+
+func (c *attributeBatchClient) GetAttributesSingle(refs []cpppo.AttributeRef) ([]cpppo.AttributeResult, error) {
+	c.batches = append(c.batches, len(refs))
+	results := make([]cpppo.AttributeResult, len(refs))
+	for i, ref := range refs {
+		results[i].Data, results[i].Err = c.GetAttributeSingle(ref.Class, ref.Instance, ref.Attribute)
+	}
+	return results, nil
+}
+
+// TestReadRegisterSetAttributes tests that R and SR registers are read in
+// batches of attribute reads in AccessAttribute mode
+func TestReadRegisterSetAttributes(t *testing.T) {
+	mock := &attributeBatchClient{mockPLCClient: newMockPLCClient()}
+	client := &FanucClient{PLCClient: mock, Access: AccessAttribute}
+
+	for i := 1; i <= 60; i++ {
+		mock.attributes[attributeKey{0x6C, 1, uint16(i)}] = cpppo.EncodeFloat32(float32(i))
+	}
+	delete(mock.attributes, attributeKey{0x6C, 1, 7})
+	mock.attributes[attributeKey{0x6D, 1, 2}], _ = cpppo.EncodeValue(cpppo.CIPDataTypeSTRING, "PART_A")
+
+	set, _ := registerRange(RegisterTypeR, 1, 60)
+	set = append(set, RegisterRef{RegisterTypeSR, 2})
+	results, err := client.ReadRegisterSet(set)
+	if err != nil {
+		t.Fatalf("ReadRegisterSet returned error: %v", err)
+	}
+
+	if results[0].Value != float32(1) || results[59].Value != float32(60) || results[60].Value != "PART_A" {
+		t.Errorf("Unexpected results %+v", results)
+	}
+	if results[6].Err == nil {
+		t.Errorf("Expected error for missing R[7], got %+v", results[6])
+	}
+
+	// Get Attribute Single requests and REAL replies are 8 bytes each, so
+	// 49 fit in a packet
+	if fmt.Sprint(mock.batches) != "[49 12]" {
+		t.Errorf("Expected batches [49 12], got %v", mock.batches)
+	}
+	if len(mock.readCalls) != 0 {
+		t.Errorf("Expected no tag reads, got %v", mock.readCalls)
+	}
+}
+
+// TestReadRegisterSet tests a mixed set, including registers that cannot be
+// batched
+func TestReadRegisterSet(t *testing.T) {
+	mock := &batchMockPLCClient{mockPLCClient: newMockPLCClient()}
+	client := &FanucClient{PLCClient: mock}
+
+	mock.readResponses["R[1]"] = float32(1)
+	mock.readResponses["GO[2]"] = int32(37)
+	mock.readResponses["DI[3]"] = true
+	for _, member := range []string{"X", "Y", "Z", "W", "P", "R"} {
+		mock.readResponses["PR[4]."+member] = float32(0)
+	}
+	mock.readResponses["PR[4].Config"] = "N U T, 0, 0, 0"
+
+	results, err := client.ReadRegisterSet(RegisterSet{
+		{RegisterTypeR, 1},
+		{RegisterTypePR, 4},
+		{RegisterTypeGO, 2},
+		{RegisterTypeDI, 3},
+	})
+	if err != nil {
+		t.Fatalf("ReadRegisterSet returned error: %v", err)
+	}
+
+	if results[0].Value != float32(1) || results[2].Value != int32(37) || results[3].Value != true {
+		t.Errorf("Unexpected results %+v", results)
+	}
+	if _, ok := results[1].Value.(*Position); !ok || results[1].Err != nil {
+		t.Errorf("Expected a position for PR[4], got %+v", results[1])
+	}
+
+	// One batch for the position, one for the other registers
+	if mock.batches != 2 {
+		t.Errorf("Expected 2 batches, got %d", mock.batches)
+	}
+}
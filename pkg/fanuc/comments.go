@@ -0,0 +1,128 @@
+package fanuc
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/carun/cpppo-go/pkg/cpppo"
+)
+
+// MaxStringRegisterLength is the longest string an SR register holds
+const MaxStringRegisterLength = 254
+
+// Longest comments the controller keeps
+const (
+	maxRegisterCommentLength = 16 // R, PR, SR, UR and VR comments
+	maxIOCommentLength       = 24 // I/O signal, flag and marker comments
+)
+
+// checkText returns an error unless s is printable ASCII of at most max
+// characters, which is all the teach pendant can show
+func checkText(s string, max int) error {
+	if len(s) > max {
+		return fmt.Errorf("%d characters is longer than %d", len(s), max)
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < 0x20 || s[i] > 0x7E {
+			return fmt.Errorf("character %q at %d is not printable ASCII", s[i], i)
+		}
+	}
+	return nil
+}
+
+// checkStringRegister returns an error unless value can be stored in an SR
+// register
+func checkStringRegister(value interface{}) error {
+	s, ok := value.(string)
+	if !ok {
+		return errors.New("value must be a string for SR registers")
+	}
+	return checkText(s, MaxStringRegisterLength)
+}
+
+// storedString returns the value read from an SR register. Only the length
+// is checked: a value longer than an SR register holds did not come from
+// one, but the controller may store characters the pendant cannot type,
+// such as katakana, and those must stay readable.
+func storedString(value interface{}, err error) (interface{}, error) {
+	if err != nil {
+		return nil, err
+	}
+	s, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("unexpected type %T for an SR register", value)
+	}
+	if len(s) > MaxStringRegisterLength {
+		return nil, fmt.Errorf("unexpected SR value: %d characters is longer than %d", len(s), MaxStringRegisterLength)
+	}
+	return value, nil
+}
+
+// ReadStringRegister is a convenience method to read an SR register. Values
+// longer than MaxStringRegisterLength are reported as errors; other
+// characters are returned as stored.
+func (f *FanucClient) ReadStringRegister(index int) (string, error) {
+	value, err := f.ReadRegister(RegisterTypeSR, index)
+	if err != nil {
+		return "", err
+	}
+	s, ok := value.(string)
+	if !ok {
+		return "", errors.New("failed to convert value to string")
+	}
+	return s, nil
+}
+
+// WriteStringRegister is a convenience method to write an SR register. The
+// value must be printable ASCII of at most MaxStringRegisterLength
+// characters.
+func (f *FanucClient) WriteStringRegister(index int, value string) error {
+	return f.WriteRegister(RegisterTypeSR, index, value)
+}
+
+// commentLength returns the longest comment registers of regType can have
+func commentLength(regType RegisterType) int {
+	switch regType {
+	case RegisterTypeR, RegisterTypePR, RegisterTypeSR, RegisterTypeUR, RegisterTypeVR:
+		return maxRegisterCommentLength
+	default:
+		return maxIOCommentLength
+	}
+}
+
+// commentTag returns the tag holding a register's comment, such as
+// "R[1].COMMENT"
+func commentTag(regType RegisterType, index int) string {
+	return buildRegisterTag(regType, index) + ".COMMENT"
+}
+
+// ReadRegisterComment reads the comment of any register or I/O signal, as
+// shown next to it on the teach pendant
+func (f *FanucClient) ReadRegisterComment(regType RegisterType, index int) (string, error) {
+	tagName := commentTag(regType, index)
+	value, err := f.PLCClient.ReadTag(tagName, cpppo.CIPDataTypeSTRING)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", tagName, err)
+	}
+
+	comment, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("unexpected type %T for %s", value, tagName)
+	}
+	return comment, nil
+}
+
+// WriteRegisterComment sets the comment of any register or I/O signal.
+// Register comments hold up to 16 characters and I/O comments up to 24, all
+// printable ASCII.
+func (f *FanucClient) WriteRegisterComment(regType RegisterType, index int, comment string) error {
+	tagName := commentTag(regType, index)
+	if err := checkText(comment, commentLength(regType)); err != nil {
+		return fmt.Errorf("%s: %w", tagName, err)
+	}
+
+	if err := f.PLCClient.WriteTag(tagName, cpppo.CIPDataTypeSTRING, comment); err != nil {
+		return fmt.Errorf("failed to write %s: %w", tagName, err)
+	}
+	return nil
+}
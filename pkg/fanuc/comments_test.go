@@ -0,0 +1,96 @@
+package fanuc
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/carun/cpppo-go/pkg/cpppo"
+)
+
+func TestStringRegister(t *testing.T) {
+	mock := newMockPLCClient()
+	client := &FanucClient{PLCClient: mock}
+
+	mock.readResponses["SR[1]"] = "PART_A"
+	value, err := client.ReadStringRegister(1)
+	if err != nil || value != "PART_A" {
+		t.Errorf("Expected PART_A, got %q (%v)", value, err)
+	}
+
+	longest := strings.Repeat("x", MaxStringRegisterLength)
+	if err := client.WriteStringRegister(2, longest); err != nil {
+		t.Fatalf("Failed to write %d characters: %v", MaxStringRegisterLength, err)
+	}
+	if mock.writeCalls["SR[2]"] != longest {
+		t.Errorf("Expected SR[2] to be written, got %v", mock.writeCalls["SR[2]"])
+	}
+
+	for _, invalid := range []interface{}{longest + "x", "tab\there", 42} {
+		if err := client.WriteRegister(RegisterTypeSR, 3, invalid); err == nil {
+			t.Errorf("Expected error writing %q", invalid)
+		}
+		if _, err := client.WriteRegisters(RegisterTypeSR, 3, []interface{}{invalid}); err == nil {
+			t.Errorf("Expected error writing %q in a batch", invalid)
+		}
+	}
+	if _, ok := mock.writeCalls["SR[3]"]; ok {
+		t.Error("Expected invalid strings not to be written")
+	}
+
+	// Reads are held to the length limit only, so values the controller
+	// stored in other characters stay readable
+	mock.readResponses["SR[4]"] = longest + "x"
+	if value, err := client.ReadStringRegister(4); err == nil {
+		t.Errorf("Expected error reading SR[4], got %q", value)
+	}
+	for _, stored := range []string{"bell\a", "\xb6\xc0\xb6\xc5"} {
+		mock.readResponses["SR[5]"] = stored
+		if value, err := client.ReadStringRegister(5); err != nil || value != stored {
+			t.Errorf("Expected %q, got %q (%v)", stored, value, err)
+		}
+	}
+
+	batch := &typedBatchClient{typedTagClient: &typedTagClient{tags: map[string]typedTag{
+		"SR[1]": {cpppo.CIPDataTypeSTRING, "PART_A"},
+		"SR[2]": {cpppo.CIPDataTypeSTRING, longest + "x"},
+	}}}
+	client.PLCClient = batch
+	results, err := client.ReadRegisters(RegisterTypeSR, 1, 2)
+	if err != nil {
+		t.Fatalf("ReadRegisters returned error: %v", err)
+	}
+	if results[0].Value != "PART_A" || results[1].Err == nil {
+		t.Errorf("Expected an error for the over-length SR[2], got %+v", results)
+	}
+}
+
+func TestRegisterComment(t *testing.T) {
+	mock := newMockPLCClient()
+	client := &FanucClient{PLCClient: mock}
+
+	mock.readResponses["R[5].COMMENT"] = "Part count"
+	comment, err := client.ReadRegisterComment(RegisterTypeR, 5)
+	if err != nil || comment != "Part count" {
+		t.Errorf("Expected 'Part count', got %q (%v)", comment, err)
+	}
+
+	// Missing comments come back as the mock's int 0
+	if _, err := client.ReadRegisterComment(RegisterTypePR, 1); err == nil {
+		t.Error("Expected error for a non-string comment")
+	}
+
+	if err := client.WriteRegisterComment(RegisterTypeDO, 3, "Gripper close request"); err != nil {
+		t.Fatalf("Failed to write I/O comment: %v", err)
+	}
+	if mock.writeCalls["DO[3].COMMENT"] != "Gripper close request" {
+		t.Errorf("Unexpected comment writes %v", mock.writeCalls)
+	}
+
+	// 21 characters fit an I/O comment but not a register comment
+	if err := client.WriteRegisterComment(RegisterTypePR, 1, "Gripper close request"); err == nil {
+		t.Error("Expected error for a long register comment")
+	}
+	if err := client.WriteRegisterComment(RegisterTypeF, 1, "caf\xc3\xa9"); err == nil {
+		t.Error("Expected error for a non-ASCII comment")
+	}
+}
@@ -0,0 +1,154 @@
+package fanuc
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Turn number limits; the controller keeps each turn number in four bits
+const (
+	MinTurn = -8
+	MaxTurn = 7
+)
+
+// Configuration is the arm configuration of a Cartesian position, shown on
+// the teach pendant as "N U T, 0, 0, 0". The zero value is "N U T, 0, 0, 0".
+type Configuration struct {
+	Flip  bool    // Wrist flipped (F), otherwise not flipped (N)
+	Down  bool    // Elbow down (D), otherwise up (U)
+	Back  bool    // Arm at the bottom/back (B), otherwise top/front (T)
+	Turns [3]int8 // Turn numbers of the three turn axes, normally J4, J5 and J6
+}
+
+// ParseConfiguration parses a configuration in the teach pendant form. The
+// letters may be run together ("NUT") and commas are optional; omitted turn
+// numbers are zero. Letters are not case sensitive.
+func ParseConfiguration(s string) (Configuration, error) {
+	var c Configuration
+
+	fields := strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+	if len(fields) == 0 {
+		return c, fmt.Errorf("invalid configuration %q: empty", s)
+	}
+
+	// The three letters, either as one field or as three
+	letters := strings.ToUpper(fields[0])
+	fields = fields[1:]
+	for len(letters) < 3 && len(fields) > 0 {
+		letters += strings.ToUpper(fields[0])
+		fields = fields[1:]
+	}
+	if len(letters) != 3 {
+		return c, fmt.Errorf("invalid configuration %q: expected three letters", s)
+	}
+
+	for i, choice := range []struct {
+		set, clear byte
+		field      *bool
+	}{
+		{'F', 'N', &c.Flip},
+		{'D', 'U', &c.Down},
+		{'B', 'T', &c.Back},
+	} {
+		switch letters[i] {
+		case choice.set:
+			*choice.field = true
+		case choice.clear:
+		default:
+			return c, fmt.Errorf("invalid configuration %q: unexpected %q", s, letters[i])
+		}
+	}
+
+	if len(fields) > len(c.Turns) {
+		return c, fmt.Errorf("invalid configuration %q: too many turn numbers", s)
+	}
+	for i, field := range fields {
+		turn, err := strconv.Atoi(field)
+		if err != nil {
+			return c, fmt.Errorf("invalid configuration %q: turn number %q", s, field)
+		}
+		if turn < MinTurn || turn > MaxTurn {
+			return c, fmt.Errorf("invalid configuration %q: turn number %d out of range %d to %d", s, turn, MinTurn, MaxTurn)
+		}
+		c.Turns[i] = int8(turn)
+	}
+
+	return c, nil
+}
+
+// String formats the configuration in the teach pendant form
+func (c Configuration) String() string {
+	letter := func(set bool, yes, no string) string {
+		if set {
+			return yes
+		}
+		return no
+	}
+
+	return fmt.Sprintf("%s %s %s, %d, %d, %d",
+		letter(c.Flip, "F", "N"), letter(c.Down, "D", "U"), letter(c.Back, "B", "T"),
+		c.Turns[0], c.Turns[1], c.Turns[2])
+}
+
+// MarshalText encodes the configuration in the teach pendant form
+func (c Configuration) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+// UnmarshalText parses a configuration in the teach pendant form
+func (c *Configuration) UnmarshalText(text []byte) error {
+	parsed, err := ParseConfiguration(string(text))
+	if err != nil {
+		return err
+	}
+	*c = parsed
+	return nil
+}
+
+// Validate checks that the turn numbers are within range
+func (c Configuration) Validate() error {
+	for i, turn := range c.Turns {
+		if turn < MinTurn || turn > MaxTurn {
+			return fmt.Errorf("turn number %d is %d, out of range %d to %d", i+1, turn, MinTurn, MaxTurn)
+		}
+	}
+	return nil
+}
+
+// Configuration flag bits in a position record
+const (
+	configFlip  = 0x01 // Wrist flipped (F), otherwise N
+	configUp    = 0x02 // Elbow up (U), otherwise D
+	configFront = 0x04 // Arm in front (T), otherwise B
+)
+
+// decodeConfiguration builds a configuration from the flags byte and three
+// turn number bytes of a position record
+func decodeConfiguration(data []byte) Configuration {
+	return Configuration{
+		Flip:  data[0]&configFlip != 0,
+		Down:  data[0]&configUp == 0,
+		Back:  data[0]&configFront == 0,
+		Turns: [3]int8{int8(data[1]), int8(data[2]), int8(data[3])},
+	}
+}
+
+// encode returns the flags byte and three turn number bytes of a position
+// record
+func (c Configuration) encode() []byte {
+	var flags byte
+	if c.Flip {
+		flags |= configFlip
+	}
+	if !c.Down {
+		flags |= configUp
+	}
+	if !c.Back {
+		flags |= configFront
+	}
+	return []byte{flags, byte(c.Turns[0]), byte(c.Turns[1]), byte(c.Turns[2])}
+}
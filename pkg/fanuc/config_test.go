@@ -0,0 +1,86 @@
+package fanuc
+
+import (
+	"testing"
+)
+
+// TestParseConfiguration tests parsing the teach pendant forms
+func TestParseConfiguration(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Configuration
+	}{
+		{"N U T, 0, 0, 0", Configuration{}},
+		{"F D B, 1, -1, 7", Configuration{Flip: true, Down: true, Back: true, Turns: [3]int8{1, -1, 7}}},
+		{"NUT, 0, 0, 0", Configuration{}},
+		{"f u b 0 1", Configuration{Flip: true, Back: true, Turns: [3]int8{0, 1, 0}}},
+		{"N D T", Configuration{Down: true}},
+	}
+
+	for _, tc := range tests {
+		c, err := ParseConfiguration(tc.input)
+		if err != nil {
+			t.Errorf("ParseConfiguration(%q) returned error: %v", tc.input, err)
+			continue
+		}
+		if c != tc.expected {
+			t.Errorf("ParseConfiguration(%q): expected %+v, got %+v", tc.input, tc.expected, c)
+		}
+	}
+}
+
+// TestParseConfigurationErrors tests rejected configurations
+func TestParseConfigurationErrors(t *testing.T) {
+	for _, input := range []string{
+		"",
+		"X U T, 0, 0, 0",
+		"N U, 0, 0, 0",
+		"N U T, 0, 0, 0, 0",
+		"N U T, 0, 0, 8",
+		"N U T, 0, a, 0",
+		"garbage",
+	} {
+		if c, err := ParseConfiguration(input); err == nil {
+			t.Errorf("ParseConfiguration(%q): expected error, got %+v", input, c)
+		}
+	}
+}
+
+// TestConfigurationRoundTrip tests that formatting and encoding preserve
+// every configuration
+func TestConfigurationRoundTrip(t *testing.T) {
+	for flags := 0; flags < 8; flags++ {
+		for turn := MinTurn; turn <= MaxTurn; turn++ {
+			c := Configuration{
+				Flip:  flags&1 != 0,
+				Down:  flags&2 != 0,
+				Back:  flags&4 != 0,
+				Turns: [3]int8{int8(turn), 0, int8(-turn - 1)},
+			}
+
+			parsed, err := ParseConfiguration(c.String())
+			if err != nil || parsed != c {
+				t.Errorf("String round trip of %+v gave %+v (%v)", c, parsed, err)
+			}
+			if decoded := decodeConfiguration(c.encode()); decoded != c {
+				t.Errorf("Record round trip of %+v gave %+v", c, decoded)
+			}
+		}
+	}
+}
+
+// TestConfigurationValidate tests the turn number range check
+func TestConfigurationValidate(t *testing.T) {
+	if err := (Configuration{Turns: [3]int8{MinTurn, 0, MaxTurn}}).Validate(); err != nil {
+		t.Errorf("Expected valid configuration, got %v", err)
+	}
+	if err := (Configuration{Turns: [3]int8{0, MaxTurn + 1, 0}}).Validate(); err == nil {
+		t.Error("Expected error for turn number out of range")
+	}
+
+	// Positions with an invalid configuration are not written
+	client := &FanucClient{PLCClient: newMockPLCClient()}
+	if err := client.WritePositionRegister(1, &Position{Config: Configuration{Turns: [3]int8{-9, 0, 0}}}); err == nil {
+		t.Error("Expected error writing an invalid configuration")
+	}
+}
@@ -0,0 +1,205 @@
+package fanuc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/carun/cpppo-go/pkg/cpppo"
+)
+
+// DefaultStreamInterval is the sampling interval of StreamPosition when none
+// is given
+const DefaultStreamInterval = 100 * time.Millisecond
+
+// currentPositionAttribute is the attribute of the current position objects
+// holding the position. As with position registers, the motion group is the
+// instance.
+const currentPositionAttribute = 1
+
+// CurrentPosition reads the current Cartesian position of a motion group, as
+// CURPOS returns it in a TP program. Through the current position object the
+// position is in the active user and tool frames, which are reported in
+// Frames. Through system variables it is the world position of the tool
+// center point, and Config is not reported.
+func (f *FanucClient) CurrentPosition(group int) (*Position, error) {
+	return f.currentPosition(group, RepresentationCartesian)
+}
+
+// CurrentJoints reads the current joint angles of a motion group, as
+// CURJPOS returns them in a TP program
+func (f *FanucClient) CurrentJoints(group int) (*Position, error) {
+	return f.currentPosition(group, RepresentationJoint)
+}
+
+// currentPosition reads the current position of a group in the given
+// representation
+func (f *FanucClient) currentPosition(group int, repr Representation) (*Position, error) {
+	if group < 1 || group > maxMotionGroups {
+		return nil, fmt.Errorf("motion group %d out of range 1-%d", group, maxMotionGroups)
+	}
+
+	if class := f.currentPositionClass(repr); class != 0 {
+		return f.readCurrentAttribute(class, group, repr)
+	}
+	return f.readCurrentSysVars(group, repr)
+}
+
+// currentPositionClass returns the CIP class holding the current position in
+// the given representation, or zero if it is read from system variables
+func (f *FanucClient) currentPositionClass(repr Representation) uint16 {
+	if f.Access != AccessAttribute {
+		return 0
+	}
+
+	classes := f.objectClasses()
+	if repr == RepresentationJoint {
+		return classes.CurrentJoints
+	}
+	return classes.CurrentPosition
+}
+
+// readCurrentAttribute reads the current position through its CIP object,
+// which returns a record in the same layout as a position register
+func (f *FanucClient) readCurrentAttribute(class uint16, group int, repr Representation) (*Position, error) {
+	client, err := f.attributeClient()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := client.GetAttributeSingle(class, uint32(group), currentPositionAttribute)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read current position of group %d: %w", group, err)
+	}
+
+	position, err := decodePositionRecord(data, repr)
+	if err != nil {
+		return nil, err
+	}
+	position.Group = group
+	return position, nil
+}
+
+// readCurrentSysVars reads the current position from the $SCR_GRP system
+// variables of the group, in one request when the client can batch
+func (f *FanucClient) readCurrentSysVars(group int, repr Representation) (*Position, error) {
+	base := fmt.Sprintf("$SCR_GRP[%d]", group)
+
+	var reads []cpppo.TagRead
+	if repr == RepresentationJoint {
+		for i := 1; i <= maxJoints; i++ {
+			reads = append(reads, cpppo.TagRead{Name: fmt.Sprintf("%s.$MCH_ANG[%d]", base, i), DataType: cpppo.CIPDataTypeREAL})
+		}
+	} else {
+		for _, axis := range positionAxes {
+			reads = append(reads, cpppo.TagRead{Name: base + ".$MCH_POS_" + axis, DataType: cpppo.CIPDataTypeREAL})
+		}
+	}
+
+	results, err := f.readComponents(reads)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read current position of group %d: %w", group, err)
+	}
+
+	values := make([]float32, 0, len(results))
+	for i, result := range results {
+		value, ok := result.Value.(float32)
+		if result.Err == nil && ok {
+			values = append(values, value)
+			continue
+		}
+
+		// Groups have as many joints as they have axes
+		if repr == RepresentationJoint && i > 0 {
+			break
+		}
+		if result.Err == nil {
+			result.Err = fmt.Errorf("unexpected type %T", result.Value)
+		}
+		return nil, fmt.Errorf("failed to read %s: %w", reads[i].Name, result.Err)
+	}
+
+	position := &Position{Representation: repr, Group: group}
+	if repr == RepresentationJoint {
+		position.Joints = values
+	} else {
+		position.X, position.Y, position.Z = values[0], values[1], values[2]
+		position.W, position.P, position.R = values[3], values[4], values[5]
+	}
+	return position, nil
+}
+
+// StreamOptions configures StreamPosition
+type StreamOptions struct {
+	Group          int            // Motion group, 0 means group 1
+	Representation Representation // Cartesian (CURPOS) or joint (CURJPOS)
+	Interval       time.Duration  // Time between samples, 0 means DefaultStreamInterval
+}
+
+// PositionSample is one sample of a position stream
+type PositionSample struct {
+	Time     time.Time // When the read completed
+	Position *Position // Nil if the read failed
+	Err      error
+}
+
+// StreamPosition samples the current position at a fixed interval and sends
+// each sample on the returned channel until ctx is canceled, then closes it.
+// A failed read is sent as a sample with Err set and sampling continues. If
+// the receiver falls behind, samples are delayed rather than dropped.
+func (f *FanucClient) StreamPosition(ctx context.Context, options StreamOptions) (<-chan PositionSample, error) {
+	group := options.Group
+	if group == 0 {
+		group = 1
+	}
+	if group < 1 || group > maxMotionGroups {
+		return nil, fmt.Errorf("motion group %d out of range 1-%d", options.Group, maxMotionGroups)
+	}
+	if options.Representation != RepresentationCartesian && options.Representation != RepresentationJoint {
+		return nil, fmt.Errorf("unknown representation %v", options.Representation)
+	}
+
+	interval := options.Interval
+	if interval == 0 {
+		interval = DefaultStreamInterval
+	}
+	if interval < 0 {
+		return nil, errors.New("stream interval must not be negative")
+	}
+
+	samples := make(chan PositionSample, 16)
+
+	go func() {
+		defer close(samples)
+
+		sampleEvery(ctx, interval, func() bool {
+			position, err := f.currentPosition(group, options.Representation)
+			sample := PositionSample{Time: time.Now(), Position: position, Err: err}
+
+			select {
+			case samples <- sample:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		})
+	}()
+
+	return samples, nil
+}
+
+// sampleEvery calls sample at once and then at every interval, until ctx is
+// canceled or sample returns false
+func sampleEvery(ctx context.Context, interval time.Duration, sample func() bool) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for sample() {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
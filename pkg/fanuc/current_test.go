@@ -0,0 +1,127 @@
+package fanuc
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCurrentPositionSysVars(t *testing.T) {
+	mock := &batchMockPLCClient{mockPLCClient: newMockPLCClient()}
+	client := &FanucClient{PLCClient: mock}
+
+	for i, axis := range positionAxes {
+		mock.readResponses["$SCR_GRP[2].$MCH_POS_"+axis] = float32(i + 1)
+	}
+	for i := 1; i <= 6; i++ {
+		mock.readResponses[fmt.Sprintf("$SCR_GRP[1].$MCH_ANG[%d]", i)] = float32(i * 10)
+	}
+
+	position, err := client.CurrentPosition(2)
+	if err != nil {
+		t.Fatalf("CurrentPosition returned error: %v", err)
+	}
+	if position.Group != 2 || position.X != 1 || position.Z != 3 || position.R != 6 {
+		t.Errorf("Unexpected position %+v", position)
+	}
+
+	// A six axis arm has no J7 to J9
+	joints, err := client.CurrentJoints(1)
+	if err != nil {
+		t.Fatalf("CurrentJoints returned error: %v", err)
+	}
+	if !reflect.DeepEqual(joints.Joints, []float32{10, 20, 30, 40, 50, 60}) {
+		t.Errorf("Unexpected joints %v", joints.Joints)
+	}
+	if mock.batches != 2 {
+		t.Errorf("Expected one request per read, got %d", mock.batches)
+	}
+
+	// Missing Cartesian components are errors
+	if _, err := client.CurrentPosition(3); err == nil {
+		t.Error("Expected error for group without position")
+	}
+	if _, err := client.CurrentPosition(9); err == nil {
+		t.Error("Expected error for group 9")
+	}
+}
+
+func TestCurrentPositionAttribute(t *testing.T) {
+	mock := newMockPLCClient()
+	client := &FanucClient{PLCClient: mock, Access: AccessAttribute}
+
+	record := make([]byte, frameRecordLength+6*4)
+	record[0], record[4] = 1, 2 // UT 1, UF 2
+	for i := 0; i < 6; i++ {
+		binary.LittleEndian.PutUint32(record[frameRecordLength+4*i:], uint32(i))
+	}
+	mock.attributes[attributeKey{0x7E, 1, 1}] = record
+
+	joints, err := client.CurrentJoints(1)
+	if err != nil {
+		t.Fatalf("CurrentJoints returned error: %v", err)
+	}
+	if len(joints.Joints) != 6 || joints.Frames == nil || *joints.Frames != (FrameNumbers{UF: 2, UT: 1}) {
+		t.Errorf("Unexpected joints %+v", joints)
+	}
+	if len(mock.readCalls) != 0 {
+		t.Errorf("Expected no symbolic reads, got %v", mock.readCalls)
+	}
+}
+
+func TestStreamPosition(t *testing.T) {
+	mock := newMockPLCClient()
+	client := &FanucClient{PLCClient: mock}
+	for _, axis := range positionAxes {
+		mock.readResponses["$SCR_GRP[1].$MCH_POS_"+axis] = float32(5)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	samples, err := client.StreamPosition(ctx, StreamOptions{Interval: time.Millisecond})
+	if err != nil {
+		t.Fatalf("StreamPosition returned error: %v", err)
+	}
+
+	var last time.Time
+	for i := 0; i < 3; i++ {
+		sample := <-samples
+		if sample.Err != nil || sample.Position == nil || sample.Position.X != 5 {
+			t.Fatalf("Unexpected sample %+v", sample)
+		}
+		if !sample.Time.After(last) {
+			t.Errorf("Expected increasing timestamps, got %v after %v", sample.Time, last)
+		}
+		last = sample.Time
+	}
+
+	cancel()
+	for range samples {
+		// Drain until the stream closes
+	}
+
+	// Failed reads are delivered and do not stop the stream
+	client.PLCClient = newMockPLCClient()
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	samples, err = client.StreamPosition(ctx, StreamOptions{Representation: RepresentationCartesian, Interval: time.Millisecond})
+	if err != nil {
+		t.Fatalf("StreamPosition returned error: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if sample := <-samples; sample.Err == nil {
+			t.Errorf("Expected error sample, got %+v", sample)
+		}
+	}
+
+	if _, err := client.StreamPosition(ctx, StreamOptions{Group: 9}); err == nil {
+		t.Error("Expected error for group 9")
+	}
+	if _, err := client.StreamPosition(ctx, StreamOptions{Interval: -time.Second}); err == nil {
+		t.Error("Expected error for negative interval")
+	}
+}
@@ -0,0 +1,226 @@
+package fanuc
+
+import (
+	"fmt"
+
+	"github.com/carun/cpppo-go/pkg/cpppo"
+)
+
+// Frame numbers available on the controller
+const (
+	maxUserFrames = 9  // UF 1-9; UF 0 is the world frame
+	maxToolFrames = 10 // UT 1-10
+)
+
+// Frame is a user or tool frame: an origin and an orientation in W/P/R form
+type Frame struct {
+	X, Y, Z float32 // Origin
+	W, P, R float32 // Orientation
+}
+
+// frameKind tells user frames from tool frames
+type frameKind struct {
+	name     string // Name used in errors
+	variable string // System variable holding the frames
+	max      int    // Highest frame number
+}
+
+var (
+	userFrames = frameKind{name: "user frame", variable: "$MNUFRAME", max: maxUserFrames}
+	toolFrames = frameKind{name: "tool frame", variable: "$MNUTOOL", max: maxToolFrames}
+)
+
+// Active frame numbers of each group
+const (
+	sysVarActiveUserFrame = "$MNUFRAMENUM[%d]"
+	sysVarActiveToolFrame = "$MNUTOOLNUM[%d]"
+)
+
+// ReadUserFrame reads user frame number (1-9) of a motion group
+func (f *FanucClient) ReadUserFrame(group, number int) (*Frame, error) {
+	return f.readFrame(userFrames, group, number)
+}
+
+// WriteUserFrame writes user frame number (1-9) of a motion group. The frame
+// is a system variable and each component is checked on its own, so the
+// client's SysVarWrites list needs an entry such as "$MNUFRAME[1,2].*" that
+// allows all of them.
+func (f *FanucClient) WriteUserFrame(group, number int, frame *Frame) error {
+	return f.writeFrame(userFrames, group, number, frame)
+}
+
+// ReadToolFrame reads tool frame number (1-10) of a motion group
+func (f *FanucClient) ReadToolFrame(group, number int) (*Frame, error) {
+	return f.readFrame(toolFrames, group, number)
+}
+
+// WriteToolFrame writes tool frame number (1-10) of a motion group, for
+// example after recalibrating the tool center point. It moves the TCP of
+// every program using the frame, so the client's SysVarWrites list needs an
+// entry such as "$MNUTOOL[1,2].*" that allows all of its components.
+func (f *FanucClient) WriteToolFrame(group, number int, frame *Frame) error {
+	return f.writeFrame(toolFrames, group, number, frame)
+}
+
+// frameTags returns the system variables holding the components of a frame,
+// in the order of the Frame fields
+func (k frameKind) frameTags(group, number int) ([]string, error) {
+	if group < 1 || group > maxMotionGroups {
+		return nil, fmt.Errorf("motion group %d out of range 1-%d", group, maxMotionGroups)
+	}
+	if number < 1 || number > k.max {
+		return nil, fmt.Errorf("%s %d out of range 1-%d", k.name, number, k.max)
+	}
+
+	tags := make([]string, len(positionAxes))
+	for i, axis := range positionAxes {
+		tags[i] = fmt.Sprintf("%s[%d,%d].$%s", k.variable, group, number, axis)
+	}
+	return tags, nil
+}
+
+// readFrame reads the six components of a frame in one request when the
+// client can batch
+func (f *FanucClient) readFrame(kind frameKind, group, number int) (*Frame, error) {
+	tags, err := kind.frameTags(group, number)
+	if err != nil {
+		return nil, err
+	}
+
+	reads := make([]cpppo.TagRead, len(tags))
+	for i, tag := range tags {
+		reads[i] = cpppo.TagRead{Name: tag, DataType: cpppo.CIPDataTypeREAL}
+	}
+
+	results, err := f.readComponents(reads)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s %d: %w", kind.name, number, err)
+	}
+
+	values := make([]float32, len(results))
+	for i, result := range results {
+		if result.Err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", tags[i], result.Err)
+		}
+		value, ok := result.Value.(float32)
+		if !ok {
+			return nil, fmt.Errorf("unexpected type %T for %s", result.Value, tags[i])
+		}
+		values[i] = value
+	}
+
+	return &Frame{
+		X: values[0], Y: values[1], Z: values[2],
+		W: values[3], P: values[4], R: values[5],
+	}, nil
+}
+
+// writeFrame writes the six components of a frame in one request when the
+// client can batch, so the robot never uses a half-written frame
+func (f *FanucClient) writeFrame(kind frameKind, group, number int, frame *Frame) error {
+	writes, err := f.frameWrites(kind, group, number, frame)
+	if err != nil {
+		return err
+	}
+
+	errs, err := f.writeComponents(writes)
+	if err != nil {
+		return fmt.Errorf("failed to write %s %d: %w", kind.name, number, err)
+	}
+	for i, err := range errs {
+		if err != nil {
+			return fmt.Errorf("failed to write %s: %w", writes[i].Name, err)
+		}
+	}
+	return nil
+}
+
+// frameWrites checks a frame and returns the writes of its components,
+// which the client's SysVarWrites list must allow
+func (f *FanucClient) frameWrites(kind frameKind, group, number int, frame *Frame) ([]cpppo.TagWrite, error) {
+	if frame == nil {
+		return nil, fmt.Errorf("no %s to write", kind.name)
+	}
+	tags, err := kind.frameTags(group, number)
+	if err != nil {
+		return nil, err
+	}
+
+	values := []float32{frame.X, frame.Y, frame.Z, frame.W, frame.P, frame.R}
+	if err := checkFinite(values); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", kind.name, err)
+	}
+	writes := make([]cpppo.TagWrite, len(tags))
+	for i, tag := range tags {
+		writes[i] = cpppo.TagWrite{Name: tag, DataType: cpppo.CIPDataTypeREAL, Value: values[i]}
+	}
+	if err := f.checkSysVarWrites(writes); err != nil {
+		return nil, err
+	}
+	return writes, nil
+}
+
+// ActiveFrames returns the user and tool frame numbers a motion group is
+// currently using
+func (f *FanucClient) ActiveFrames(group int) (FrameNumbers, error) {
+	if group < 1 || group > maxMotionGroups {
+		return FrameNumbers{}, fmt.Errorf("motion group %d out of range 1-%d", group, maxMotionGroups)
+	}
+
+	reads := []cpppo.TagRead{
+		{Name: fmt.Sprintf(sysVarActiveUserFrame, group), DataType: cpppo.CIPDataTypeDINT},
+		{Name: fmt.Sprintf(sysVarActiveToolFrame, group), DataType: cpppo.CIPDataTypeDINT},
+	}
+	results, err := f.readComponents(reads)
+	if err != nil {
+		return FrameNumbers{}, fmt.Errorf("failed to read active frames: %w", err)
+	}
+
+	numbers := make([]int, len(results))
+	for i, result := range results {
+		if result.Err != nil {
+			return FrameNumbers{}, fmt.Errorf("failed to read %s: %w", reads[i].Name, result.Err)
+		}
+		value, ok := result.Value.(int32)
+		if !ok {
+			return FrameNumbers{}, fmt.Errorf("unexpected type %T for %s", result.Value, reads[i].Name)
+		}
+		numbers[i] = int(value)
+	}
+
+	return FrameNumbers{UF: numbers[0], UT: numbers[1]}, nil
+}
+
+// SetActiveFrames selects the user frame (0-9, 0 is the world frame) and
+// tool frame (1-10) a motion group uses, as UFRAME_NUM and UTOOL_NUM do in a
+// TP program. $MNUFRAMENUM[group] and $MNUTOOLNUM[group] must be in the
+// client's SysVarWrites list.
+func (f *FanucClient) SetActiveFrames(group int, frames FrameNumbers) error {
+	if group < 1 || group > maxMotionGroups {
+		return fmt.Errorf("motion group %d out of range 1-%d", group, maxMotionGroups)
+	}
+	if frames.UF < 0 || frames.UF > maxUserFrames {
+		return fmt.Errorf("user frame %d out of range 0-%d", frames.UF, maxUserFrames)
+	}
+	if frames.UT < 1 || frames.UT > maxToolFrames {
+		return fmt.Errorf("tool frame %d out of range 1-%d", frames.UT, maxToolFrames)
+	}
+
+	writes := []cpppo.TagWrite{
+		{Name: fmt.Sprintf(sysVarActiveUserFrame, group), DataType: cpppo.CIPDataTypeDINT, Value: int32(frames.UF)},
+		{Name: fmt.Sprintf(sysVarActiveToolFrame, group), DataType: cpppo.CIPDataTypeDINT, Value: int32(frames.UT)},
+	}
+	if err := f.checkSysVarWrites(writes); err != nil {
+		return err
+	}
+	errs, err := f.writeComponents(writes)
+	if err != nil {
+		return fmt.Errorf("failed to select frames: %w", err)
+	}
+	for i, err := range errs {
+		if err != nil {
+			return fmt.Errorf("failed to write %s: %w", writes[i].Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package fanuc
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestUserAndToolFrames(t *testing.T) {
+	mock := &batchMockPLCClient{mockPLCClient: newMockPLCClient()}
+	client := &FanucClient{PLCClient: mock}
+
+	// Frames are system variables and written only when allowed
+	tool := &Frame{X: 1.5, Y: -2, Z: 250, W: 180, P: 0, R: 90}
+	if err := client.WriteToolFrame(1, 3, tool); !errors.Is(err, ErrSysVarWriteDenied) {
+		t.Errorf("Expected ErrSysVarWriteDenied, got %v", err)
+	}
+	if err := client.WriteRegister(RegisterTypeUR, 4, &Frame{X: 7}); !errors.Is(err, ErrSysVarWriteDenied) {
+		t.Errorf("Expected ErrSysVarWriteDenied for UR[4], got %v", err)
+	}
+	if len(mock.writeCalls) != 0 || mock.batches != 0 {
+		t.Fatalf("Expected nothing to be written, got %v", mock.writeCalls)
+	}
+
+	// The frame variable alone does not cover its components
+	client.SysVarWrites = SysVarAllowList{"$MNUTOOL[1,3]"}
+	if err := client.WriteToolFrame(1, 3, tool); !errors.Is(err, ErrSysVarWriteDenied) {
+		t.Errorf("Expected ErrSysVarWriteDenied for the variable alone, got %v", err)
+	}
+
+	client.SysVarWrites = SysVarAllowList{"$MNUTOOL[1,3].*", "$MNUFRAME[1,4].*"}
+	if err := client.WriteToolFrame(1, 3, tool); err != nil {
+		t.Fatalf("WriteToolFrame returned error: %v", err)
+	}
+	if mock.batches != 1 {
+		t.Errorf("Expected the frame in one request, got %d", mock.batches)
+	}
+	if mock.writeCalls["$MNUTOOL[1,3].$Z"] != float32(250) || mock.writeCalls["$MNUTOOL[1,3].$R"] != float32(90) {
+		t.Errorf("Unexpected writes %v", mock.writeCalls)
+	}
+
+	// Read back what was written
+	for name, value := range mock.writeCalls {
+		mock.readResponses[name] = value
+	}
+	got, err := client.ReadToolFrame(1, 3)
+	if err != nil || *got != *tool {
+		t.Errorf("Expected %+v, got %+v (%v)", *tool, got, err)
+	}
+
+	// UR registers are the user frames of group 1
+	for i, axis := range positionAxes {
+		mock.readResponses["$MNUFRAME[1,2].$"+axis] = float32(i)
+	}
+	value, err := client.ReadRegister(RegisterTypeUR, 2)
+	if err != nil || *value.(*Frame) != (Frame{0, 1, 2, 3, 4, 5}) {
+		t.Errorf("Unexpected UR[2] = %v (%v)", value, err)
+	}
+	if err := client.WriteRegister(RegisterTypeUR, 4, &Frame{X: 7}); err != nil {
+		t.Fatalf("Failed to write UR[4]: %v", err)
+	}
+	if mock.writeCalls["$MNUFRAME[1,4].$X"] != float32(7) {
+		t.Errorf("Expected UR[4] to be written as user frame 4, got %v", mock.writeCalls)
+	}
+
+	if _, err := client.ReadUserFrame(1, 0); err == nil {
+		t.Error("Expected error for user frame 0")
+	}
+	if _, err := client.ReadToolFrame(9, 1); err == nil {
+		t.Error("Expected error for group 9")
+	}
+	if err := client.WriteUserFrame(1, 1, nil); err == nil {
+		t.Error("Expected error for nil frame")
+	}
+	if _, err := client.ReadUserFrame(2, 1); err == nil {
+		t.Error("Expected error for missing frame")
+	}
+}
+
+func TestActiveFrames(t *testing.T) {
+	mock := &batchMockPLCClient{mockPLCClient: newMockPLCClient()}
+	client := &FanucClient{PLCClient: mock}
+
+	if err := client.SetActiveFrames(2, FrameNumbers{UF: 0, UT: 10}); !errors.Is(err, ErrSysVarWriteDenied) {
+		t.Errorf("Expected ErrSysVarWriteDenied, got %v", err)
+	}
+
+	client.SysVarWrites = SysVarAllowList{"$MNUFRAMENUM[*", "$MNUTOOLNUM[*"}
+	if err := client.SetActiveFrames(2, FrameNumbers{UF: 0, UT: 10}); err != nil {
+		t.Fatalf("SetActiveFrames returned error: %v", err)
+	}
+	if mock.writeCalls["$MNUFRAMENUM[2]"] != int32(0) || mock.writeCalls["$MNUTOOLNUM[2]"] != int32(10) {
+		t.Errorf("Unexpected writes %v", mock.writeCalls)
+	}
+
+	mock.readResponses["$MNUFRAMENUM[1]"] = int32(3)
+	mock.readResponses["$MNUTOOLNUM[1]"] = int32(1)
+	frames, err := client.ActiveFrames(1)
+	if err != nil || frames != (FrameNumbers{UF: 3, UT: 1}) {
+		t.Errorf("Expected UF 3, UT 1, got %+v (%v)", frames, err)
+	}
+
+	for _, invalid := range []FrameNumbers{{UF: 10, UT: 1}, {UF: 1, UT: 0}, {UF: -1, UT: 1}} {
+		if err := client.SetActiveFrames(1, invalid); err == nil {
+			t.Errorf("Expected error for %+v", invalid)
+		}
+	}
+}
@@ -0,0 +1,401 @@
+package fanuc
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Storage devices of the controller
+const (
+	DeviceMD  = "MD:"  // Memory device: programs, variables and listings such as errall.ls
+	DeviceFR  = "FR:"  // FROM disk
+	DeviceUD1 = "UD1:" // USB stick in the controller
+)
+
+// ErrNotConnected is returned when a FileClient is used before Connect, or
+// after a timeout or transport error closed its connection
+var ErrNotConnected = errors.New("not connected to the FTP server")
+
+// FileClient transfers files to and from the controller's FTP server
+type FileClient struct {
+	address  string        // Controller address (IP:port)
+	timeout  time.Duration // Timeout of each command and of each data read or write
+	user     string
+	password string
+
+	conn  net.Conn
+	text  *textproto.Conn
+	mutex sync.Mutex
+}
+
+// NewFileClient creates a client for the controller's FTP server. It logs in
+// anonymously, as controllers accept by default.
+func NewFileClient(address string, timeout time.Duration) *FileClient {
+	// Add default port if not specified
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, "21")
+	}
+
+	return &FileClient{
+		address:  address,
+		timeout:  timeout,
+		user:     "anonymous",
+		password: "anonymous",
+	}
+}
+
+// SetCredentials sets the user and password used by Connect, for
+// controllers with FTP accounts configured
+func (fc *FileClient) SetCredentials(user, password string) {
+	fc.mutex.Lock()
+	defer fc.mutex.Unlock()
+	fc.user, fc.password = user, password
+}
+
+// Connect opens the control connection and logs in
+func (fc *FileClient) Connect() error {
+	fc.mutex.Lock()
+	defer fc.mutex.Unlock()
+
+	if fc.text != nil {
+		return nil // Already connected
+	}
+
+	conn, err := net.DialTimeout("tcp", fc.address, fc.timeout)
+	if err != nil {
+		return fmt.Errorf("failed to connect to FTP server: %w", err)
+	}
+	fc.conn = conn
+	fc.text = textproto.NewConn(conn)
+
+	if err := fc.login(); err != nil {
+		fc.closeLocked()
+		return err
+	}
+	return nil
+}
+
+// login reads the greeting, logs in and selects binary transfers
+func (fc *FileClient) login() error {
+	fc.extendDeadline()
+	if _, _, err := fc.text.ReadResponse(220); err != nil {
+		return fmt.Errorf("unexpected FTP greeting: %w", err)
+	}
+
+	code, _, err := fc.command(0, "USER %s", fc.user)
+	if err != nil {
+		return fmt.Errorf("login failed: %w", err)
+	}
+	if code == 331 {
+		if _, _, err := fc.command(230, "PASS %s", fc.password); err != nil {
+			return fmt.Errorf("login failed: %w", err)
+		}
+	} else if code != 230 {
+		return fmt.Errorf("login failed: unexpected reply %d", code)
+	}
+
+	if _, _, err := fc.command(200, "TYPE I"); err != nil {
+		return fmt.Errorf("failed to select binary transfers: %w", err)
+	}
+	return nil
+}
+
+// Close logs out and closes the connection
+func (fc *FileClient) Close() error {
+	fc.mutex.Lock()
+	defer fc.mutex.Unlock()
+
+	if fc.text == nil {
+		return nil
+	}
+	fc.command(221, "QUIT")
+	return fc.closeLocked()
+}
+
+// closeLocked closes the control connection; the mutex must be held
+func (fc *FileClient) closeLocked() error {
+	if fc.text == nil {
+		return nil
+	}
+	err := fc.text.Close()
+	fc.conn, fc.text = nil, nil
+	return err
+}
+
+// List returns the names of the files on a device, such as DeviceMD
+func (fc *FileClient) List(device string) ([]string, error) {
+	fc.mutex.Lock()
+	defer fc.mutex.Unlock()
+
+	if err := fc.changeDevice(device); err != nil {
+		return nil, err
+	}
+
+	var names []string
+	err := fc.transfer("NLST", func(data io.ReadWriter) error {
+		content, err := io.ReadAll(data)
+		if err != nil {
+			return err
+		}
+		for _, line := range strings.Split(string(content), "\n") {
+			if name := strings.TrimSpace(line); name != "" {
+				names = append(names, name)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", device, err)
+	}
+	return names, nil
+}
+
+// Get copies a file from a device to w
+func (fc *FileClient) Get(device, name string, w io.Writer) error {
+	if err := checkFileName(name); err != nil {
+		return err
+	}
+
+	fc.mutex.Lock()
+	defer fc.mutex.Unlock()
+
+	if err := fc.changeDevice(device); err != nil {
+		return err
+	}
+	err := fc.transfer("RETR "+name, func(data io.ReadWriter) error {
+		_, err := io.Copy(w, data)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get %s%s: %w", device, name, err)
+	}
+	return nil
+}
+
+// Put copies r to a file on a device. The controller loads programs
+// (.TP, .LS) and variable files (.VA, .SV) as they are written.
+func (fc *FileClient) Put(device, name string, r io.Reader) error {
+	if err := checkFileName(name); err != nil {
+		return err
+	}
+
+	fc.mutex.Lock()
+	defer fc.mutex.Unlock()
+
+	if err := fc.changeDevice(device); err != nil {
+		return err
+	}
+	err := fc.transfer("STOR "+name, func(data io.ReadWriter) error {
+		_, err := io.Copy(data, r)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to put %s%s: %w", device, name, err)
+	}
+	return nil
+}
+
+// Backup downloads every file of a device into dir, which is created if
+// needed, and returns the names of the files written. DeviceMD holds a full
+// backup of programs, variables and listings.
+func (fc *FileClient) Backup(device, dir string) ([]string, error) {
+	names, err := fc.List(device)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	var written []string
+	for _, name := range names {
+		if err := checkFileName(name); err != nil {
+			return written, fmt.Errorf("unexpected file name on %s: %w", device, err)
+		}
+		if err := fc.backupFile(device, name, filepath.Join(dir, name)); err != nil {
+			return written, err
+		}
+		written = append(written, name)
+	}
+	return written, nil
+}
+
+// backupFile downloads one file into path. The file is written under a
+// temporary name and renamed once complete, so a failed transfer never
+// leaves a truncated file that looks like a backup.
+func (fc *FileClient) backupFile(device, name, path string) error {
+	partial := path + ".part"
+	file, err := os.Create(partial)
+	if err != nil {
+		return err
+	}
+
+	err = fc.Get(device, name, file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(partial, path)
+	}
+	if err != nil {
+		os.Remove(partial)
+		return err
+	}
+	return nil
+}
+
+var deviceName = regexp.MustCompile(`^[A-Z]+[0-9]*:$`)
+
+// changeDevice makes device the working directory, as "cd md:" does
+func (fc *FileClient) changeDevice(device string) error {
+	if !deviceName.MatchString(device) {
+		return fmt.Errorf("invalid device %q", device)
+	}
+	if fc.text == nil {
+		return ErrNotConnected
+	}
+	if _, _, err := fc.command(250, "CWD %s", device); err != nil {
+		return fmt.Errorf("failed to select %s: %w", device, err)
+	}
+	return nil
+}
+
+// checkFileName returns an error unless name is a plain file name that is
+// safe to send in a command and to use as a local file name
+func checkFileName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, "/\\:\r\n") {
+		return fmt.Errorf("invalid file name %q", name)
+	}
+	return nil
+}
+
+// command sends a command and reads its reply. An expectCode of 0 accepts
+// any reply below 400; see textproto.Conn.ReadResponse.
+func (fc *FileClient) command(expectCode int, format string, args ...interface{}) (int, string, error) {
+	fc.extendDeadline()
+	if _, err := fc.text.Cmd(format, args...); err != nil {
+		return 0, "", fc.controlError(err)
+	}
+	if expectCode == 0 {
+		code, message, err := fc.text.ReadResponse(0)
+		if err == nil && code >= 400 {
+			err = &textproto.Error{Code: code, Msg: message}
+		}
+		return code, message, fc.controlError(err)
+	}
+	code, message, err := fc.text.ReadResponse(expectCode)
+	return code, message, fc.controlError(err)
+}
+
+// controlError closes the control connection after an error other than an
+// error reply from the server, such as a timeout or a malformed reply. The
+// late reply would otherwise be taken for the reply to the next command, so
+// Connect must be called again. It returns err.
+func (fc *FileClient) controlError(err error) error {
+	var reply *textproto.Error
+	if err != nil && !errors.As(err, &reply) {
+		fc.closeLocked()
+	}
+	return err
+}
+
+// transfer opens a passive data connection, sends cmd and passes the data
+// connection to handle, then waits for the transfer to complete
+func (fc *FileClient) transfer(cmd string, handle func(data io.ReadWriter) error) error {
+	_, message, err := fc.command(227, "PASV")
+	if err != nil {
+		return err
+	}
+	port, err := parsePassivePort(message)
+	if err != nil {
+		return err
+	}
+
+	// Connect to the host of the control connection; the address in the
+	// reply is wrong behind NAT
+	host, _, err := net.SplitHostPort(fc.conn.RemoteAddr().String())
+	if err != nil {
+		return err
+	}
+	data, err := net.DialTimeout("tcp", net.JoinHostPort(host, strconv.Itoa(port)), fc.timeout)
+	if err != nil {
+		return fmt.Errorf("failed to open data connection: %w", err)
+	}
+	defer data.Close()
+
+	if _, _, err := fc.command(1, "%s", cmd); err != nil {
+		return err
+	}
+
+	err = handle(&deadlineConn{Conn: data, timeout: fc.timeout})
+	if closeErr := data.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		// Read the transfer reply so the next command stays in step
+		fc.extendDeadline()
+		_, _, replyErr := fc.text.ReadResponse(2)
+		fc.controlError(replyErr)
+		return err
+	}
+
+	fc.extendDeadline()
+	_, _, err = fc.text.ReadResponse(2)
+	return fc.controlError(err)
+}
+
+var passiveReply = regexp.MustCompile(`\(\s*\d+,\d+,\d+,\d+,(\d+),(\d+)\s*\)`)
+
+// parsePassivePort returns the data port of a "227 Entering Passive Mode
+// (h1,h2,h3,h4,p1,p2)" reply
+func parsePassivePort(message string) (int, error) {
+	match := passiveReply.FindStringSubmatch(message)
+	if match == nil {
+		return 0, fmt.Errorf("unexpected PASV reply %q", message)
+	}
+	high, _ := strconv.Atoi(match[1])
+	low, _ := strconv.Atoi(match[2])
+	if high > 255 || low > 255 {
+		return 0, fmt.Errorf("unexpected PASV reply %q", message)
+	}
+	return high<<8 | low, nil
+}
+
+// extendDeadline gives the next exchange on the control connection the
+// client's timeout
+func (fc *FileClient) extendDeadline() {
+	if fc.timeout > 0 {
+		fc.conn.SetDeadline(time.Now().Add(fc.timeout))
+	}
+}
+
+// deadlineConn applies a timeout to every read and write, so large
+// transfers are limited by inactivity rather than by total time
+type deadlineConn struct {
+	net.Conn
+	timeout time.Duration
+}
+
+func (c *deadlineConn) Read(p []byte) (int, error) {
+	if c.timeout > 0 {
+		c.Conn.SetReadDeadline(time.Now().Add(c.timeout))
+	}
+	return c.Conn.Read(p)
+}
+
+func (c *deadlineConn) Write(p []byte) (int, error) {
+	if c.timeout > 0 {
+		c.Conn.SetWriteDeadline(time.Now().Add(c.timeout))
+	}
+	return c.Conn.Write(p)
+}
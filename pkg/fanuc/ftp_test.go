@@ -0,0 +1,298 @@
+package fanuc
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// ftpStandIn is a local FTP server with the commands a controller answers,
+// storing files in memory by device
+type ftpStandIn struct {
+	listener net.Listener
+	mu       sync.Mutex
+	devices  map[string]map[string][]byte
+	password string // Required password, empty for anonymous logins
+	stall    string // Command left unanswered, to time the client out
+}
+
+func newFTPStandIn(t *testing.T) *ftpStandIn {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	s := &ftpStandIn{
+		listener: listener,
+		devices: map[string]map[string][]byte{
+			"MD:":  {"ERRALL.LS": []byte("alarm history\r\n"), "NUMREG.VA": []byte(numRegVA)},
+			"FR:":  {},
+			"UD1:": {},
+		},
+	}
+	t.Cleanup(func() { listener.Close() })
+	go s.serve()
+	return s
+}
+
+func (s *ftpStandIn) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.session(conn)
+	}
+}
+
+func (s *ftpStandIn) session(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	reply := func(format string, args ...interface{}) {
+		fmt.Fprintf(conn, format+"\r\n", args...)
+	}
+
+	reply("220 FTP server ready")
+	device := "MD:"
+	var passive net.Listener
+	defer func() {
+		if passive != nil {
+			passive.Close()
+		}
+	}()
+
+	// data accepts the data connection opened after PASV
+	data := func() net.Conn {
+		if passive == nil {
+			return nil
+		}
+		defer func() { passive.Close(); passive = nil }()
+		c, err := passive.Accept()
+		if err != nil {
+			return nil
+		}
+		return c
+	}
+
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd, arg, _ := strings.Cut(strings.TrimSpace(line), " ")
+
+		s.mu.Lock()
+		files := s.devices[device]
+		stall := s.stall
+		s.mu.Unlock()
+		if strings.EqualFold(cmd, stall) {
+			continue
+		}
+
+		switch strings.ToUpper(cmd) {
+		case "USER":
+			reply("331 Password required")
+		case "PASS":
+			if s.password != "" && arg != s.password {
+				reply("530 Login incorrect")
+				continue
+			}
+			reply("230 Logged in")
+		case "TYPE":
+			reply("200 Type set")
+		case "CWD":
+			if _, ok := s.devices[strings.ToUpper(arg)]; !ok {
+				reply("550 No such device")
+				continue
+			}
+			device = strings.ToUpper(arg)
+			reply("250 CWD successful")
+		case "PASV":
+			passive, _ = net.Listen("tcp", "127.0.0.1:0")
+			port := passive.Addr().(*net.TCPAddr).Port
+			reply("227 Entering Passive Mode (10,0,0,1,%d,%d)", port>>8, port&0xFF)
+		case "NLST":
+			reply("150 Opening data connection")
+			c := data()
+			s.mu.Lock()
+			var names []string
+			for name := range files {
+				names = append(names, name)
+			}
+			s.mu.Unlock()
+			sort.Strings(names)
+			for _, name := range names {
+				fmt.Fprintf(c, "%s\r\n", name)
+			}
+			c.Close()
+			reply("226 Transfer complete")
+		case "RETR":
+			s.mu.Lock()
+			content, ok := files[arg]
+			s.mu.Unlock()
+			if !ok {
+				data()
+				reply("550 File not found")
+				continue
+			}
+			reply("150 Opening data connection")
+			c := data()
+			c.Write(content)
+			c.Close()
+			reply("226 Transfer complete")
+		case "STOR":
+			reply("150 Opening data connection")
+			c := data()
+			content, _ := io.ReadAll(c)
+			c.Close()
+			s.mu.Lock()
+			files[arg] = content
+			s.mu.Unlock()
+			reply("226 Transfer complete")
+		case "QUIT":
+			reply("221 Goodbye")
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
+
+func TestFileClient(t *testing.T) {
+	server := newFTPStandIn(t)
+	client := NewFileClient(server.listener.Addr().String(), time.Second)
+
+	if _, err := client.List(DeviceMD); !errors.Is(err, ErrNotConnected) {
+		t.Errorf("Expected ErrNotConnected, got %v", err)
+	}
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect returned error: %v", err)
+	}
+	defer client.Close()
+
+	names, err := client.List(DeviceMD)
+	if err != nil || !reflect.DeepEqual(names, []string{"ERRALL.LS", "NUMREG.VA"}) {
+		t.Errorf("Unexpected MD: listing %v (%v)", names, err)
+	}
+
+	var errall bytes.Buffer
+	if err := client.Get(DeviceMD, "ERRALL.LS", &errall); err != nil || errall.String() != "alarm history\r\n" {
+		t.Errorf("Unexpected errall.ls %q (%v)", errall.String(), err)
+	}
+
+	program := "/PROG  PICK\n/MN\n/END\n"
+	if err := client.Put(DeviceUD1, "PICK.LS", strings.NewReader(program)); err != nil {
+		t.Fatalf("Put returned error: %v", err)
+	}
+	server.mu.Lock()
+	uploaded := string(server.devices["UD1:"]["PICK.LS"])
+	server.mu.Unlock()
+	if uploaded != program {
+		t.Errorf("Unexpected upload %q", uploaded)
+	}
+	names, err = client.List(DeviceUD1)
+	if err != nil || !reflect.DeepEqual(names, []string{"PICK.LS"}) {
+		t.Errorf("Unexpected UD1: listing %v (%v)", names, err)
+	}
+
+	// Errors leave the connection usable
+	if err := client.Get(DeviceMD, "MISSING.TP", io.Discard); err == nil {
+		t.Error("Expected error for a missing file")
+	}
+	if _, err := client.List("XX9:"); err == nil {
+		t.Error("Expected error for an unknown device")
+	}
+	for _, name := range []string{"", "..", "a/b", "X.LS\r\nDELE Y"} {
+		if err := client.Put(DeviceMD, name, strings.NewReader("")); err == nil {
+			t.Errorf("Expected error for file name %q", name)
+		}
+	}
+	if _, err := client.List("md:\r\nQUIT"); err == nil {
+		t.Error("Expected error for an invalid device")
+	}
+
+	dir := t.TempDir()
+	written, err := client.Backup(DeviceMD, filepath.Join(dir, "backup"))
+	if err != nil || len(written) != 2 {
+		t.Fatalf("Backup returned %v (%v)", written, err)
+	}
+	content, err := os.ReadFile(filepath.Join(dir, "backup", "NUMREG.VA"))
+	if err != nil || string(content) != numRegVA {
+		t.Errorf("Unexpected NUMREG.VA backup %q (%v)", content, err)
+	}
+}
+
+// TestFileClientTimeout tests that a timeout closes the connection and that
+// a failed backup leaves no file behind
+func TestFileClientTimeout(t *testing.T) {
+	server := newFTPStandIn(t)
+	client := NewFileClient(server.listener.Addr().String(), 200*time.Millisecond)
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect returned error: %v", err)
+	}
+	defer client.Close()
+
+	server.mu.Lock()
+	server.stall = "RETR"
+	server.mu.Unlock()
+
+	dir := t.TempDir()
+	written, err := client.Backup(DeviceMD, dir)
+	if err == nil || len(written) != 0 {
+		t.Fatalf("Expected the backup to fail, got %v (%v)", written, err)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 0 {
+		t.Errorf("Expected no files after a failed backup, got %v", entries)
+	}
+
+	// The late reply must not be read as the reply to the next command
+	if _, err := client.List(DeviceMD); !errors.Is(err, ErrNotConnected) {
+		t.Errorf("Expected ErrNotConnected after a timeout, got %v", err)
+	}
+
+	server.mu.Lock()
+	server.stall = ""
+	server.mu.Unlock()
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Reconnect returned error: %v", err)
+	}
+	if names, err := client.List(DeviceMD); err != nil || len(names) != 2 {
+		t.Errorf("Unexpected listing after reconnecting %v (%v)", names, err)
+	}
+}
+
+func TestFileClientLogin(t *testing.T) {
+	server := newFTPStandIn(t)
+	server.password = "secret"
+
+	client := NewFileClient(server.listener.Addr().String(), time.Second)
+	if err := client.Connect(); err == nil {
+		t.Error("Expected anonymous login to fail")
+	}
+
+	client.SetCredentials("robot", "secret")
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect returned error: %v", err)
+	}
+	if err := client.Close(); err != nil {
+		t.Errorf("Close returned error: %v", err)
+	}
+
+	if port, err := parsePassivePort("Entering Passive Mode (192,168,1,10,4,1)"); err != nil || port != 1025 {
+		t.Errorf("Expected port 1025, got %d (%v)", port, err)
+	}
+	if _, err := parsePassivePort("Entering Passive Mode"); err == nil {
+		t.Error("Expected error for a reply without an address")
+	}
+}
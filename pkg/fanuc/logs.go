@@ -54,6 +54,7 @@ type LogReader struct {
 	address string        // Controller address (IP:port)
 	timeout time.Duration // Connection timeout
 	conn    net.Conn      // Network connection
+	reader  *bufio.Reader // Buffered reader shared by all reads on conn
 	mutex   sync.Mutex    // Mutex for thread safety
 	// connectOnce sync.Once     // Ensure single connection attempt
 	connected bool // Connection status
@@ -96,21 +97,22 @@ func (lr *LogReader) Connect() error {
 		return fmt.Errorf("failed to send authentication: %w", err)
 	}
 
-	// Read authentication response
-	response := make([]byte, 128)
+	// Read authentication response through the shared reader, so that log
+	// lines sent right after it stay buffered for later reads
+	lr.reader = bufio.NewReader(lr.conn)
 	if err := lr.conn.SetReadDeadline(time.Now().Add(lr.timeout)); err != nil {
 		lr.conn.Close()
 		return fmt.Errorf("failed to set read deadline: %w", err)
 	}
 
-	n, err := lr.conn.Read(response)
+	response, err := lr.reader.ReadString('\n')
 	if err != nil {
 		lr.conn.Close()
 		return fmt.Errorf("failed to read authentication response: %w", err)
 	}
 
 	// Check for success response (simplified - actual format may vary)
-	if !strings.Contains(string(response[:n]), "OK") {
+	if !strings.Contains(response, "OK") {
 		lr.conn.Close()
 		return errors.New("authentication failed")
 	}
@@ -148,9 +150,6 @@ func (lr *LogReader) ReadLogs(ctx context.Context) (<-chan LogEntry, error) {
 		defer close(logChan)
 		defer lr.Close()
 
-		// Create a reader for the connection
-		reader := bufio.NewReader(lr.conn)
-
 		for {
 			select {
 			case <-ctx.Done():
@@ -158,7 +157,7 @@ func (lr *LogReader) ReadLogs(ctx context.Context) (<-chan LogEntry, error) {
 				return
 			default:
 				// Read the next log entry
-				line, err := reader.ReadString('\n')
+				line, err := lr.reader.ReadString('\n')
 				if err != nil {
 					if err == io.EOF {
 						// Connection closed
@@ -216,25 +215,26 @@ func (lr *LogReader) reconnect() {
 	}
 
 	// Read authentication response
-	response := make([]byte, 128)
+	reader := bufio.NewReader(conn)
 	if err := conn.SetReadDeadline(time.Now().Add(lr.timeout)); err != nil {
 		conn.Close()
 		return
 	}
 
-	n, err := conn.Read(response)
+	response, err := reader.ReadString('\n')
 	if err != nil {
 		conn.Close()
 		return
 	}
 
 	// Check for success response
-	if !strings.Contains(string(response[:n]), "OK") {
+	if !strings.Contains(response, "OK") {
 		conn.Close()
 		return
 	}
 
 	lr.conn = conn
+	lr.reader = reader
 	lr.connected = true
 }
 
@@ -349,8 +349,7 @@ func (lr *LogReader) GetLatestAlarms(ctx context.Context, count int) ([]LogEntry
 	}
 
 	// Read response header
-	reader := bufio.NewReader(lr.conn)
-	header, err := reader.ReadString('\n')
+	header, err := lr.reader.ReadString('\n')
 	if err != nil {
 		return nil, fmt.Errorf("failed to read alarm history header: %w", err)
 	}
@@ -366,7 +365,7 @@ func (lr *LogReader) GetLatestAlarms(ctx context.Context, count int) ([]LogEntry
 	// Read alarm entries
 	alarms := make([]LogEntry, 0, numAlarms)
 	for i := 0; i < numAlarms; i++ {
-		line, err := reader.ReadString('\n')
+		line, err := lr.reader.ReadString('\n')
 		if err != nil {
 			break
 		}
@@ -425,8 +424,7 @@ func (lr *LogReader) StartRemoteLogMonitor(ctx context.Context, request RemoteLo
 	}
 
 	// Read response
-	reader := bufio.NewReader(lr.conn)
-	response, err := reader.ReadString('\n')
+	response, err := lr.reader.ReadString('\n')
 	if err != nil {
 		return nil, fmt.Errorf("failed to read monitor response: %w", err)
 	}
@@ -456,8 +454,7 @@ func (lr *LogReader) StopRemoteLogMonitor() error {
 	}
 
 	// Read response
-	reader := bufio.NewReader(lr.conn)
-	response, err := reader.ReadString('\n')
+	response, err := lr.reader.ReadString('\n')
 	if err != nil {
 		return fmt.Errorf("failed to read stop monitor response: %w", err)
 	}
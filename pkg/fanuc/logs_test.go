@@ -110,10 +110,7 @@ func TestClose(t *testing.T) {
 
 		// Wait for connection to be closed
 		buf := make([]byte, 1)
-		_, err := conn.Read(buf) // This will return when conn is closed
-		if err != nil {
-			t.Errorf("Failed to read response %v", err)
-		}
+		conn.Read(buf) // This will return when conn is closed
 	})
 	defer cleanup()
 
@@ -0,0 +1,477 @@
+package fanuc
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Program is a TP program parsed from an .LS listing
+type Program struct {
+	Name       string
+	SubType    string      // Such as "Macro", empty for a normal program
+	Attributes []Attribute // /ATTR section, in file order
+	Appl       []string    // /APPL section, kept as is
+	Lines      []Line      // /MN section; line n is Lines[n-1]
+	Positions  []ProgramPosition
+}
+
+// Attribute is one entry of the /ATTR section, such as OWNER = MNEDITOR.
+// Multi-line entries such as TCD keep their lines in Value.
+type Attribute struct {
+	Name  string
+	Value string
+}
+
+// LineKind tells what a program line holds
+type LineKind int
+
+const (
+	LineInstruction LineKind = iota // An instruction, such as "J P[1] 100% FINE"
+	LineComment                     // A comment, "!text"
+	LineRemark                      // A commented-out instruction, "//text"
+	LineBlank                       // An empty line
+)
+
+// Line is one line of the /MN section, without its number and ";"
+type Line struct {
+	Kind LineKind
+	Text string // Instruction, or text after "!" or "//"
+}
+
+// ProgramPosition is a position of the /POS section, such as P[1:"HOME"]
+type ProgramPosition struct {
+	Index   int
+	Comment string
+	Groups  []*Position // One per motion group, with Group and Frames set
+}
+
+// positionRef finds references to program positions, but not to position
+// registers
+var positionRef = regexp.MustCompile(`(?:^|[^A-Z])P\[([0-9]+)`)
+
+// PositionRefs returns the program positions an instruction uses
+func (l Line) PositionRefs() []int {
+	if l.Kind != LineInstruction {
+		return nil
+	}
+	var refs []int
+	for _, match := range positionRef.FindAllStringSubmatch(l.Text, -1) {
+		index, _ := strconv.Atoi(match[1])
+		refs = append(refs, index)
+	}
+	return refs
+}
+
+// Attribute returns the value of the named /ATTR entry
+func (p *Program) Attribute(name string) (string, bool) {
+	for _, attr := range p.Attributes {
+		if attr.Name == name {
+			return attr.Value, true
+		}
+	}
+	return "", false
+}
+
+// Position returns the program position P[index], or nil
+func (p *Program) Position(index int) *ProgramPosition {
+	for i := range p.Positions {
+		if p.Positions[i].Index == index {
+			return &p.Positions[i]
+		}
+	}
+	return nil
+}
+
+var (
+	lsProgHeader = regexp.MustCompile(`^/PROG\s+(\S+)(?:\s+(\S+))?\s*$`)
+	lsLine       = regexp.MustCompile(`^\s*([0-9]+):(.*)$`)
+	lsPosHeader  = regexp.MustCompile(`^P\[([0-9]+)(?::"([^"]*)")?\]\s*\{\s*$`)
+)
+
+// ParseLS parses an .LS program listing
+func ParseLS(r io.Reader) (*Program, error) {
+	program := &Program{}
+	section := ""
+	var pending *Line     // /MN line waiting for its ";"
+	var attr *Attribute   // /ATTR entry waiting for its ";"
+	var position []string // /POS entry up to its "};"
+	posLine := 0
+
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimRight(scanner.Text(), " \t\r")
+
+		if strings.HasPrefix(text, "/") {
+			if pending != nil || attr != nil || position != nil {
+				return nil, fmt.Errorf("line %d: %s before the end of the previous entry", line, text)
+			}
+			if match := lsProgHeader.FindStringSubmatch(text); match != nil {
+				program.Name, program.SubType = match[1], match[2]
+				section = "/PROG"
+				continue
+			}
+			switch text {
+			case "/ATTR", "/APPL", "/MN", "/POS":
+				section = text
+				continue
+			case "/END":
+				if program.Name == "" {
+					return nil, errors.New("no /PROG header")
+				}
+				return program, nil
+			}
+			return nil, fmt.Errorf("line %d: unknown section %s", line, text)
+		}
+
+		switch section {
+		case "":
+			if strings.TrimSpace(text) != "" {
+				return nil, fmt.Errorf("line %d: expected /PROG", line)
+			}
+
+		case "/ATTR":
+			if attr == nil {
+				name, value, ok := cutAttribute(text)
+				if !ok {
+					return nil, fmt.Errorf("line %d: bad attribute %q", line, text)
+				}
+				program.Attributes = append(program.Attributes, Attribute{Name: name, Value: value})
+				attr = &program.Attributes[len(program.Attributes)-1]
+			} else {
+				attr.Value += "\n" + text
+			}
+			if strings.HasSuffix(attr.Value, ";") {
+				attr.Value = strings.TrimSuffix(attr.Value, ";")
+				attr = nil
+			}
+
+		case "/APPL":
+			program.Appl = append(program.Appl, text)
+
+		case "/MN":
+			if strings.TrimSpace(text) == "" {
+				continue
+			}
+			if pending == nil {
+				match := lsLine.FindStringSubmatch(text)
+				if match == nil {
+					return nil, fmt.Errorf("line %d: expected a numbered line", line)
+				}
+				if n, _ := strconv.Atoi(match[1]); n != len(program.Lines)+1 {
+					return nil, fmt.Errorf("line %d: expected line %d, got %d", line, len(program.Lines)+1, n)
+				}
+				program.Lines = append(program.Lines, Line{Text: match[2]})
+				pending = &program.Lines[len(program.Lines)-1]
+			} else {
+				// Long lines continue on lines starting with ":"
+				rest, ok := strings.CutPrefix(strings.TrimSpace(text), ":")
+				if !ok {
+					return nil, fmt.Errorf("line %d: line %d has no ';'", line, len(program.Lines))
+				}
+				pending.Text += " " + strings.TrimSpace(rest)
+			}
+			if strings.HasSuffix(pending.Text, ";") {
+				*pending = classifyLine(strings.TrimSuffix(pending.Text, ";"))
+				pending = nil
+			}
+
+		case "/POS":
+			if strings.TrimSpace(text) == "" && position == nil {
+				continue
+			}
+			if position == nil {
+				posLine = line
+			}
+			position = append(position, strings.TrimSpace(text))
+			if strings.TrimSpace(text) == "};" {
+				parsed, err := parseLSPosition(position)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: %w", posLine, err)
+				}
+				program.Positions = append(program.Positions, *parsed)
+				position = nil
+			}
+
+		default:
+			if strings.TrimSpace(text) != "" {
+				return nil, fmt.Errorf("line %d: unexpected %q after /PROG", line, text)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, errors.New("no /END")
+}
+
+// cutAttribute splits an /ATTR line at its "=" or, for TCD, its ":"
+func cutAttribute(text string) (string, string, bool) {
+	i := strings.IndexAny(text, "=:")
+	if i < 0 {
+		return "", "", false
+	}
+	name := strings.TrimSpace(text[:i])
+	if name == "" || strings.ContainsAny(name, " \t") {
+		return "", "", false
+	}
+	if text[i] == ':' {
+		// Keep the layout of multi-line entries
+		return name, text[i+1:], true
+	}
+	return name, strings.TrimSpace(text[i+1:]), true
+}
+
+// classifyLine builds a Line from the text between the line number and ";"
+func classifyLine(text string) Line {
+	trimmed := strings.TrimSpace(text)
+	switch {
+	case trimmed == "":
+		return Line{Kind: LineBlank}
+	case strings.HasPrefix(trimmed, "!"):
+		return Line{Kind: LineComment, Text: strings.TrimPrefix(trimmed, "!")}
+	case strings.HasPrefix(trimmed, "//"):
+		return Line{Kind: LineRemark, Text: strings.TrimPrefix(trimmed, "//")}
+	default:
+		return Line{Kind: LineInstruction, Text: trimmed}
+	}
+}
+
+var (
+	lsGroup     = regexp.MustCompile(`^GP([0-9]+):$`)
+	lsFrames    = regexp.MustCompile(`\bUF\s*:\s*(-?[0-9]+)\s*,\s*UT\s*:\s*(-?[0-9]+)`)
+	lsConfig    = regexp.MustCompile(`\bCONFIG\s*:\s*'([^']*)'`)
+	lsComponent = regexp.MustCompile(`\b([XYZWPR]|[JE][0-9])\s*=\s*(-?[0-9.]+)`)
+)
+
+// parseLSPosition parses the lines of one /POS entry, from "P[n]{" to "};"
+func parseLSPosition(lines []string) (*ProgramPosition, error) {
+	match := lsPosHeader.FindStringSubmatch(lines[0])
+	if match == nil {
+		return nil, fmt.Errorf("bad position header %q", lines[0])
+	}
+	index, _ := strconv.Atoi(match[1])
+	position := &ProgramPosition{Index: index, Comment: match[2]}
+
+	// Split the entry into motion groups
+	var groups [][]string
+	var numbers []int
+	for _, line := range lines[1 : len(lines)-1] {
+		if match := lsGroup.FindStringSubmatch(line); match != nil {
+			n, _ := strconv.Atoi(match[1])
+			numbers = append(numbers, n)
+			groups = append(groups, nil)
+			continue
+		}
+		if len(groups) == 0 {
+			return nil, fmt.Errorf("P[%d]: %q before GP1:", index, line)
+		}
+		groups[len(groups)-1] = append(groups[len(groups)-1], line)
+	}
+	if len(groups) == 0 {
+		return nil, fmt.Errorf("P[%d] has no motion group", index)
+	}
+
+	for i, group := range groups {
+		p, err := parseLSGroup(strings.Join(group, " "))
+		if err != nil {
+			return nil, fmt.Errorf("P[%d] GP%d: %w", index, numbers[i], err)
+		}
+		p.Group = numbers[i]
+		position.Groups = append(position.Groups, p)
+	}
+	return position, nil
+}
+
+// parseLSGroup parses the components of one motion group of a position
+func parseLSGroup(text string) (*Position, error) {
+	position := &Position{Representation: RepresentationCartesian}
+	if match := lsFrames.FindStringSubmatch(text); match != nil {
+		uf, _ := strconv.Atoi(match[1])
+		ut, _ := strconv.Atoi(match[2])
+		position.Frames = &FrameNumbers{UF: uf, UT: ut}
+	}
+	if match := lsConfig.FindStringSubmatch(text); match != nil {
+		config, err := ParseConfiguration(match[1])
+		if err != nil {
+			return nil, err
+		}
+		position.Config = config
+	}
+
+	components := make(map[string]float32)
+	for _, match := range lsComponent.FindAllStringSubmatch(text, -1) {
+		value, err := strconv.ParseFloat(match[2], 32)
+		if err != nil {
+			return nil, fmt.Errorf("bad %s value %q", match[1], match[2])
+		}
+		components[match[1]] = float32(value)
+	}
+	reals := func(prefix string, max int) []float32 {
+		var values []float32
+		for i := 1; i <= max; i++ {
+			value, ok := components[fmt.Sprintf("%s%d", prefix, i)]
+			if !ok {
+				break
+			}
+			values = append(values, value)
+		}
+		return values
+	}
+
+	// Both representations can carry extended axes
+	position.Extensions = reals("E", maxExtensionAxes)
+
+	if joints := reals("J", maxJoints); len(joints) > 0 {
+		position.Representation = RepresentationJoint
+		position.Joints = joints
+		return position, nil
+	}
+
+	axes := make([]float32, len(positionAxes))
+	for i, axis := range positionAxes {
+		value, ok := components[axis]
+		if !ok {
+			return nil, fmt.Errorf("no %s", axis)
+		}
+		axes[i] = value
+	}
+	position.X, position.Y, position.Z = axes[0], axes[1], axes[2]
+	position.W, position.P, position.R = axes[3], axes[4], axes[5]
+	return position, nil
+}
+
+// motionInstruction matches instructions written right after the line
+// number, such as "2:J P[1] 100% FINE"
+var motionInstruction = regexp.MustCompile(`^[JLCA] `)
+
+// circularMotion splits a circular motion after its via point, such as
+// "C P[2]" and "P[4] 100mm/sec FINE"
+var circularMotion = regexp.MustCompile(`^(C (?:P|PR)\[[^\]]*\])\s+(.*)$`)
+
+// WriteLS writes the program as a canonical .LS listing: lines are
+// renumbered, long lines are not wrapped and LINE_COUNT is updated. Circular
+// motions put their end point on a ":" continuation line, as the controller
+// does. Extended axes are written in mm.
+func (p *Program) WriteLS(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+
+	if p.SubType != "" {
+		fmt.Fprintf(bw, "/PROG  %s\t  %s\n", p.Name, p.SubType)
+	} else {
+		fmt.Fprintf(bw, "/PROG  %s\n", p.Name)
+	}
+
+	fmt.Fprintln(bw, "/ATTR")
+	for _, attr := range p.Attributes {
+		value := attr.Value
+		if attr.Name == "LINE_COUNT" {
+			value = strconv.Itoa(len(p.Lines))
+		}
+		if strings.Contains(value, "\n") || strings.HasPrefix(value, " ") {
+			fmt.Fprintf(bw, "%s:%s;\n", attr.Name, value)
+			continue
+		}
+		tabs := "\t"
+		if len(attr.Name) < 8 {
+			tabs = "\t\t"
+		}
+		fmt.Fprintf(bw, "%s%s= %s;\n", attr.Name, tabs, value)
+	}
+
+	if len(p.Appl) > 0 {
+		fmt.Fprintln(bw, "/APPL")
+		for _, line := range p.Appl {
+			fmt.Fprintln(bw, line)
+		}
+	}
+
+	fmt.Fprintln(bw, "/MN")
+	for i, line := range p.Lines {
+		switch line.Kind {
+		case LineComment:
+			fmt.Fprintf(bw, "%4d:  !%s ;\n", i+1, line.Text)
+		case LineRemark:
+			fmt.Fprintf(bw, "%4d:  //%s ;\n", i+1, line.Text)
+		case LineBlank:
+			fmt.Fprintf(bw, "%4d:   ;\n", i+1)
+		default:
+			if match := circularMotion.FindStringSubmatch(line.Text); match != nil {
+				fmt.Fprintf(bw, "%4d:%s\n    :  %s    ;\n", i+1, match[1], match[2])
+			} else if motionInstruction.MatchString(line.Text) {
+				fmt.Fprintf(bw, "%4d:%s    ;\n", i+1, line.Text)
+			} else {
+				fmt.Fprintf(bw, "%4d:  %s ;\n", i+1, line.Text)
+			}
+		}
+	}
+
+	fmt.Fprintln(bw, "/POS")
+	for _, position := range p.Positions {
+		if position.Comment != "" {
+			fmt.Fprintf(bw, "P[%d:\"%s\"]{\n", position.Index, position.Comment)
+		} else {
+			fmt.Fprintf(bw, "P[%d]{\n", position.Index)
+		}
+		for _, group := range position.Groups {
+			writeLSGroup(bw, group)
+		}
+		fmt.Fprintln(bw, "};")
+	}
+
+	fmt.Fprintln(bw, "/END")
+	return bw.Flush()
+}
+
+// writeLSGroup writes one motion group of a /POS entry
+func writeLSGroup(w io.Writer, position *Position) {
+	group := position.Group
+	if group == 0 {
+		group = 1
+	}
+	fmt.Fprintf(w, "   GP%d:\n", group)
+
+	var header []string
+	if position.Frames != nil {
+		header = append(header, fmt.Sprintf("UF : %d, UT : %d,", position.Frames.UF, position.Frames.UT))
+	}
+	if position.Representation != RepresentationJoint {
+		header = append(header, fmt.Sprintf("CONFIG : '%s',", position.Config))
+	}
+	if len(header) > 0 {
+		fmt.Fprintf(w, "\t%s\n", strings.Join(header, "\t\t"))
+	}
+
+	var fields []string
+	if position.Representation == RepresentationJoint {
+		for i, joint := range position.Joints {
+			fields = append(fields, fmt.Sprintf("J%d= %9.3f deg", i+1, joint))
+		}
+	} else {
+		for i, value := range []float32{position.X, position.Y, position.Z} {
+			fields = append(fields, fmt.Sprintf("%s = %9.3f  mm", positionAxes[i], value))
+		}
+		for i, value := range []float32{position.W, position.P, position.R} {
+			fields = append(fields, fmt.Sprintf("%s = %9.3f deg", positionAxes[i+3], value))
+		}
+	}
+	for i, value := range position.Extensions {
+		fields = append(fields, fmt.Sprintf("E%d= %9.3f  mm", i+1, value))
+	}
+
+	// Three components per line, separated by commas
+	for i := 0; i < len(fields); i += 3 {
+		end := min(i+3, len(fields))
+		line := strings.Join(fields[i:end], ",\t")
+		if end < len(fields) {
+			line += ","
+		}
+		fmt.Fprintf(w, "\t%s\n", line)
+	}
+}
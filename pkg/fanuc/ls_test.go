@@ -0,0 +1,218 @@
+package fanuc
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const sampleLS = `/PROG  PICK	  Macro
+/ATTR
+OWNER		= MNEDITOR;
+COMMENT		= "Pick part";
+PROG_SIZE	= 636;
+CREATE		= DATE 24-01-15  TIME 10:20:30;
+LINE_COUNT	= 9;
+TCD:  STACK_SIZE	= 0,
+      TASK_PRIORITY	= 50,
+      TIME_SLICE	= 0;
+DEFAULT_GROUP	= 1,1,*,*,*;
+/MN
+   1:  !Move to pick ;
+   2:J P[1:HOME] 100% FINE    ;
+   3:L P[2] 500mm/sec CNT100 Offset,PR[1]    ;
+   4:  R[1]=R[1]+1    ;
+   5:   ;
+   6:  //L P[3] 100mm/sec FINE ;
+   7:  IF R[1]>5,JMP LBL[1]
+    :  ;
+   8:  CALL PLACE    ;
+   9:C P[2]
+    :  P[4] 100mm/sec FINE    ;
+/POS
+P[1:"HOME"]{
+   GP1:
+	UF : 0, UT : 1,		CONFIG : 'N U T, 0, 0, 0',
+	X =  1500.000  mm,	Y =     0.000  mm,	Z =  1200.000  mm,
+	W =   180.000 deg,	P =     0.000 deg,	R =   -90.000 deg,
+	E1=   250.000  mm
+   GP2:
+	UF : 0, UT : 1,
+	J1=    90.000 deg
+};
+P[2]{
+   GP1:
+	UF : 1, UT : 2,
+	J1=     0.000 deg,	J2=    10.000 deg,	J3=   -10.000 deg,
+	J4=     0.000 deg,	J5=   -90.000 deg,	J6=     0.000 deg
+};
+/END
+`
+
+func TestParseLS(t *testing.T) {
+	program, err := ParseLS(strings.NewReader(sampleLS))
+	if err != nil {
+		t.Fatalf("ParseLS returned error: %v", err)
+	}
+
+	if program.Name != "PICK" || program.SubType != "Macro" {
+		t.Errorf("Unexpected header %q %q", program.Name, program.SubType)
+	}
+	if comment, ok := program.Attribute("COMMENT"); !ok || comment != `"Pick part"` {
+		t.Errorf("Unexpected COMMENT %q", comment)
+	}
+	if tcd, _ := program.Attribute("TCD"); !strings.Contains(tcd, "TASK_PRIORITY\t= 50,") {
+		t.Errorf("Unexpected TCD %q", tcd)
+	}
+
+	expected := []Line{
+		{Kind: LineComment, Text: "Move to pick"},
+		{Kind: LineInstruction, Text: "J P[1:HOME] 100% FINE"},
+		{Kind: LineInstruction, Text: "L P[2] 500mm/sec CNT100 Offset,PR[1]"},
+		{Kind: LineInstruction, Text: "R[1]=R[1]+1"},
+		{Kind: LineBlank},
+		{Kind: LineRemark, Text: "L P[3] 100mm/sec FINE"},
+		{Kind: LineInstruction, Text: "IF R[1]>5,JMP LBL[1]"},
+		{Kind: LineInstruction, Text: "CALL PLACE"},
+		{Kind: LineInstruction, Text: "C P[2] P[4] 100mm/sec FINE"},
+	}
+	if !reflect.DeepEqual(program.Lines, expected) {
+		t.Errorf("Expected lines %+v, got %+v", expected, program.Lines)
+	}
+	if refs := program.Lines[2].PositionRefs(); !reflect.DeepEqual(refs, []int{2}) {
+		t.Errorf("Expected P[2] without PR[1], got %v", refs)
+	}
+	if refs := program.Lines[8].PositionRefs(); !reflect.DeepEqual(refs, []int{2, 4}) {
+		t.Errorf("Expected P[2] and P[4], got %v", refs)
+	}
+	if refs := program.Lines[5].PositionRefs(); refs != nil {
+		t.Errorf("Expected no positions in a remark, got %v", refs)
+	}
+
+	home := program.Position(1)
+	if home == nil || home.Comment != "HOME" || len(home.Groups) != 2 {
+		t.Fatalf("Unexpected P[1] %+v", home)
+	}
+	expectedHome := &Position{
+		Group:  1,
+		Frames: &FrameNumbers{UF: 0, UT: 1},
+		X:      1500, Z: 1200, W: 180, R: -90,
+		Extensions: []float32{250},
+	}
+	if !reflect.DeepEqual(home.Groups[0], expectedHome) {
+		t.Errorf("Expected %+v, got %+v", expectedHome, home.Groups[0])
+	}
+	if g2 := home.Groups[1]; g2.Group != 2 || g2.Representation != RepresentationJoint || g2.Joints[0] != 90 {
+		t.Errorf("Unexpected GP2 %+v", g2)
+	}
+	if p2 := program.Position(2).Groups[0]; len(p2.Joints) != 6 || p2.Joints[4] != -90 || p2.Frames.UT != 2 {
+		t.Errorf("Unexpected P[2] %+v", p2)
+	}
+	if program.Position(3) != nil {
+		t.Error("Expected no P[3]")
+	}
+}
+
+func TestWriteLS(t *testing.T) {
+	program, err := ParseLS(strings.NewReader(sampleLS))
+	if err != nil {
+		t.Fatalf("ParseLS returned error: %v", err)
+	}
+
+	// Offset a point and drop a line
+	program.Position(1).Groups[0].X += 10
+	program.Lines = append(program.Lines[:4], program.Lines[5:]...)
+
+	var buf bytes.Buffer
+	if err := program.WriteLS(&buf); err != nil {
+		t.Fatalf("WriteLS returned error: %v", err)
+	}
+	for _, want := range []string{
+		"/PROG  PICK\t  Macro\n",
+		"COMMENT\t\t= \"Pick part\";\n",
+		"LINE_COUNT\t= 8;\n",
+		"TCD:  STACK_SIZE\t= 0,\n      TASK_PRIORITY\t= 50,\n      TIME_SLICE\t= 0;\n",
+		"   1:  !Move to pick ;\n",
+		"   2:J P[1:HOME] 100% FINE    ;\n",
+		"   5:  //L P[3] 100mm/sec FINE ;\n",
+		"   6:  IF R[1]>5,JMP LBL[1] ;\n",
+		"   8:C P[2]\n    :  P[4] 100mm/sec FINE    ;\n",
+		"P[1:\"HOME\"]{\n   GP1:\n\tUF : 0, UT : 1,\t\tCONFIG : 'N U T, 0, 0, 0',\n",
+		"\tX =  1510.000  mm,\tY =     0.000  mm,\tZ =  1200.000  mm,\n",
+		"\tE1=   250.000  mm\n   GP2:\n",
+		"\tJ4=     0.000 deg,\tJ5=   -90.000 deg,\tJ6=     0.000 deg\n};\n/END\n",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Expected %q in\n%s", want, buf.String())
+		}
+	}
+
+	// The canonical form parses back to the same program
+	reparsed, err := ParseLS(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("Failed to parse the written program: %v", err)
+	}
+	program.Attributes[4].Value = "8"
+	if !reflect.DeepEqual(reparsed, program) {
+		t.Errorf("Expected %+v, got %+v", program, reparsed)
+	}
+}
+
+// TestLSJointExtensions tests that extended axes of a joint position survive
+// a parse and print round trip
+func TestLSJointExtensions(t *testing.T) {
+	const listing = `/PROG  RAIL
+/MN
+   1:J P[1] 100% FINE    ;
+/POS
+P[1]{
+   GP1:
+	UF : 0, UT : 1,
+	J1=    10.000 deg,	J2=    20.000 deg,	J3=    30.000 deg,
+	J4=     0.000 deg,	J5=   -90.000 deg,	J6=     0.000 deg,
+	E1=  1250.000  mm
+};
+/END
+`
+	program, err := ParseLS(strings.NewReader(listing))
+	if err != nil {
+		t.Fatalf("ParseLS returned error: %v", err)
+	}
+	p1 := program.Position(1).Groups[0]
+	if p1.Representation != RepresentationJoint || len(p1.Joints) != 6 || !reflect.DeepEqual(p1.Extensions, []float32{1250}) {
+		t.Fatalf("Unexpected P[1] %+v", p1)
+	}
+
+	var buf bytes.Buffer
+	if err := program.WriteLS(&buf); err != nil {
+		t.Fatalf("WriteLS returned error: %v", err)
+	}
+	if want := "\tJ4=     0.000 deg,\tJ5=   -90.000 deg,\tJ6=     0.000 deg,\n\tE1=  1250.000  mm\n"; !strings.Contains(buf.String(), want) {
+		t.Errorf("Expected %q in\n%s", want, buf.String())
+	}
+	reparsed, err := ParseLS(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("Failed to parse the written program: %v", err)
+	}
+	if !reflect.DeepEqual(reparsed.Positions, program.Positions) {
+		t.Errorf("Expected %+v, got %+v", program.Positions, reparsed.Positions)
+	}
+}
+
+func TestParseLSErrors(t *testing.T) {
+	for _, bad := range []string{
+		"",
+		"/PROG  X\n/MN\n",
+		"/PROG  X\n/MN\n   2:  R[1]=1 ;\n/END\n",
+		"/PROG  X\n/MN\n   1:  R[1]=1\n/POS\n/END\n",
+		"/PROG  X\n/ATTR\nbad line;\n/END\n",
+		"/PROG  X\n/POS\nP[1]{\n   GP1:\n\tX = 1 mm\n};\n/END\n",
+		"/PROG  X\n/XYZ\n/END\n",
+		"/MN\n/END\n",
+	} {
+		if _, err := ParseLS(strings.NewReader(bad)); err == nil {
+			t.Errorf("Expected error for %q", bad)
+		}
+	}
+}
@@ -0,0 +1,278 @@
+package fanuc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/carun/cpppo-go/pkg/cpppo"
+)
+
+// NumericKind tells whether a numeric register holds an integer or a real
+type NumericKind int
+
+const (
+	// NumericReal is a 32-bit floating point value
+	NumericReal NumericKind = iota
+
+	// NumericInteger is a 32-bit signed integer, as used by TP programs
+	// for counters and indexes
+	NumericInteger
+
+	// NumericUnknown is a value read through a view that converts it and
+	// does not report the stored kind, such as the CIP register objects
+	// in attribute mode. Real holds the value; it is written back as a
+	// real.
+	NumericUnknown
+)
+
+// String returns the name of the kind
+func (k NumericKind) String() string {
+	switch k {
+	case NumericReal:
+		return "real"
+	case NumericInteger:
+		return "integer"
+	case NumericUnknown:
+		return "unknown"
+	default:
+		return fmt.Sprintf("NumericKind(%d)", int(k))
+	}
+}
+
+// NumericValue is the content of an R register. The controller keeps either
+// an integer or a real in each register and switches with every write, so
+// the kind travels with the value.
+type NumericValue struct {
+	Kind NumericKind
+	Int  int32   // Value when Kind is NumericInteger
+	Real float32 // Value when Kind is NumericReal or NumericUnknown
+}
+
+// IntegerValue returns an integer numeric value
+func IntegerValue(v int32) NumericValue {
+	return NumericValue{Kind: NumericInteger, Int: v}
+}
+
+// RealValue returns a real numeric value
+func RealValue(v float32) NumericValue {
+	return NumericValue{Kind: NumericReal, Real: v}
+}
+
+// UnknownValue returns a numeric value whose stored kind is not known
+func UnknownValue(v float32) NumericValue {
+	return NumericValue{Kind: NumericUnknown, Real: v}
+}
+
+// SameKind reports whether v and other are known to hold the same kind. A
+// value of unknown kind matches either.
+func (v NumericValue) SameKind(other NumericValue) bool {
+	return v.Kind == other.Kind || v.Kind == NumericUnknown || other.Kind == NumericUnknown
+}
+
+// Float64 returns the value as a float64, which holds either kind exactly
+func (v NumericValue) Float64() float64 {
+	if v.Kind == NumericInteger {
+		return float64(v.Int)
+	}
+	return float64(v.Real)
+}
+
+// Value returns the value as an int32 or a float32 depending on its kind
+func (v NumericValue) Value() interface{} {
+	if v.Kind == NumericInteger {
+		return v.Int
+	}
+	return v.Real
+}
+
+// String formats the value the way the teach pendant shows it: integers
+// without a decimal point
+func (v NumericValue) String() string {
+	if v.Kind == NumericInteger {
+		return strconv.FormatInt(int64(v.Int), 10)
+	}
+	return strconv.FormatFloat(float64(v.Real), 'f', -1, 32)
+}
+
+// numericJSON is the JSON form of a NumericValue: {"integer": 5},
+// {"real": 1.5} or, for an unknown kind, {"number": 1.5}
+type numericJSON struct {
+	Integer *int32   `json:"integer,omitempty"`
+	Real    *float32 `json:"real,omitempty"`
+	Number  *float32 `json:"number,omitempty"`
+}
+
+// MarshalJSON encodes the value with its kind
+func (v NumericValue) MarshalJSON() ([]byte, error) {
+	switch v.Kind {
+	case NumericInteger:
+		return json.Marshal(numericJSON{Integer: &v.Int})
+	case NumericUnknown:
+		return json.Marshal(numericJSON{Number: &v.Real})
+	default:
+		return json.Marshal(numericJSON{Real: &v.Real})
+	}
+}
+
+// UnmarshalJSON decodes a value encoded by MarshalJSON
+func (v *NumericValue) UnmarshalJSON(data []byte) error {
+	var j numericJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	switch {
+	case j.Integer != nil && j.Real == nil && j.Number == nil:
+		*v = IntegerValue(*j.Integer)
+	case j.Real != nil && j.Integer == nil && j.Number == nil:
+		*v = RealValue(*j.Real)
+	case j.Number != nil && j.Integer == nil && j.Real == nil:
+		*v = UnknownValue(*j.Number)
+	default:
+		return errors.New("numeric value needs exactly one of integer, real or number")
+	}
+	return nil
+}
+
+// dataType returns the CIP data type that stores the value's kind
+func (v NumericValue) dataType() byte {
+	if v.Kind == NumericInteger {
+		return cpppo.CIPDataTypeDINT
+	}
+	return cpppo.CIPDataTypeREAL
+}
+
+// numericValueOf converts a value returned by a read into a NumericValue
+func numericValueOf(value interface{}) (NumericValue, error) {
+	switch v := value.(type) {
+	case int32:
+		return IntegerValue(v), nil
+	case float32:
+		return RealValue(v), nil
+	default:
+		return NumericValue{}, fmt.Errorf("unexpected numeric register value %T", value)
+	}
+}
+
+// toNumericValue converts a value written to an R register. A NumericValue
+// or float32 is taken as is and any Go integer becomes an integer.
+func toNumericValue(value interface{}) (NumericValue, error) {
+	switch v := value.(type) {
+	case NumericValue:
+		return v, nil
+	case float32:
+		return RealValue(v), nil
+	}
+
+	n, err := toInt32(value)
+	if err != nil {
+		return NumericValue{}, err
+	}
+	return IntegerValue(n), nil
+}
+
+// ReadNumericRegister reads an R register and reports whether it holds an
+// integer or a real. In attribute mode the controller does not report the
+// stored kind, so the value is NumericUnknown.
+func (f *FanucClient) ReadNumericRegister(index int) (NumericValue, error) {
+	if f.Access == AccessAttribute && f.numericClass(NumericReal) != 0 {
+		return f.readNumericAttribute(index)
+	}
+
+	value, err := f.PLCClient.ReadTag(buildRegisterTag(RegisterTypeR, index), cpppo.CIPDataTypeREAL)
+	if err != nil {
+		value, err = storedNumeric(err)
+	}
+	if err != nil {
+		return NumericValue{}, err
+	}
+
+	return numericValueOf(value)
+}
+
+// storedNumeric returns the integer an R register replied with when it was
+// read as a REAL, and err otherwise. The reply names the type actually
+// stored and carries the value, so the register need not be read again.
+func storedNumeric(err error) (interface{}, error) {
+	var mismatch cpppo.DataTypeMismatchError
+	if errors.As(err, &mismatch) && mismatch.Actual == cpppo.CIPDataTypeDINT {
+		return mismatch.Value()
+	}
+	return nil, err
+}
+
+// WriteNumericRegister writes an R register with the kind of value given, so
+// that an integer stays an integer on the controller
+func (f *FanucClient) WriteNumericRegister(index int, value NumericValue) error {
+	dataType := value.dataType()
+
+	if f.Access == AccessAttribute {
+		if class := f.numericClass(value.Kind); class != 0 {
+			return f.writeNumericAttribute(class, index, dataType, value.Value())
+		}
+	}
+
+	return f.PLCClient.WriteTag(buildRegisterTag(RegisterTypeR, index), dataType, value.Value())
+}
+
+// numericClass returns the CIP class holding R registers viewed as kind, or
+// zero if that view is not configured
+func (f *FanucClient) numericClass(kind NumericKind) uint16 {
+	classes := f.objectClasses()
+	if kind == NumericInteger {
+		return classes.Integer
+	}
+	return classes.Real
+}
+
+// readNumericAttribute reads an R register through its real CIP object.
+// The controller converts a register to whichever view is read and does not
+// report the stored kind, so a whole number may be a real as well as an
+// integer and the kind is reported as unknown.
+func (f *FanucClient) readNumericAttribute(index int) (NumericValue, error) {
+	value, err := f.readNumericView(f.numericClass(NumericReal), index, cpppo.CIPDataTypeREAL)
+	if err != nil {
+		return NumericValue{}, err
+	}
+	return UnknownValue(value.(float32)), nil
+}
+
+// readNumericView reads one view of an R register
+func (f *FanucClient) readNumericView(class uint16, index int, dataType byte) (interface{}, error) {
+	client, err := f.attributeClient()
+	if err != nil {
+		return nil, err
+	}
+	attribute, err := registerAttributeID(index)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := client.GetAttributeSingle(class, registerInstance, attribute)
+	if err != nil {
+		return nil, err
+	}
+
+	return cpppo.DecodeValue(dataType, data)
+}
+
+// writeNumericAttribute writes an R register through the CIP object of one
+// view, which also sets the stored kind
+func (f *FanucClient) writeNumericAttribute(class uint16, index int, dataType byte, value interface{}) error {
+	client, err := f.attributeClient()
+	if err != nil {
+		return err
+	}
+	attribute, err := registerAttributeID(index)
+	if err != nil {
+		return err
+	}
+
+	data, err := cpppo.EncodeValue(dataType, value)
+	if err != nil {
+		return err
+	}
+
+	return client.SetAttributeSingle(class, registerInstance, attribute, data)
+}
@@ -0,0 +1,119 @@
+package fanuc
+
+import (
+	"testing"
+
+	"github.com/carun/cpppo-go/pkg/cpppo"
+)
+
+// typedTag is a tag value together with the data type the controller stores
+type typedTag struct {
+	dataType byte
+	value    interface{}
+}
+
+// typedTagClient answers reads like a controller: a read with the wrong data
+// type fails with the stored type
+type typedTagClient struct {
+	tags  map[string]typedTag
+	reads int
+}
+
+func (c *typedTagClient) ReadTag(tagName string, dataType byte) (interface{}, error) {
+	c.reads++
+	tag := c.tags[tagName]
+	if tag.dataType != dataType {
+		data, err := cpppo.EncodeValue(tag.dataType, tag.value)
+		if err != nil {
+			return nil, err
+		}
+		return nil, cpppo.DataTypeMismatchError{Expected: dataType, Actual: tag.dataType, Data: data}
+	}
+	return tag.value, nil
+}
+
+func (c *typedTagClient) WriteTag(tagName string, dataType byte, value interface{}) error {
+	c.tags[tagName] = typedTag{dataType, value}
+	return nil
+}
+
+func (c *typedTagClient) Close() error {
+	return nil
+}
+
+// TestNumericRegisterSymbolic tests that R registers keep their kind
+func TestNumericRegisterSymbolic(t *testing.T) {
+	mock := &typedTagClient{tags: map[string]typedTag{}}
+	client := &FanucClient{PLCClient: mock}
+
+	if err := client.WriteRegister(RegisterTypeR, 1, 3); err != nil {
+		t.Fatalf("Failed to write integer: %v", err)
+	}
+	if err := client.WriteNumericRegister(2, RealValue(2.5)); err != nil {
+		t.Fatalf("Failed to write real: %v", err)
+	}
+	if mock.tags["R[1]"] != (typedTag{cpppo.CIPDataTypeDINT, int32(3)}) {
+		t.Errorf("Expected R[1] to be written as DINT 3, got %+v", mock.tags["R[1]"])
+	}
+
+	value, err := client.ReadNumericRegister(1)
+	if err != nil || value != IntegerValue(3) || value.String() != "3" {
+		t.Errorf("Expected integer 3, got %v (%v)", value, err)
+	}
+	if mock.reads != 1 {
+		t.Errorf("Expected the integer decoded from the first reply, got %d reads", mock.reads)
+	}
+	value, err = client.ReadNumericRegister(2)
+	if err != nil || value != RealValue(2.5) || value.String() != "2.5" {
+		t.Errorf("Expected real 2.5, got %v (%v)", value, err)
+	}
+
+	// ReadRegister returns the stored kind, ReadRRegister always a float32
+	if v, err := client.ReadRegister(RegisterTypeR, 1); err != nil || v != int32(3) {
+		t.Errorf("Expected int32(3), got %#v (%v)", v, err)
+	}
+	if v, err := client.ReadRRegister(1); err != nil || v != 3 {
+		t.Errorf("Expected 3, got %v (%v)", v, err)
+	}
+}
+
+// TestNumericRegisterAttribute tests R registers through the integer and
+// real objects
+func TestNumericRegisterAttribute(t *testing.T) {
+	mock := newMockPLCClient()
+	client := &FanucClient{PLCClient: mock, Access: AccessAttribute}
+
+	// Integers are written through the integer object
+	if err := client.WriteNumericRegister(1, IntegerValue(16777217)); err != nil {
+		t.Fatalf("Failed to write integer: %v", err)
+	}
+	if data := mock.attributes[attributeKey{0x6B, 1, 1}]; string(data) != string(cpppo.EncodeInt32(16777217)) {
+		t.Errorf("Expected R[1] written as DINT 16777217, got %v", data)
+	}
+
+	// The objects do not report the stored kind: a whole number may be a
+	// real, so it is not reported as an integer
+	mock.attributes[attributeKey{0x6C, 1, 1}] = cpppo.EncodeFloat32(10)
+	mock.attributes[attributeKey{0x6B, 1, 1}] = cpppo.EncodeInt32(10)
+	mock.attributes[attributeKey{0x6C, 1, 2}] = cpppo.EncodeFloat32(0.25)
+
+	value, err := client.ReadNumericRegister(1)
+	if err != nil || value != UnknownValue(10) || value.String() != "10" {
+		t.Errorf("Expected 10 of unknown kind, got %v (%v)", value, err)
+	}
+	value, err = client.ReadNumericRegister(2)
+	if err != nil || value != UnknownValue(0.25) {
+		t.Errorf("Expected 0.25 of unknown kind, got %v (%v)", value, err)
+	}
+	if len(mock.readCalls) != 0 {
+		t.Errorf("Expected no symbolic reads, got %v", mock.readCalls)
+	}
+
+	// A value of unknown kind is written back through the real object
+	if err := client.WriteNumericRegister(3, UnknownValue(1.5)); err != nil {
+		t.Fatalf("Failed to write unknown kind: %v", err)
+	}
+	if data := mock.attributes[attributeKey{0x6C, 1, 3}]; string(data) != string(cpppo.EncodeFloat32(1.5)) {
+		t.Errorf("Expected R[3] written as REAL 1.5, got %v", data)
+	}
+}
@@ -0,0 +1,170 @@
+package fanuc
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/carun/cpppo-go/pkg/cpppo"
+)
+
+// AccessMode selects how FanucClient addresses registers on the controller
+type AccessMode int
+
+const (
+	// AccessSymbolic reads and writes registers as symbolic tags such as
+	// "R[1]", for gateways and simulators that publish them by name
+	AccessSymbolic AccessMode = iota
+
+	// AccessAttribute reads and writes registers through FANUC's
+	// vendor-specific CIP objects with Get/Set Attribute Single. R and SR
+	// registers are attributes of instance 1, numbered by register index;
+	// position registers are attributes of the motion group's instance,
+	// numbered by PR index. This is what R-30iB controllers expose over
+	// EtherNet/IP explicit messaging.
+	AccessAttribute
+)
+
+// String returns the name of the access mode
+func (m AccessMode) String() string {
+	switch m {
+	case AccessSymbolic:
+		return "symbolic"
+	case AccessAttribute:
+		return "attribute"
+	default:
+		return fmt.Sprintf("AccessMode(%d)", int(m))
+	}
+}
+
+// ObjectClasses are the vendor-specific CIP classes used in AccessAttribute
+// mode. A register type whose class is zero falls back to symbolic tags.
+type ObjectClasses struct {
+	Real     uint16 // R registers as REAL
+	Integer  uint16 // R registers as DINT
+	Position uint16 // PR registers in Cartesian representation
+	Joint    uint16 // PR registers in joint representation
+	String   uint16 // SR registers
+
+	CurrentPosition uint16 // Current Cartesian position (CURPOS)
+	CurrentJoints   uint16 // Current joint position (CURJPOS)
+}
+
+// DefaultObjectClasses are the classes used by R-30iB controllers
+var DefaultObjectClasses = ObjectClasses{
+	Real:     0x6C,
+	Integer:  0x6B,
+	Position: 0x7B,
+	Joint:    0x7C,
+	String:   0x6D,
+
+	CurrentPosition: 0x7D,
+	CurrentJoints:   0x7E,
+}
+
+// registerInstance is the instance of the R and SR objects. Each register is
+// the attribute numbered by its index. Position objects use the motion group
+// as the instance instead.
+const registerInstance = 1
+
+// registerAttributeID returns the attribute addressing register index in an
+// object, where attribute numbers are 16 bits
+func registerAttributeID(index int) (uint16, error) {
+	if index < 1 || index > math.MaxUint16 {
+		return 0, fmt.Errorf("register index %d out of range 1-%d for attribute access", index, math.MaxUint16)
+	}
+	return uint16(index), nil
+}
+
+// AttributeClient is implemented by PLC clients that can address CIP
+// objects directly, such as cpppo.PLCClient
+type AttributeClient interface {
+	GetAttributeSingle(class uint16, instance uint32, attribute uint16) ([]byte, error)
+	SetAttributeSingle(class uint16, instance uint32, attribute uint16, data []byte) error
+}
+
+var _ AttributeClient = (*cpppo.PLCClient)(nil)
+
+// AttributeBatchClient is implemented by PLC clients that can read several
+// CIP attributes in one request, such as cpppo.PLCClient
+type AttributeBatchClient interface {
+	GetAttributesSingle(refs []cpppo.AttributeRef) ([]cpppo.AttributeResult, error)
+}
+
+var _ AttributeBatchClient = (*cpppo.PLCClient)(nil)
+
+// ErrAttributeAccessUnsupported is returned in AccessAttribute mode when the
+// PLC client cannot address CIP objects
+var ErrAttributeAccessUnsupported = errors.New("PLC client does not support attribute access")
+
+// objectClasses returns the configured classes, or the defaults if none are set
+func (f *FanucClient) objectClasses() ObjectClasses {
+	if f.Classes == (ObjectClasses{}) {
+		return DefaultObjectClasses
+	}
+	return f.Classes
+}
+
+// registerClass returns the CIP class holding registers of regType, or zero
+// if they are accessed symbolically. R registers have two classes and are
+// handled by numericClass.
+func (f *FanucClient) registerClass(regType RegisterType) uint16 {
+	if f.Access != AccessAttribute {
+		return 0
+	}
+
+	classes := f.objectClasses()
+	switch regType {
+	case RegisterTypeSR:
+		return classes.String
+	default:
+		return 0
+	}
+}
+
+// attributeClient returns the PLC client as an AttributeClient
+func (f *FanucClient) attributeClient() (AttributeClient, error) {
+	client, ok := f.PLCClient.(AttributeClient)
+	if !ok {
+		return nil, ErrAttributeAccessUnsupported
+	}
+	return client, nil
+}
+
+// readRegisterAttribute reads a register through its CIP object
+func (f *FanucClient) readRegisterAttribute(class uint16, regType RegisterType, index int) (interface{}, error) {
+	client, err := f.attributeClient()
+	if err != nil {
+		return nil, err
+	}
+	attribute, err := registerAttributeID(index)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := client.GetAttributeSingle(class, registerInstance, attribute)
+	if err != nil {
+		return nil, err
+	}
+
+	return cpppo.DecodeValue(getRegisterDataType(regType), data)
+}
+
+// writeRegisterAttribute writes a register through its CIP object
+func (f *FanucClient) writeRegisterAttribute(class uint16, regType RegisterType, index int, value interface{}) error {
+	client, err := f.attributeClient()
+	if err != nil {
+		return err
+	}
+	attribute, err := registerAttributeID(index)
+	if err != nil {
+		return err
+	}
+
+	data, err := cpppo.EncodeValue(getRegisterDataType(regType), value)
+	if err != nil {
+		return err
+	}
+
+	return client.SetAttributeSingle(class, registerInstance, attribute, data)
+}
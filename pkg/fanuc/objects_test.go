@@ -0,0 +1,185 @@
+package fanuc
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/carun/cpppo-go/pkg/cpppo"
+)
+
+// symbolicOnlyClient hides the attribute methods of the wrapped client
+type symbolicOnlyClient struct {
+	PLCClientInterface
+}
+
+// TestReadRegisterAttributeAccess tests register reads through CIP objects
+func TestReadRegisterAttributeAccess(t *testing.T) {
+	mock := newMockPLCClient()
+	client := &FanucClient{PLCClient: mock, Access: AccessAttribute}
+
+	mock.attributes[attributeKey{0x6C, 1, 5}] = cpppo.EncodeFloat32(42.5)
+	mock.attributes[attributeKey{0x6D, 1, 2}], _ = cpppo.EncodeValue(cpppo.CIPDataTypeSTRING, "PART_A")
+	mock.readResponses["DI[3]"] = true
+
+	value, err := client.ReadRegister(RegisterTypeR, 5)
+	if err != nil || value != float32(42.5) {
+		t.Errorf("Expected R[5] = 42.5, got %v (%v)", value, err)
+	}
+
+	value, err = client.ReadRegister(RegisterTypeSR, 2)
+	if err != nil || value != "PART_A" {
+		t.Errorf("Expected SR[2] = PART_A, got %v (%v)", value, err)
+	}
+
+	// Types without an object class fall back to symbolic tags
+	value, err = client.ReadRegister(RegisterTypeDI, 3)
+	if err != nil || value != true {
+		t.Errorf("Expected DI[3] = true, got %v (%v)", value, err)
+	}
+
+	if len(mock.readCalls) != 1 || mock.readCalls["DI[3]"] != 1 {
+		t.Errorf("Expected only DI[3] to be read symbolically, got %v", mock.readCalls)
+	}
+}
+
+// TestWriteRegisterAttributeAccess tests register writes through CIP objects
+// with configured classes
+func TestWriteRegisterAttributeAccess(t *testing.T) {
+	mock := newMockPLCClient()
+	client := &FanucClient{
+		PLCClient: mock,
+		Access:    AccessAttribute,
+		Classes:   ObjectClasses{Real: 0x80},
+	}
+
+	if err := client.WriteRRegister(7, 1.5); err != nil {
+		t.Fatalf("Failed to write R register: %v", err)
+	}
+	if value, err := cpppo.DecodeFloat32(mock.attributes[attributeKey{0x80, 1, 7}]); err != nil || value != 1.5 {
+		t.Errorf("Expected class 0x80 attribute 7 = 1.5, got %v (%v)", value, err)
+	}
+
+	// A zero class in a custom set falls back to symbolic tags
+	if err := client.WriteRegister(RegisterTypeSR, 1, "TEXT"); err != nil {
+		t.Fatalf("Failed to write SR register: %v", err)
+	}
+	if mock.writeCalls["SR[1]"] != "TEXT" {
+		t.Errorf("Expected SR[1] to be written symbolically, got %v", mock.writeCalls)
+	}
+}
+
+// TestPositionRegisterAttributeAccess tests the position record round trip
+func TestPositionRegisterAttributeAccess(t *testing.T) {
+	mock := newMockPLCClient()
+	client := &FanucClient{PLCClient: mock, Access: AccessAttribute}
+
+	// Existing record in user frame 2, tool frame 3
+	record := make([]byte, cartesianRecordHeader+3*4)
+	record[0], record[4] = 3, 2
+	mock.attributes[attributeKey{0x7B, 1, 4}] = record
+
+	position := &Position{
+		X: 100.5, Y: -200.25, Z: 300, W: 180, P: 0, R: -90,
+		Config:     Configuration{Flip: true, Down: true, Back: true, Turns: [3]int8{1, 0, -1}},
+		Extensions: []float32{10},
+	}
+	if err := client.WritePositionRegister(4, position); err != nil {
+		t.Fatalf("Failed to write position register: %v", err)
+	}
+
+	written := mock.attributes[attributeKey{0x7B, 1, 4}]
+	if written[0] != 3 || written[4] != 2 {
+		t.Errorf("Expected frames UT 3, UF 2 to be kept, got %v", written[0:8])
+	}
+
+	got, err := client.ReadPositionRegister(4)
+	if err != nil {
+		t.Fatalf("Failed to read position register: %v", err)
+	}
+
+	expected := *position
+	expected.Group = 1
+	expected.Frames = &FrameNumbers{UF: 2, UT: 3}
+	expected.Extensions = []float32{10, 0, 0}
+	if !reflect.DeepEqual(*got, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, *got)
+	}
+	if len(mock.readCalls) != 0 {
+		t.Errorf("Expected no symbolic reads, got %v", mock.readCalls)
+	}
+}
+
+// TestAttributePaths tests the class, instance and attribute segments sent
+// for each kind of object
+func TestAttributePaths(t *testing.T) {
+	mock := newMockPLCClient()
+	batch := &attributeBatchClient{mockPLCClient: mock}
+	client := &FanucClient{PLCClient: batch, Access: AccessAttribute}
+
+	// path returns the path of the request sent i-th
+	path := func(i int) []byte {
+		if i >= len(mock.requests) {
+			return nil
+		}
+		request := mock.requests[i]
+		return request[2 : 2+2*int(request[1])]
+	}
+
+	tests := []struct {
+		name string
+		run  func() error
+		path []byte // Class, instance and attribute segments
+	}{
+		{"R[5]", func() error { _, err := client.ReadRegister(RegisterTypeR, 5); return err },
+			[]byte{0x20, 0x6C, 0x24, 0x01, 0x30, 0x05}},
+		{"R[7] as DINT", func() error { return client.WriteNumericRegister(7, IntegerValue(1)) },
+			[]byte{0x20, 0x6B, 0x24, 0x01, 0x30, 0x07}},
+		{"SR[300]", func() error { _, err := client.ReadRegister(RegisterTypeSR, 300); return err },
+			[]byte{0x20, 0x6D, 0x24, 0x01, 0x31, 0x00, 0x2C, 0x01}},
+		{"PR[GP2:5]", func() error { _, err := client.ReadPositionRegisterAs(5, 2, RepresentationCartesian); return err },
+			[]byte{0x20, 0x7B, 0x24, 0x02, 0x30, 0x05}},
+		{"PR[4] in joints", func() error { _, err := client.ReadPositionRegisterAs(4, 1, RepresentationJoint); return err },
+			[]byte{0x20, 0x7C, 0x24, 0x01, 0x30, 0x04}},
+		{"CURJPOS of group 2", func() error { _, err := client.CurrentJoints(2); return err },
+			[]byte{0x20, 0x7E, 0x24, 0x02, 0x30, 0x01}},
+		{"R[9] in a batch", func() error { _, err := client.ReadRegisterSet(RegisterSet{{RegisterTypeR, 9}}); return err },
+			[]byte{0x20, 0x6C, 0x24, 0x01, 0x30, 0x09}},
+	}
+	for _, tc := range tests {
+		mock.requests = nil
+		tc.run() // The objects are empty; only the request matters
+		if got := path(0); !reflect.DeepEqual(got, tc.path) {
+			t.Errorf("%s: expected path % X, got % X", tc.name, tc.path, got)
+		}
+	}
+
+	// Attribute numbers are 16 bits
+	mock.requests = nil
+	if _, err := client.ReadRegister(RegisterTypeR, 70000); err == nil {
+		t.Error("Expected error for R[70000]")
+	}
+	if err := client.WriteStringRegister(0x10000, "X"); err == nil {
+		t.Error("Expected error for SR[65536]")
+	}
+	results, err := client.ReadRegisterSet(RegisterSet{{RegisterTypeSR, 70000}})
+	if err != nil || results[0].Err == nil {
+		t.Errorf("Expected an error result for SR[70000], got %+v (%v)", results, err)
+	}
+	if len(mock.requests) != 0 {
+		t.Errorf("Expected nothing to be sent, got % X", mock.requests)
+	}
+}
+
+// TestAttributeAccessUnsupported tests attribute mode with a client that
+// cannot address CIP objects
+func TestAttributeAccessUnsupported(t *testing.T) {
+	client := &FanucClient{
+		PLCClient: symbolicOnlyClient{newMockPLCClient()},
+		Access:    AccessAttribute,
+	}
+
+	if _, err := client.ReadRegister(RegisterTypeR, 1); !errors.Is(err, ErrAttributeAccessUnsupported) {
+		t.Errorf("Expected ErrAttributeAccessUnsupported, got %v", err)
+	}
+}
@@ -0,0 +1,628 @@
+package fanuc
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/carun/cpppo-go/pkg/cpppo"
+)
+
+// Representation tells how a position register holds its position
+type Representation int
+
+const (
+	// RepresentationCartesian is a tool center point position (XYZWPR),
+	// robot configuration and extended axes
+	RepresentationCartesian Representation = iota
+
+	// RepresentationJoint is a set of joint angles (J1-J9)
+	RepresentationJoint
+)
+
+// String returns the name of the representation
+func (r Representation) String() string {
+	switch r {
+	case RepresentationCartesian:
+		return "cartesian"
+	case RepresentationJoint:
+		return "joint"
+	default:
+		return fmt.Sprintf("Representation(%d)", int(r))
+	}
+}
+
+// MarshalText encodes the representation by name
+func (r Representation) MarshalText() ([]byte, error) {
+	switch r {
+	case RepresentationCartesian, RepresentationJoint:
+		return []byte(r.String()), nil
+	default:
+		return nil, fmt.Errorf("unknown representation %d", int(r))
+	}
+}
+
+// UnmarshalText decodes a representation encoded by MarshalText
+func (r *Representation) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "cartesian":
+		*r = RepresentationCartesian
+	case "joint":
+		*r = RepresentationJoint
+	default:
+		return fmt.Errorf("unknown representation %q", text)
+	}
+	return nil
+}
+
+// Position register limits
+const (
+	maxMotionGroups  = 8 // Motion groups on one controller
+	maxExtensionAxes = 6 // Extended axes of a Cartesian position
+	maxJoints        = 9 // Joints of a joint position
+)
+
+// FrameNumbers are the user and tool frames a position was taught in
+type FrameNumbers struct {
+	UF int // User frame number
+	UT int // User tool number
+}
+
+// Position represents the contents of a position register for one motion
+// group, in either Cartesian or joint representation
+type Position struct {
+	Representation Representation // Which of the fields below hold the position
+	Group          int            // Motion group, 0 means group 1
+
+	// Frames are the register's frame numbers. Reads fill them in when the
+	// controller reports them; a write with nil Frames keeps the register's
+	// current frames.
+	Frames *FrameNumbers
+
+	X, Y, Z    float32       // Cartesian coordinates
+	W, P, R    float32       // Wrist orientation (W/P/R format)
+	Config     Configuration // Robot configuration
+	Extensions []float32     // Additional axes (E1 onwards)
+
+	Joints []float32 // Joint angles (J1 onwards) in joint representation
+}
+
+// validate checks that a position can be written and returns its group
+func (p *Position) validate() (int, error) {
+	if p == nil {
+		return 0, errors.New("no position to write")
+	}
+	group := p.Group
+	if group == 0 {
+		group = 1
+	}
+	if group < 1 || group > maxMotionGroups {
+		return 0, fmt.Errorf("motion group %d out of range 1-%d", p.Group, maxMotionGroups)
+	}
+
+	switch p.Representation {
+	case RepresentationCartesian:
+		if err := p.Config.Validate(); err != nil {
+			return 0, fmt.Errorf("invalid configuration: %w", err)
+		}
+		if len(p.Extensions) > maxExtensionAxes {
+			return 0, fmt.Errorf("position has %d extension axes, at most %d are supported", len(p.Extensions), maxExtensionAxes)
+		}
+		if err := checkFinite(append([]float32{p.X, p.Y, p.Z, p.W, p.P, p.R}, p.Extensions...)); err != nil {
+			return 0, fmt.Errorf("invalid position: %w", err)
+		}
+	case RepresentationJoint:
+		if len(p.Joints) == 0 || len(p.Joints) > maxJoints {
+			return 0, fmt.Errorf("joint position needs 1-%d joints, got %d", maxJoints, len(p.Joints))
+		}
+		if err := checkFinite(p.Joints); err != nil {
+			return 0, fmt.Errorf("invalid joint position: %w", err)
+		}
+	default:
+		return 0, fmt.Errorf("unknown representation %v", p.Representation)
+	}
+
+	return group, nil
+}
+
+// checkFinite returns an error if a value is NaN or infinite, which the
+// controller would store as is
+func checkFinite(values []float32) error {
+	for _, v := range values {
+		if math.IsNaN(float64(v)) || math.IsInf(float64(v), 0) {
+			return fmt.Errorf("component %v is not a finite number", v)
+		}
+	}
+	return nil
+}
+
+// positionTag returns the tag name of a position register, such as "PR[1]"
+// for group 1 or "PR[GP2:1]" for other groups
+func positionTag(group, index int) string {
+	if group <= 1 {
+		return fmt.Sprintf("PR[%d]", index)
+	}
+	return fmt.Sprintf("PR[GP%d:%d]", group, index)
+}
+
+// positionAxes are the Cartesian components of a position register, in the
+// order of the Position fields
+var positionAxes = []string{"X", "Y", "Z", "W", "P", "R"}
+
+// ReadPositionRegister reads a position register (PR) of motion group 1 in
+// Cartesian representation
+func (f *FanucClient) ReadPositionRegister(index int) (*Position, error) {
+	return f.ReadPositionRegisterAs(index, 1, RepresentationCartesian)
+}
+
+// ReadPositionRegisterAs reads a position register of a motion group in the
+// given representation. The controller converts between representations, so
+// a register taught in joints can be read as Cartesian and the other way round.
+func (f *FanucClient) ReadPositionRegisterAs(index, group int, repr Representation) (*Position, error) {
+	if group < 1 || group > maxMotionGroups {
+		return nil, fmt.Errorf("motion group %d out of range 1-%d", group, maxMotionGroups)
+	}
+
+	// The position object returns every component in one record
+	if class := f.positionClass(repr); class != 0 {
+		return f.readPositionAttribute(class, index, group, repr)
+	}
+
+	return f.readPositionTags(index, group, repr)
+}
+
+// Position register types, as the TYPE member of a position register and
+// KAREL's POS_REG_TYPE report them
+const (
+	posRegTypeXYZWPR    = 2
+	posRegTypeXYZWPRExt = 6
+	posRegTypeJoint     = 9
+)
+
+// cipStatusObjectStateConflict is the status the position objects answer
+// when a register is stored in the other representation
+const cipStatusObjectStateConflict = 0x0C
+
+// PositionRepresentation returns the representation a position register of
+// a motion group is stored in, which is the one it was taught or last
+// written in
+func (f *FanucClient) PositionRepresentation(index, group int) (Representation, error) {
+	if group < 1 || group > maxMotionGroups {
+		return 0, fmt.Errorf("motion group %d out of range 1-%d", group, maxMotionGroups)
+	}
+
+	// The position objects have no TYPE member; the record is read from the
+	// object of the stored representation
+	if f.positionClass(RepresentationCartesian) != 0 {
+		position, err := f.readPositionNative(index, group)
+		if err != nil {
+			return 0, err
+		}
+		return position.Representation, nil
+	}
+
+	tagName := positionTag(group, index) + ".TYPE"
+	value, err := f.PLCClient.ReadTag(tagName, cpppo.CIPDataTypeDINT)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s: %w", tagName, err)
+	}
+	posType, ok := value.(int32)
+	if !ok {
+		return 0, fmt.Errorf("unexpected type %T for %s", value, tagName)
+	}
+
+	switch posType {
+	case posRegTypeXYZWPR, posRegTypeXYZWPRExt:
+		return RepresentationCartesian, nil
+	case posRegTypeJoint:
+		return RepresentationJoint, nil
+	default:
+		return 0, fmt.Errorf("unsupported position type %d for %s", posType, positionTag(group, index))
+	}
+}
+
+// ReadPositionRegisterNative reads a position register of a motion group in
+// the representation it is stored in, so writing the result back leaves the
+// register as it was
+func (f *FanucClient) ReadPositionRegisterNative(index, group int) (*Position, error) {
+	if f.positionClass(RepresentationCartesian) != 0 {
+		if group < 1 || group > maxMotionGroups {
+			return nil, fmt.Errorf("motion group %d out of range 1-%d", group, maxMotionGroups)
+		}
+		return f.readPositionNative(index, group)
+	}
+
+	repr, err := f.PositionRepresentation(index, group)
+	if err != nil {
+		return nil, err
+	}
+	return f.ReadPositionRegisterAs(index, group, repr)
+}
+
+// readPositionNative reads a position register through the Cartesian object,
+// and through the joint object if the controller answers that the register
+// holds joints
+func (f *FanucClient) readPositionNative(index, group int) (*Position, error) {
+	position, err := f.readPositionAttribute(f.positionClass(RepresentationCartesian), index, group, RepresentationCartesian)
+	var cipErr cpppo.CIPError
+	if !errors.As(err, &cipErr) || cipErr.Code != cipStatusObjectStateConflict {
+		return position, err
+	}
+	return f.ReadPositionRegisterAs(index, group, RepresentationJoint)
+}
+
+// WritePositionRegister writes a Position to a position register (PR), in
+// the position's representation and motion group
+func (f *FanucClient) WritePositionRegister(index int, position *Position) error {
+	group, err := position.validate()
+	if err != nil {
+		return err
+	}
+
+	// The position object takes every component in one record
+	if class := f.positionClass(position.Representation); class != 0 {
+		return f.writePositionAttribute(class, index, group, position)
+	}
+
+	return f.writePositionTags(index, group, position)
+}
+
+// positionClass returns the CIP class holding position registers in the
+// given representation, or zero if they are accessed symbolically
+func (f *FanucClient) positionClass(repr Representation) uint16 {
+	if f.Access != AccessAttribute {
+		return 0
+	}
+
+	classes := f.objectClasses()
+	if repr == RepresentationJoint {
+		return classes.Joint
+	}
+	return classes.Position
+}
+
+// readPositionTags reads a position register as symbolic tags, one for each
+// component. Extended axes, further joints and frame numbers depend on the
+// controller, so failures to read them are not errors.
+func (f *FanucClient) readPositionTags(index, group int, repr Representation) (*Position, error) {
+	base := positionTag(group, index)
+
+	var reads []cpppo.TagRead
+	add := func(member string, dataType byte) {
+		reads = append(reads, cpppo.TagRead{Name: base + "." + member, DataType: dataType})
+	}
+	if repr == RepresentationJoint {
+		for i := 1; i <= maxJoints; i++ {
+			add(fmt.Sprintf("J%d", i), cpppo.CIPDataTypeREAL)
+		}
+	} else {
+		for _, axis := range positionAxes {
+			add(axis, cpppo.CIPDataTypeREAL)
+		}
+		add("Config", cpppo.CIPDataTypeSTRING)
+		for i := 1; i <= maxExtensionAxes; i++ {
+			add(fmt.Sprintf("E%d", i), cpppo.CIPDataTypeREAL)
+		}
+	}
+	add("UF", cpppo.CIPDataTypeDINT)
+	add("UT", cpppo.CIPDataTypeDINT)
+
+	results, err := f.readComponents(reads)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", base, err)
+	}
+
+	// Index the results by member name
+	members := make(map[string]cpppo.TagResult, len(reads))
+	for i, read := range reads {
+		members[strings.TrimPrefix(read.Name, base+".")] = results[i]
+	}
+
+	required := func(member string) (interface{}, error) {
+		result := members[member]
+		if result.Err != nil {
+			return nil, fmt.Errorf("failed to read PR %s component: %w", member, result.Err)
+		}
+		return result.Value, nil
+	}
+	requiredReal := func(member string) (float32, error) {
+		value, err := required(member)
+		if err != nil {
+			return 0, err
+		}
+		floatValue, ok := value.(float32)
+		if !ok {
+			return 0, fmt.Errorf("unexpected type %T for PR %s component", value, member)
+		}
+		return floatValue, nil
+	}
+	// optionalReals returns prefix1, prefix2, ... up to the first that fails
+	optionalReals := func(prefix string, from, to int) []float32 {
+		values := []float32{}
+		for i := from; i <= to; i++ {
+			result := members[fmt.Sprintf("%s%d", prefix, i)]
+			value, ok := result.Value.(float32)
+			if result.Err != nil || !ok {
+				break
+			}
+			values = append(values, value)
+		}
+		return values
+	}
+
+	position := &Position{Representation: repr, Group: group}
+
+	if repr == RepresentationJoint {
+		j1, err := requiredReal("J1")
+		if err != nil {
+			return nil, err
+		}
+		position.Joints = append([]float32{j1}, optionalReals("J", 2, maxJoints)...)
+	} else {
+		axes := make([]float32, len(positionAxes))
+		for i, axis := range positionAxes {
+			if axes[i], err = requiredReal(axis); err != nil {
+				return nil, err
+			}
+		}
+		position.X, position.Y, position.Z = axes[0], axes[1], axes[2]
+		position.W, position.P, position.R = axes[3], axes[4], axes[5]
+
+		config, err := required("Config")
+		if err != nil {
+			return nil, err
+		}
+		configValue, ok := config.(string)
+		if !ok {
+			return nil, fmt.Errorf("unexpected type %T for PR Config component", config)
+		}
+		if position.Config, err = ParseConfiguration(configValue); err != nil {
+			return nil, fmt.Errorf("failed to parse PR Config component: %w", err)
+		}
+		position.Extensions = optionalReals("E", 1, maxExtensionAxes)
+	}
+
+	uf, ufOK := members["UF"].Value.(int32)
+	ut, utOK := members["UT"].Value.(int32)
+	if ufOK && utOK && members["UF"].Err == nil && members["UT"].Err == nil {
+		position.Frames = &FrameNumbers{UF: int(uf), UT: int(ut)}
+	}
+
+	return position, nil
+}
+
+// writePositionTags writes a position register as symbolic tags, one for
+// each component
+func (f *FanucClient) writePositionTags(index, group int, position *Position) error {
+	base := positionTag(group, index)
+
+	var writes []cpppo.TagWrite
+	add := func(member string, dataType byte, value interface{}) {
+		writes = append(writes, cpppo.TagWrite{Name: base + "." + member, DataType: dataType, Value: value})
+	}
+	if position.Representation == RepresentationJoint {
+		for i, joint := range position.Joints {
+			add(fmt.Sprintf("J%d", i+1), cpppo.CIPDataTypeREAL, joint)
+		}
+	} else {
+		values := []float32{position.X, position.Y, position.Z, position.W, position.P, position.R}
+		for i, axis := range positionAxes {
+			add(axis, cpppo.CIPDataTypeREAL, values[i])
+		}
+		add("Config", cpppo.CIPDataTypeSTRING, position.Config.String())
+		for i, ext := range position.Extensions {
+			add(fmt.Sprintf("E%d", i+1), cpppo.CIPDataTypeREAL, ext)
+		}
+	}
+	if position.Frames != nil {
+		add("UF", cpppo.CIPDataTypeDINT, int32(position.Frames.UF))
+		add("UT", cpppo.CIPDataTypeDINT, int32(position.Frames.UT))
+	}
+
+	errs, err := f.writeComponents(writes)
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", base, err)
+	}
+	for i, err := range errs {
+		if err != nil {
+			member := strings.TrimPrefix(writes[i].Name, base+".")
+			return fmt.Errorf("failed to write PR %s component: %w", member, err)
+		}
+	}
+
+	return nil
+}
+
+// readComponents reads several tags in one request when the client can
+// batch, so all values come from the same controller scan, and one by one
+// otherwise
+func (f *FanucClient) readComponents(reads []cpppo.TagRead) ([]cpppo.TagResult, error) {
+	if batch, ok := f.PLCClient.(BatchClient); ok {
+		results, err := batch.ReadTags(reads)
+		if err == nil && len(results) != len(reads) {
+			err = fmt.Errorf("expected %d results, got %d", len(reads), len(results))
+		}
+		return results, err
+	}
+
+	results := make([]cpppo.TagResult, len(reads))
+	for i, read := range reads {
+		results[i].Value, results[i].Err = f.PLCClient.ReadTag(read.Name, read.DataType)
+	}
+	return results, nil
+}
+
+// writeComponents writes several tags in one request when the client can
+// batch, so nobody observes a half-written position, and one by one
+// otherwise, stopping at the first failure
+func (f *FanucClient) writeComponents(writes []cpppo.TagWrite) ([]error, error) {
+	if batch, ok := f.PLCClient.(BatchClient); ok {
+		errs, err := batch.WriteTags(writes)
+		if err == nil && len(errs) != len(writes) {
+			err = fmt.Errorf("expected %d results, got %d", len(writes), len(errs))
+		}
+		return errs, err
+	}
+
+	errs := make([]error, len(writes))
+	for i, write := range writes {
+		if errs[i] = f.PLCClient.WriteTag(write.Name, write.DataType, write.Value); errs[i] != nil {
+			break
+		}
+	}
+	return errs, nil
+}
+
+// Position records exchanged with the position objects start with the tool
+// and user frame numbers (DINT each). A Cartesian record continues with
+// X, Y, Z, W, P, R (REAL each), the configuration flags and three turn
+// numbers (one byte each), and the extended axes (REAL each). A joint record
+// continues with the joint angles (REAL each). The number of extended axes or
+// joints follows from the record length.
+const (
+	frameRecordLength     = 8
+	cartesianRecordHeader = 36
+)
+
+// readPositionAttribute reads a position register through its CIP object.
+// The motion group is the instance and the register index the attribute.
+func (f *FanucClient) readPositionAttribute(class uint16, index, group int, repr Representation) (*Position, error) {
+	client, err := f.attributeClient()
+	if err != nil {
+		return nil, err
+	}
+	attribute, err := registerAttributeID(index)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := client.GetAttributeSingle(class, uint32(group), attribute)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", positionTag(group, index), err)
+	}
+
+	position, err := decodePositionRecord(data, repr)
+	if err != nil {
+		return nil, err
+	}
+	position.Group = group
+	return position, nil
+}
+
+// writePositionAttribute writes a position register through its CIP object.
+// Frames, extended axes and joints the position leaves out keep their
+// current values.
+func (f *FanucClient) writePositionAttribute(class uint16, index, group int, position *Position) error {
+	client, err := f.attributeClient()
+	if err != nil {
+		return err
+	}
+	attribute, err := registerAttributeID(index)
+	if err != nil {
+		return err
+	}
+
+	current, err := client.GetAttributeSingle(class, uint32(group), attribute)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", positionTag(group, index), err)
+	}
+
+	data, err := encodePositionRecord(position, current)
+	if err != nil {
+		return err
+	}
+
+	if err := client.SetAttributeSingle(class, uint32(group), attribute, data); err != nil {
+		return fmt.Errorf("failed to write %s: %w", positionTag(group, index), err)
+	}
+	return nil
+}
+
+// decodePositionRecord parses a position record in the given representation
+func decodePositionRecord(data []byte, repr Representation) (*Position, error) {
+	header := cartesianRecordHeader
+	if repr == RepresentationJoint {
+		header = frameRecordLength + 4
+	}
+	if len(data) < header || (len(data)-frameRecordLength)%4 != 0 {
+		return nil, fmt.Errorf("invalid position record length: %d bytes", len(data))
+	}
+
+	realAt := func(offset int) float32 {
+		return math.Float32frombits(binary.LittleEndian.Uint32(data[offset:]))
+	}
+	reals := func(from int) []float32 {
+		values := []float32{}
+		for offset := from; offset+4 <= len(data); offset += 4 {
+			values = append(values, realAt(offset))
+		}
+		return values
+	}
+
+	position := &Position{
+		Representation: repr,
+		Frames: &FrameNumbers{
+			UT: int(int32(binary.LittleEndian.Uint32(data[0:]))),
+			UF: int(int32(binary.LittleEndian.Uint32(data[4:]))),
+		},
+	}
+
+	if repr == RepresentationJoint {
+		position.Joints = reals(frameRecordLength)
+		return position, nil
+	}
+
+	position.X, position.Y, position.Z = realAt(8), realAt(12), realAt(16)
+	position.W, position.P, position.R = realAt(20), realAt(24), realAt(28)
+	position.Config = decodeConfiguration(data[32:36])
+	position.Extensions = reals(cartesianRecordHeader)
+
+	return position, nil
+}
+
+// encodePositionRecord builds a position record from position, starting from
+// the register's current record so omitted fields keep their values
+func encodePositionRecord(position *Position, current []byte) ([]byte, error) {
+	header := cartesianRecordHeader
+	if position.Representation == RepresentationJoint {
+		header = frameRecordLength
+	}
+	if len(current) < header || (len(current)-frameRecordLength)%4 != 0 {
+		return nil, fmt.Errorf("invalid position record length: %d bytes", len(current))
+	}
+
+	data := make([]byte, len(current))
+	copy(data, current)
+
+	if position.Frames != nil {
+		binary.LittleEndian.PutUint32(data[0:], uint32(int32(position.Frames.UT)))
+		binary.LittleEndian.PutUint32(data[4:], uint32(int32(position.Frames.UF)))
+	}
+
+	putReals := func(offset int, values []float32) error {
+		if offset+4*len(values) > len(data) {
+			return fmt.Errorf("position register holds %d values, got %d", (len(data)-offset)/4, len(values))
+		}
+		for i, v := range values {
+			binary.LittleEndian.PutUint32(data[offset+4*i:], math.Float32bits(v))
+		}
+		return nil
+	}
+
+	if position.Representation == RepresentationJoint {
+		if err := putReals(frameRecordLength, position.Joints); err != nil {
+			return nil, err
+		}
+		return data, nil
+	}
+
+	putReals(frameRecordLength, []float32{position.X, position.Y, position.Z, position.W, position.P, position.R})
+	copy(data[32:36], position.Config.encode())
+	if err := putReals(cartesianRecordHeader, position.Extensions); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
@@ -0,0 +1,151 @@
+package fanuc
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+)
+
+// TestJointPositionRegisterTags tests joint positions of another motion
+// group as symbolic tags
+func TestJointPositionRegisterTags(t *testing.T) {
+	mock := newMockPLCClient()
+	client := &FanucClient{PLCClient: mock}
+
+	for i, joint := range []float32{10, 20, 30, 40, 50, 60, 70} {
+		mock.readResponses[buildJointTag(2, 3, i+1)] = joint
+	}
+	mock.readResponses["PR[GP2:3].UF"] = int32(1)
+	mock.readResponses["PR[GP2:3].UT"] = int32(4)
+
+	position, err := client.ReadPositionRegisterAs(3, 2, RepresentationJoint)
+	if err != nil {
+		t.Fatalf("Failed to read joint position: %v", err)
+	}
+
+	expected := &Position{
+		Representation: RepresentationJoint,
+		Group:          2,
+		Frames:         &FrameNumbers{UF: 1, UT: 4},
+		Joints:         []float32{10, 20, 30, 40, 50, 60, 70},
+	}
+	if !reflect.DeepEqual(position, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, position)
+	}
+
+	position.Joints = []float32{1, 2, 3, 4}
+	position.Frames = &FrameNumbers{UF: 5, UT: 6}
+	if err := client.WritePositionRegister(8, position); err != nil {
+		t.Fatalf("Failed to write joint position: %v", err)
+	}
+	if mock.writeCalls["PR[GP2:8].J4"] != float32(4) || mock.writeCalls["PR[GP2:8].UF"] != int32(5) {
+		t.Errorf("Unexpected writes %v", mock.writeCalls)
+	}
+	if _, ok := mock.writeCalls["PR[GP2:8].X"]; ok {
+		t.Error("Expected no Cartesian components in a joint write")
+	}
+}
+
+// buildJointTag returns the tag name of one joint of a position register
+func buildJointTag(group, index, joint int) string {
+	return fmt.Sprintf("%s.J%d", positionTag(group, index), joint)
+}
+
+// TestCartesianExtendedAxes tests more than three extended axes
+func TestCartesianExtendedAxes(t *testing.T) {
+	mock := newMockPLCClient()
+	client := &FanucClient{PLCClient: mock}
+
+	position := &Position{Extensions: []float32{1, 2, 3, 4, 5}}
+	if err := client.WritePositionRegister(1, position); err != nil {
+		t.Fatalf("Failed to write position: %v", err)
+	}
+	if mock.writeCalls["PR[1].E5"] != float32(5) {
+		t.Errorf("Expected E5 to be written, got %v", mock.writeCalls)
+	}
+	if _, ok := mock.writeCalls["PR[1].UF"]; ok {
+		t.Error("Expected frames to be left alone when Frames is nil")
+	}
+
+	for _, axis := range positionAxes {
+		mock.readResponses["PR[1]."+axis] = float32(0)
+	}
+	mock.readResponses["PR[1].Config"] = "N U T, 0, 0, 0"
+	for i := 1; i <= 5; i++ {
+		mock.readResponses[fmt.Sprintf("PR[1].E%d", i)] = float32(i)
+	}
+	read, err := client.ReadPositionRegister(1)
+	if err != nil {
+		t.Fatalf("Failed to read position: %v", err)
+	}
+	if !reflect.DeepEqual(read.Extensions, []float32{1, 2, 3, 4, 5}) {
+		t.Errorf("Expected 5 extended axes, got %v", read.Extensions)
+	}
+}
+
+// TestJointPositionRegisterAttribute tests joint records, with the motion
+// group as the instance
+func TestJointPositionRegisterAttribute(t *testing.T) {
+	mock := newMockPLCClient()
+	client := &FanucClient{PLCClient: mock, Access: AccessAttribute}
+
+	// Six joints at 90 degrees in user frame 1, tool frame 2
+	record := make([]byte, frameRecordLength+6*4)
+	binary.LittleEndian.PutUint32(record[0:], 2)
+	binary.LittleEndian.PutUint32(record[4:], 1)
+	for offset := frameRecordLength; offset < len(record); offset += 4 {
+		binary.LittleEndian.PutUint32(record[offset:], math.Float32bits(90))
+	}
+	mock.attributes[attributeKey{0x7C, 2, 5}] = record
+
+	// Only the first two joints are given; the rest keep their values
+	position := &Position{Representation: RepresentationJoint, Group: 2, Joints: []float32{0, 45}}
+	if err := client.WritePositionRegister(5, position); err != nil {
+		t.Fatalf("Failed to write joint position: %v", err)
+	}
+
+	read, err := client.ReadPositionRegisterAs(5, 2, RepresentationJoint)
+	if err != nil {
+		t.Fatalf("Failed to read joint position: %v", err)
+	}
+	if !reflect.DeepEqual(read.Joints, []float32{0, 45, 90, 90, 90, 90}) {
+		t.Errorf("Unexpected joints %v", read.Joints)
+	}
+	if *read.Frames != (FrameNumbers{UF: 1, UT: 2}) || read.Group != 2 {
+		t.Errorf("Unexpected frames %+v or group %d", *read.Frames, read.Group)
+	}
+
+	// More joints than the register holds
+	position.Joints = make([]float32, 7)
+	if err := client.WritePositionRegister(5, position); err == nil {
+		t.Error("Expected error writing more joints than the register holds")
+	}
+}
+
+// TestPositionValidation tests positions that cannot be written
+func TestPositionValidation(t *testing.T) {
+	client := &FanucClient{PLCClient: newMockPLCClient()}
+
+	invalid := []*Position{
+		{Group: maxMotionGroups + 1},
+		{Group: -1},
+		{Representation: RepresentationJoint},
+		{Representation: RepresentationJoint, Joints: make([]float32, maxJoints+1)},
+		{Extensions: make([]float32, maxExtensionAxes+1)},
+		{Representation: Representation(7)},
+		{X: float32(math.NaN())},
+		{Representation: RepresentationJoint, Joints: []float32{float32(math.Inf(1))}},
+		nil,
+	}
+	for _, position := range invalid {
+		if err := client.WritePositionRegister(1, position); err == nil {
+			t.Errorf("Expected error writing %+v", position)
+		}
+	}
+
+	if _, err := client.ReadPositionRegisterAs(1, 0, RepresentationCartesian); err == nil {
+		t.Error("Expected error reading motion group 0")
+	}
+}
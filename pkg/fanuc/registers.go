@@ -3,6 +3,7 @@ package fanuc
 import (
 	"errors"
 	"fmt"
+	"math"
 	"time"
 
 	"github.com/carun/cpppo-go/pkg/cpppo"
@@ -24,6 +25,16 @@ const (
 	RegisterTypeUR                     // UR registers (for user frame registers)
 	RegisterTypeSR                     // SR registers (for string registers)
 	RegisterTypeVR                     // VR registers (for vision registers)
+	RegisterTypeUI                     // UI signals (for UOP inputs)
+	RegisterTypeUO                     // UO signals (for UOP outputs)
+	RegisterTypeSI                     // SI signals (for operator panel inputs)
+	RegisterTypeSO                     // SO signals (for operator panel outputs)
+	RegisterTypeRI                     // RI signals (for robot inputs)
+	RegisterTypeRO                     // RO signals (for robot outputs)
+	RegisterTypeWI                     // WI signals (for weld inputs)
+	RegisterTypeWO                     // WO signals (for weld outputs)
+	RegisterTypeF                      // F flags (for internal flags)
+	RegisterTypeM                      // M markers (for logic markers)
 )
 
 // PLCClientInterface defines the interface for PLC client implementations
@@ -33,9 +44,33 @@ type PLCClientInterface interface {
 	Close() error
 }
 
+// BatchClient is implemented by PLC clients that can read or write several
+// tags in one request, such as cpppo.PLCClient
+type BatchClient interface {
+	ReadTags(reads []cpppo.TagRead) ([]cpppo.TagResult, error)
+	WriteTags(writes []cpppo.TagWrite) ([]error, error)
+}
+
+var _ BatchClient = (*cpppo.PLCClient)(nil)
+
 // FanucClient extends the PLC client with Fanuc-specific functionality
 type FanucClient struct {
 	PLCClient PLCClientInterface
+
+	// Access selects symbolic tags (the default) or vendor-specific CIP
+	// objects for register access. Classes overrides the object classes used
+	// in AccessAttribute mode; the zero value means DefaultObjectClasses.
+	Access  AccessMode
+	Classes ObjectClasses
+
+	// SysVarWrites lists the system variables WriteSysVar, frame writes and
+	// snapshot restores may change. It is empty by default, which refuses
+	// every write.
+	SysVarWrites SysVarAllowList
+
+	// RemoteTiming sets the pulse length and acknowledgement timeout of UOP
+	// remote control; the zero value means DefaultRemoteTiming.
+	RemoteTiming RemoteTiming
 }
 
 // NewFanucClient creates a new Fanuc client
@@ -80,6 +115,26 @@ func buildRegisterTag(regType RegisterType, index int) string {
 		return fmt.Sprintf("SR[%d]", index)
 	case RegisterTypeVR:
 		return fmt.Sprintf("VR[%d]", index)
+	case RegisterTypeUI:
+		return fmt.Sprintf("UI[%d]", index)
+	case RegisterTypeUO:
+		return fmt.Sprintf("UO[%d]", index)
+	case RegisterTypeSI:
+		return fmt.Sprintf("SI[%d]", index)
+	case RegisterTypeSO:
+		return fmt.Sprintf("SO[%d]", index)
+	case RegisterTypeRI:
+		return fmt.Sprintf("RI[%d]", index)
+	case RegisterTypeRO:
+		return fmt.Sprintf("RO[%d]", index)
+	case RegisterTypeWI:
+		return fmt.Sprintf("WI[%d]", index)
+	case RegisterTypeWO:
+		return fmt.Sprintf("WO[%d]", index)
+	case RegisterTypeF:
+		return fmt.Sprintf("F[%d]", index)
+	case RegisterTypeM:
+		return fmt.Sprintf("M[%d]", index)
 	default:
 		return fmt.Sprintf("R[%d]", index)
 	}
@@ -94,17 +149,23 @@ func getRegisterDataType(regType RegisterType) byte {
 		// Position registers are complex structures
 		// For simplicity, we'll use string to get raw data
 		return cpppo.CIPDataTypeSTRING
-	case RegisterTypeDI, RegisterTypeDO, RegisterTypeGI, RegisterTypeGO:
+	case RegisterTypeDI, RegisterTypeDO, RegisterTypeUI, RegisterTypeUO,
+		RegisterTypeSI, RegisterTypeSO, RegisterTypeRI, RegisterTypeRO,
+		RegisterTypeWI, RegisterTypeWO, RegisterTypeF, RegisterTypeM:
+		// Single bit signals, flags and markers
 		return cpppo.CIPDataTypeBOOL
+	case RegisterTypeGI, RegisterTypeGO:
+		// Group I/O packs several bits into one integer
+		return cpppo.CIPDataTypeDINT
 	case RegisterTypeAI, RegisterTypeAO:
 		return cpppo.CIPDataTypeREAL
 	case RegisterTypeUR:
-		// User frames are complex structures
+		// User frames are structures, read component by component as Frame
 		return cpppo.CIPDataTypeSTRING
 	case RegisterTypeSR:
 		return cpppo.CIPDataTypeSTRING
 	case RegisterTypeVR:
-		// Vision registers can be complex
+		// Vision registers are structures, read member by member as VisionResult
 		return cpppo.CIPDataTypeSTRING
 	default:
 		return cpppo.CIPDataTypeREAL
@@ -124,90 +185,39 @@ func (f *FanucClient) ReadRegister(regType RegisterType, index int) (interface{}
 		return f.ReadPositionRegister(index)
 	}
 
-	// Read the register using the PLC client
-	return f.PLCClient.ReadTag(tagName, dataType)
-}
-
-// Position represents a position in Cartesian space
-type Position struct {
-	X, Y, Z    float32   // Cartesian coordinates
-	W, P, R    float32   // Wrist orientation (W/P/R format)
-	Config     string    // Robot configuration
-	Extensions []float32 // Additional axes
-}
-
-// ReadPositionRegister reads a position register (PR) and returns structured data
-func (f *FanucClient) ReadPositionRegister(index int) (*Position, error) {
-	// Position registers have multiple components
-	// We need to read each component separately
-
-	// Read X component
-	xValue, err := f.PLCClient.ReadTag(fmt.Sprintf("PR[%d].X", index), cpppo.CIPDataTypeREAL)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read PR X component: %w", err)
-	}
-
-	// Read Y component
-	yValue, err := f.PLCClient.ReadTag(fmt.Sprintf("PR[%d].Y", index), cpppo.CIPDataTypeREAL)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read PR Y component: %w", err)
-	}
-
-	// Read Z component
-	zValue, err := f.PLCClient.ReadTag(fmt.Sprintf("PR[%d].Z", index), cpppo.CIPDataTypeREAL)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read PR Z component: %w", err)
+	// UR registers are the user frames of group 1
+	if regType == RegisterTypeUR {
+		return f.ReadUserFrame(1, index)
 	}
 
-	// Read W component
-	wValue, err := f.PLCClient.ReadTag(fmt.Sprintf("PR[%d].W", index), cpppo.CIPDataTypeREAL)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read PR W component: %w", err)
+	// VR registers hold iRVision results
+	if regType == RegisterTypeVR {
+		return f.ReadVisionRegister(index)
 	}
 
-	// Read P component
-	pValue, err := f.PLCClient.ReadTag(fmt.Sprintf("PR[%d].P", index), cpppo.CIPDataTypeREAL)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read PR P component: %w", err)
-	}
-
-	// Read R component
-	rValue, err := f.PLCClient.ReadTag(fmt.Sprintf("PR[%d].R", index), cpppo.CIPDataTypeREAL)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read PR R component: %w", err)
-	}
-
-	// Read config string
-	configValue, err := f.PLCClient.ReadTag(fmt.Sprintf("PR[%d].Config", index), cpppo.CIPDataTypeSTRING)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read PR Config component: %w", err)
+	// Numeric registers hold either an integer or a real
+	if regType == RegisterTypeR {
+		value, err := f.ReadNumericRegister(index)
+		if err != nil {
+			return nil, err
+		}
+		return value.Value(), nil
 	}
 
-	// Create position struct
-	position := &Position{
-		X:      xValue.(float32),
-		Y:      yValue.(float32),
-		Z:      zValue.(float32),
-		W:      wValue.(float32),
-		P:      pValue.(float32),
-		R:      rValue.(float32),
-		Config: configValue.(string),
+	// Read through the register's CIP object when one is configured
+	var value interface{}
+	var err error
+	if class := f.registerClass(regType); class != 0 {
+		value, err = f.readRegisterAttribute(class, regType, index)
+	} else {
+		value, err = f.PLCClient.ReadTag(tagName, dataType)
 	}
 
-	// Try to read extension axes if they exist
-	// This is controller-dependent, so we'll try E1-E3 and ignore errors
-	extensions := []float32{}
-
-	for i := 1; i <= 3; i++ {
-		eValue, err := f.PLCClient.ReadTag(fmt.Sprintf("PR[%d].E%d", index, i), cpppo.CIPDataTypeREAL)
-		if err == nil {
-			extensions = append(extensions, eValue.(float32))
-		}
+	// String registers are checked on read as on write
+	if regType == RegisterTypeSR {
+		return storedString(value, err)
 	}
-
-	position.Extensions = extensions
-
-	return position, nil
+	return value, err
 }
 
 // WriteRegister writes a value to a Fanuc register
@@ -227,85 +237,64 @@ func (f *FanucClient) WriteRegister(regType RegisterType, index int, value inter
 		return f.WritePositionRegister(index, pos)
 	}
 
-	// Write the register using the PLC client
-	return f.PLCClient.WriteTag(tagName, dataType, value)
-}
-
-// WritePositionRegister writes a Position to a position register (PR)
-func (f *FanucClient) WritePositionRegister(index int, position *Position) error {
-	// Write each component separately
-
-	// Write X component
-	err := f.PLCClient.WriteTag(fmt.Sprintf("PR[%d].X", index), cpppo.CIPDataTypeREAL, position.X)
-	if err != nil {
-		return fmt.Errorf("failed to write PR X component: %w", err)
-	}
-
-	// Write Y component
-	err = f.PLCClient.WriteTag(fmt.Sprintf("PR[%d].Y", index), cpppo.CIPDataTypeREAL, position.Y)
-	if err != nil {
-		return fmt.Errorf("failed to write PR Y component: %w", err)
-	}
-
-	// Write Z component
-	err = f.PLCClient.WriteTag(fmt.Sprintf("PR[%d].Z", index), cpppo.CIPDataTypeREAL, position.Z)
-	if err != nil {
-		return fmt.Errorf("failed to write PR Z component: %w", err)
-	}
-
-	// Write W component
-	err = f.PLCClient.WriteTag(fmt.Sprintf("PR[%d].W", index), cpppo.CIPDataTypeREAL, position.W)
-	if err != nil {
-		return fmt.Errorf("failed to write PR W component: %w", err)
-	}
-
-	// Write P component
-	err = f.PLCClient.WriteTag(fmt.Sprintf("PR[%d].P", index), cpppo.CIPDataTypeREAL, position.P)
-	if err != nil {
-		return fmt.Errorf("failed to write PR P component: %w", err)
+	// UR registers are user frames and need a SysVarWrites entry
+	if regType == RegisterTypeUR {
+		frame, ok := value.(*Frame)
+		if !ok {
+			return errors.New("value must be a Frame for UR registers")
+		}
+		return f.WriteUserFrame(1, index, frame)
 	}
 
-	// Write R component
-	err = f.PLCClient.WriteTag(fmt.Sprintf("PR[%d].R", index), cpppo.CIPDataTypeREAL, position.R)
-	if err != nil {
-		return fmt.Errorf("failed to write PR R component: %w", err)
+	if regType == RegisterTypeVR {
+		return fmt.Errorf("%s: %w", tagName, ErrVisionRegisterReadOnly)
 	}
 
-	// Write Config
-	err = f.PLCClient.WriteTag(fmt.Sprintf("PR[%d].Config", index), cpppo.CIPDataTypeSTRING, position.Config)
-	if err != nil {
-		return fmt.Errorf("failed to write PR Config component: %w", err)
+	// Numeric registers keep the kind of value written: integers stay integers
+	if regType == RegisterTypeR {
+		v, err := toNumericValue(value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", tagName, err)
+		}
+		return f.WriteNumericRegister(index, v)
 	}
 
-	// Write extension axes if they exist
-	for i, ext := range position.Extensions {
-		if i >= 3 {
-			break // Only support up to 3 extension axes
+	// String registers have a fixed capacity
+	if regType == RegisterTypeSR {
+		if err := checkStringRegister(value); err != nil {
+			return fmt.Errorf("%s: %w", tagName, err)
 		}
+	}
 
-		err = f.PLCClient.WriteTag(fmt.Sprintf("PR[%d].E%d", index, i+1), cpppo.CIPDataTypeREAL, ext)
+	// Accept any Go integer for integer registers such as GO
+	if dataType == cpppo.CIPDataTypeDINT {
+		v, err := toInt32(value)
 		if err != nil {
-			return fmt.Errorf("failed to write PR E%d component: %w", i+1, err)
+			return fmt.Errorf("%s: %w", tagName, err)
 		}
+		value = v
+	}
+
+	// Write through the register's CIP object when one is configured
+	if class := f.registerClass(regType); class != 0 {
+		return f.writeRegisterAttribute(class, regType, index, value)
 	}
 
-	return nil
+	// Write the register using the PLC client
+	return f.PLCClient.WriteTag(tagName, dataType, value)
 }
 
-// ReadRRegister is a convenience method to read an R register
+// ReadRRegister is a convenience method to read an R register as a float32.
+// Use ReadNumericRegister to tell integers from reals.
 func (f *FanucClient) ReadRRegister(index int) (float32, error) {
-	value, err := f.ReadRegister(RegisterTypeR, index)
+	value, err := f.ReadNumericRegister(index)
 	if err != nil {
 		return 0, err
 	}
-	floatVal, ok := value.(float32)
-	if !ok {
-		return 0, errors.New("failed to convert value to float32")
-	}
-	return floatVal, nil
+	return float32(value.Float64()), nil
 }
 
-// WriteRRegister is a convenience method to write an R register
+// WriteRRegister is a convenience method to write a real to an R register
 func (f *FanucClient) WriteRRegister(index int, value float32) error {
 	return f.WriteRegister(RegisterTypeR, index, value)
 }
@@ -327,3 +316,50 @@ func (f *FanucClient) ReadDIRegister(index int) (bool, error) {
 func (f *FanucClient) WriteDORegister(index int, value bool) error {
 	return f.WriteRegister(RegisterTypeDO, index, value)
 }
+
+// ReadGIRegister is a convenience method to read a GI register
+func (f *FanucClient) ReadGIRegister(index int) (int32, error) {
+	value, err := f.ReadRegister(RegisterTypeGI, index)
+	if err != nil {
+		return 0, err
+	}
+	intVal, ok := value.(int32)
+	if !ok {
+		return 0, errors.New("failed to convert value to int32")
+	}
+	return intVal, nil
+}
+
+// WriteGORegister is a convenience method to write a GO register
+func (f *FanucClient) WriteGORegister(index int, value int32) error {
+	return f.WriteRegister(RegisterTypeGO, index, value)
+}
+
+// toInt32 converts a Go integer to int32, rejecting values out of range
+func toInt32(value interface{}) (int32, error) {
+	var v int64
+	switch n := value.(type) {
+	case int32:
+		return n, nil
+	case int:
+		v = int64(n)
+	case int8:
+		v = int64(n)
+	case int16:
+		v = int64(n)
+	case int64:
+		v = n
+	case uint8:
+		v = int64(n)
+	case uint16:
+		v = int64(n)
+	case uint32:
+		v = int64(n)
+	default:
+		return 0, fmt.Errorf("value is not an integer: %T", value)
+	}
+	if v < math.MinInt32 || v > math.MaxInt32 {
+		return 0, fmt.Errorf("value %d out of range for DINT", v)
+	}
+	return int32(v), nil
+}
@@ -1,6 +1,7 @@
 package fanuc
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/carun/cpppo-go/pkg/cpppo"
@@ -12,15 +13,25 @@ type mockPLCClient struct {
 	writeResponses map[string]error
 	readCalls      map[string]int
 	writeCalls     map[string]interface{}
+	attributes     map[attributeKey][]byte
+	requests       [][]byte // Encoded Get and Set Attribute Single requests
 	closed         bool
 }
 
+// attributeKey addresses one attribute of a CIP object instance
+type attributeKey struct {
+	class     uint16
+	instance  uint32
+	attribute uint16
+}
+
 func newMockPLCClient() *mockPLCClient {
 	return &mockPLCClient{
 		readResponses:  make(map[string]interface{}),
 		writeResponses: make(map[string]error),
 		readCalls:      make(map[string]int),
 		writeCalls:     make(map[string]interface{}),
+		attributes:     make(map[attributeKey][]byte),
 	}
 }
 
@@ -40,6 +51,21 @@ func (m *mockPLCClient) WriteTag(tagName string, dataType byte, value interface{
 	return nil
 }
 
+func (m *mockPLCClient) GetAttributeSingle(class uint16, instance uint32, attribute uint16) ([]byte, error) {
+	m.requests = append(m.requests, cpppo.BuildCIPGetAttributeSingleRequest(class, instance, attribute))
+	data, ok := m.attributes[attributeKey{class, instance, attribute}]
+	if !ok {
+		return nil, cpppo.CIPError{Code: 0x05, ExtendedMsg: "Path destination unknown"}
+	}
+	return data, nil
+}
+
+func (m *mockPLCClient) SetAttributeSingle(class uint16, instance uint32, attribute uint16, data []byte) error {
+	m.requests = append(m.requests, cpppo.BuildCIPSetAttributeSingleRequest(class, instance, attribute, data))
+	m.attributes[attributeKey{class, instance, attribute}] = data
+	return nil
+}
+
 func (m *mockPLCClient) Close() error {
 	m.closed = true
 	return nil
@@ -63,6 +89,16 @@ func TestBuildRegisterTag(t *testing.T) {
 		{RegisterTypeUR, 600, "UR[600]"},
 		{RegisterTypeSR, 700, "SR[700]"},
 		{RegisterTypeVR, 800, "VR[800]"},
+		{RegisterTypeUI, 6, "UI[6]"},
+		{RegisterTypeUO, 1, "UO[1]"},
+		{RegisterTypeSI, 2, "SI[2]"},
+		{RegisterTypeSO, 3, "SO[3]"},
+		{RegisterTypeRI, 4, "RI[4]"},
+		{RegisterTypeRO, 5, "RO[5]"},
+		{RegisterTypeWI, 7, "WI[7]"},
+		{RegisterTypeWO, 8, "WO[8]"},
+		{RegisterTypeF, 9, "F[9]"},
+		{RegisterTypeM, 10, "M[10]"},
 	}
 
 	for _, tc := range tests {
@@ -86,11 +122,21 @@ func TestGetRegisterDataType(t *testing.T) {
 		{RegisterTypeDO, cpppo.CIPDataTypeBOOL},
 		{RegisterTypeAI, cpppo.CIPDataTypeREAL},
 		{RegisterTypeAO, cpppo.CIPDataTypeREAL},
-		{RegisterTypeGI, cpppo.CIPDataTypeBOOL},
-		{RegisterTypeGO, cpppo.CIPDataTypeBOOL},
+		{RegisterTypeGI, cpppo.CIPDataTypeDINT},
+		{RegisterTypeGO, cpppo.CIPDataTypeDINT},
 		{RegisterTypeUR, cpppo.CIPDataTypeSTRING},
 		{RegisterTypeSR, cpppo.CIPDataTypeSTRING},
 		{RegisterTypeVR, cpppo.CIPDataTypeSTRING},
+		{RegisterTypeUI, cpppo.CIPDataTypeBOOL},
+		{RegisterTypeUO, cpppo.CIPDataTypeBOOL},
+		{RegisterTypeSI, cpppo.CIPDataTypeBOOL},
+		{RegisterTypeSO, cpppo.CIPDataTypeBOOL},
+		{RegisterTypeRI, cpppo.CIPDataTypeBOOL},
+		{RegisterTypeRO, cpppo.CIPDataTypeBOOL},
+		{RegisterTypeWI, cpppo.CIPDataTypeBOOL},
+		{RegisterTypeWO, cpppo.CIPDataTypeBOOL},
+		{RegisterTypeF, cpppo.CIPDataTypeBOOL},
+		{RegisterTypeM, cpppo.CIPDataTypeBOOL},
 	}
 
 	for _, tc := range tests {
@@ -214,7 +260,7 @@ func TestReadPositionRegister(t *testing.T) {
 	if position.R != 180.0 {
 		t.Errorf("Expected R = 180.0, got %f", position.R)
 	}
-	if position.Config != "N U T, 0, 0, 0" {
+	if position.Config != (Configuration{}) {
 		t.Errorf("Expected Config = 'N U T, 0, 0, 0', got %s", position.Config)
 	}
 	if len(position.Extensions) != 2 {
@@ -241,7 +287,7 @@ func TestWritePositionRegister(t *testing.T) {
 		W:          0.0,
 		P:          90.0,
 		R:          180.0,
-		Config:     "N U T, 0, 0, 0",
+		Config:     Configuration{},
 		Extensions: []float32{10.0, 20.0},
 	}
 
@@ -252,31 +298,31 @@ func TestWritePositionRegister(t *testing.T) {
 	}
 
 	// Verify each component was written correctly
-	if mock.writeCalls["PR[1].X"] != 100.1 {
+	if mock.writeCalls["PR[1].X"] != float32(100.1) {
 		t.Errorf("Expected X = 100.1, got %v", mock.writeCalls["PR[1].X"])
 	}
-	if mock.writeCalls["PR[1].Y"] != 200.2 {
+	if mock.writeCalls["PR[1].Y"] != float32(200.2) {
 		t.Errorf("Expected Y = 200.2, got %v", mock.writeCalls["PR[1].Y"])
 	}
-	if mock.writeCalls["PR[1].Z"] != 300.3 {
+	if mock.writeCalls["PR[1].Z"] != float32(300.3) {
 		t.Errorf("Expected Z = 300.3, got %v", mock.writeCalls["PR[1].Z"])
 	}
-	if mock.writeCalls["PR[1].W"] != 0.0 {
+	if mock.writeCalls["PR[1].W"] != float32(0.0) {
 		t.Errorf("Expected W = 0.0, got %v", mock.writeCalls["PR[1].W"])
 	}
-	if mock.writeCalls["PR[1].P"] != 90.0 {
+	if mock.writeCalls["PR[1].P"] != float32(90.0) {
 		t.Errorf("Expected P = 90.0, got %v", mock.writeCalls["PR[1].P"])
 	}
-	if mock.writeCalls["PR[1].R"] != 180.0 {
+	if mock.writeCalls["PR[1].R"] != float32(180.0) {
 		t.Errorf("Expected R = 180.0, got %v", mock.writeCalls["PR[1].R"])
 	}
 	if mock.writeCalls["PR[1].Config"] != "N U T, 0, 0, 0" {
 		t.Errorf("Expected Config = 'N U T, 0, 0, 0', got %v", mock.writeCalls["PR[1].Config"])
 	}
-	if mock.writeCalls["PR[1].E1"] != 10.0 {
+	if mock.writeCalls["PR[1].E1"] != float32(10.0) {
 		t.Errorf("Expected E1 = 10.0, got %v", mock.writeCalls["PR[1].E1"])
 	}
-	if mock.writeCalls["PR[1].E2"] != 20.0 {
+	if mock.writeCalls["PR[1].E2"] != float32(20.0) {
 		t.Errorf("Expected E2 = 20.0, got %v", mock.writeCalls["PR[1].E2"])
 	}
 }
@@ -326,3 +372,131 @@ func TestConvenienceMethods(t *testing.T) {
 		t.Errorf("Expected value false, got %v", mock.writeCalls["DO[10]"])
 	}
 }
+
+// TestGroupIORegisters tests that group I/O is read and written as integers
+func TestGroupIORegisters(t *testing.T) {
+	mock := newMockPLCClient()
+	client := &FanucClient{PLCClient: mock}
+
+	mock.readResponses["GI[2]"] = int32(12)
+
+	value, err := client.ReadGIRegister(2)
+	if err != nil || value != 12 {
+		t.Errorf("Expected GI[2] = 12, got %v (%v)", value, err)
+	}
+
+	if err := client.WriteGORegister(1, 37); err != nil {
+		t.Fatalf("Failed to write GO register: %v", err)
+	}
+	if mock.writeCalls["GO[1]"] != int32(37) {
+		t.Errorf("Expected GO[1] = int32(37), got %#v", mock.writeCalls["GO[1]"])
+	}
+
+	// Plain Go integers are converted to DINT
+	if err := client.WriteRegister(RegisterTypeGO, 2, 255); err != nil {
+		t.Fatalf("Failed to write GO register: %v", err)
+	}
+	if mock.writeCalls["GO[2]"] != int32(255) {
+		t.Errorf("Expected GO[2] = int32(255), got %#v", mock.writeCalls["GO[2]"])
+	}
+
+	if err := client.WriteRegister(RegisterTypeGO, 3, int64(1)<<40); err == nil {
+		t.Error("Expected error for value out of range")
+	}
+	if err := client.WriteRegister(RegisterTypeGO, 3, 1.5); err == nil {
+		t.Error("Expected error for non-integer value")
+	}
+	if _, ok := mock.writeCalls["GO[3]"]; ok {
+		t.Error("Expected rejected values not to be written")
+	}
+
+	// Flags and markers are single bits
+	if err := client.WriteRegister(RegisterTypeF, 4, true); err != nil {
+		t.Fatalf("Failed to write flag: %v", err)
+	}
+	if mock.writeCalls["F[4]"] != true {
+		t.Errorf("Expected F[4] = true, got %v", mock.writeCalls["F[4]"])
+	}
+}
+
+// batchMockPLCClient adds batched reads and writes to mockPLCClient. Unlike
+// single reads, a batched read of a tag without a response fails.
+type batchMockPLCClient struct {
+	*mockPLCClient
+	batches int
+}
+
+func (m *batchMockPLCClient) ReadTags(reads []cpppo.TagRead) ([]cpppo.TagResult, error) {
+	m.batches++
+	results := make([]cpppo.TagResult, len(reads))
+	for i, read := range reads {
+		response, ok := m.readResponses[read.Name]
+		if !ok {
+			results[i].Err = cpppo.CIPError{Code: 0x05, ExtendedMsg: "Path destination unknown"}
+			continue
+		}
+		results[i].Value = response
+	}
+	return results, nil
+}
+
+func (m *batchMockPLCClient) WriteTags(writes []cpppo.TagWrite) ([]error, error) {
+	m.batches++
+	errs := make([]error, len(writes))
+	for i, write := range writes {
+		m.writeCalls[write.Name] = write.Value
+		errs[i] = m.writeResponses[write.Name]
+	}
+	return errs, nil
+}
+
+// TestPositionRegisterBatch tests that whole position registers are read and
+// written in one request
+func TestPositionRegisterBatch(t *testing.T) {
+	mock := &batchMockPLCClient{mockPLCClient: newMockPLCClient()}
+	client := &FanucClient{PLCClient: mock}
+
+	mock.readResponses["PR[2].X"] = float32(1)
+	mock.readResponses["PR[2].Y"] = float32(2)
+	mock.readResponses["PR[2].Z"] = float32(3)
+	mock.readResponses["PR[2].W"] = float32(4)
+	mock.readResponses["PR[2].P"] = float32(5)
+	mock.readResponses["PR[2].R"] = float32(6)
+	mock.readResponses["PR[2].Config"] = "N U T, 0, 0, 0"
+	mock.readResponses["PR[2].E1"] = float32(7)
+
+	// E2 and E3 do not exist on this controller
+	position, err := client.ReadPositionRegister(2)
+	if err != nil {
+		t.Fatalf("Failed to read position register: %v", err)
+	}
+	if position.X != 1 || position.R != 6 || position.Config != (Configuration{}) {
+		t.Errorf("Unexpected position %+v", position)
+	}
+	if len(position.Extensions) != 1 || position.Extensions[0] != 7 {
+		t.Errorf("Expected extensions [7], got %v", position.Extensions)
+	}
+
+	if err := client.WritePositionRegister(3, position); err != nil {
+		t.Fatalf("Failed to write position register: %v", err)
+	}
+	if mock.writeCalls["PR[3].Z"] != float32(3) || mock.writeCalls["PR[3].E1"] != float32(7) {
+		t.Errorf("Unexpected writes %v", mock.writeCalls)
+	}
+
+	if mock.batches != 2 || len(mock.readCalls) != 0 {
+		t.Errorf("Expected 2 batches and no single reads, got %d batches and %v", mock.batches, mock.readCalls)
+	}
+
+	// A missing core component fails the read
+	delete(mock.readResponses, "PR[2].Y")
+	if _, err := client.ReadPositionRegister(2); err == nil {
+		t.Error("Expected error when Y cannot be read")
+	}
+
+	// A failed component fails the write
+	mock.writeResponses["PR[3].Config"] = errors.New("rejected")
+	if err := client.WritePositionRegister(3, position); err == nil {
+		t.Error("Expected error when Config cannot be written")
+	}
+}
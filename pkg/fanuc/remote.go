@@ -0,0 +1,272 @@
+package fanuc
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/carun/cpppo-go/pkg/cpppo"
+)
+
+// UOP input signals (UI[n]) in their standard assignment. Inputs marked
+// active low must stay on for the robot to run.
+const (
+	UIImmediateStop = 1  // *IMSTP: immediate stop, active low
+	UIHold          = 2  // *HOLD: hold, active low
+	UISafeSpeed     = 3  // *SFSPD: safety speed, active low
+	UICycleStop     = 4  // CSTOPI: abort the running program
+	UIReset         = 5  // FAULT_RESET: clear alarms
+	UIStart         = 6  // START: resume a paused program on the falling edge
+	UIHome          = 7  // HOME: run the home macro
+	UIEnable        = 8  // ENBL: allow motion
+	UIRSR1          = 9  // RSR1-8 (UI[9] to UI[16]) in RSR mode
+	UIPNS1          = 9  // PNS1-8 (UI[9] to UI[16]) in PNS mode
+	UIPNSStrobe     = 17 // PNSTROBE: latch the PNS number
+	UIProdStart     = 18 // PROD_START: start the selected program
+)
+
+// UOP output signals answering remote commands
+const (
+	UOACK1  = 11 // ACK1-8 (UO[11] to UO[18]): RSR request accepted
+	UOSNO1  = 11 // SNO1-8 (UO[11] to UO[18]): selected PNS number
+	UOSNACK = 19 // SNACK: PNS number accepted
+)
+
+// Errors returned when the robot does not accept remote commands
+var (
+	ErrRemoteDisabled = errors.New("remote control disabled: CMDENBL is off")
+	ErrTPEnabled      = errors.New("teach pendant is enabled")
+	ErrFaultActive    = errors.New("an alarm is active")
+	ErrNoAcknowledge  = errors.New("robot did not acknowledge the command")
+)
+
+// RemoteTiming sets the timing of UOP handshakes
+type RemoteTiming struct {
+	Pulse   time.Duration // How long an input pulse stays on, at least
+	Timeout time.Duration // How long to wait for an acknowledgement after the pulse
+	Poll    time.Duration // How often to read outputs while waiting
+}
+
+// DefaultRemoteTiming is used when FanucClient.RemoteTiming is the zero value
+var DefaultRemoteTiming = RemoteTiming{
+	Pulse:   100 * time.Millisecond,
+	Timeout: 5 * time.Second,
+	Poll:    20 * time.Millisecond,
+}
+
+// remoteTiming returns the configured timing, or the defaults if none is set
+func (f *FanucClient) remoteTiming() RemoteTiming {
+	if f.RemoteTiming == (RemoteTiming{}) {
+		return DefaultRemoteTiming
+	}
+	return f.RemoteTiming
+}
+
+// EnableRemote turns on the inputs the robot needs before it will move under
+// remote control: *IMSTP, *HOLD, *SFSPD and ENBL
+func (f *FanucClient) EnableRemote() error {
+	for _, signal := range []int{UIImmediateStop, UIHold, UISafeSpeed, UIEnable} {
+		if err := f.setInput(signal, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Hold pauses the running program by pulsing *HOLD off and waits until the
+// program is no longer running
+func (f *FanucClient) Hold() error {
+	return f.handshake(UIHold, false, "program held", func(uo map[int]bool) bool {
+		return !uo[UOProgramRunning]
+	}, UOProgramRunning)
+}
+
+// Reset clears alarms by pulsing FAULT_RESET and waits until FAULT is off
+func (f *FanucClient) Reset() error {
+	return f.handshake(UIReset, true, "fault reset", func(uo map[int]bool) bool {
+		return !uo[UOFault]
+	}, UOFault)
+}
+
+// Start resumes a paused program by pulsing START and waits until it runs
+func (f *FanucClient) Start() error {
+	if err := f.checkRemote(); err != nil {
+		return err
+	}
+	return f.handshake(UIStart, true, "program running", func(uo map[int]bool) bool {
+		return uo[UOProgramRunning]
+	}, UOProgramRunning)
+}
+
+// Abort ends the running or paused program by pulsing CSTOPI and waits until
+// it is neither running nor paused
+func (f *FanucClient) Abort() error {
+	return f.handshake(UICycleStop, true, "program aborted", func(uo map[int]bool) bool {
+		return !uo[UOProgramRunning] && !uo[UOPaused]
+	}, UOProgramRunning, UOPaused)
+}
+
+// RunPNS selects program PNSnnnn by number (1-255) and starts it. The number
+// is set on PNS1-8, latched with PNSTROBE and confirmed on SNO1-8 and SNACK
+// before PROD_START is pulsed.
+func (f *FanucClient) RunPNS(number int) error {
+	if number < 1 || number > 255 {
+		return fmt.Errorf("PNS number %d out of range 1-255", number)
+	}
+	if err := f.checkRemote(); err != nil {
+		return err
+	}
+
+	for bit := 0; bit < 8; bit++ {
+		if err := f.setInput(UIPNS1+bit, number&(1<<bit) != 0); err != nil {
+			return err
+		}
+	}
+
+	outputs := []int{UOSNACK}
+	for bit := 0; bit < 8; bit++ {
+		outputs = append(outputs, UOSNO1+bit)
+	}
+	err := f.handshake(UIPNSStrobe, true, fmt.Sprintf("PNS%04d selected", number), func(uo map[int]bool) bool {
+		selected := 0
+		for bit := 0; bit < 8; bit++ {
+			if uo[UOSNO1+bit] {
+				selected |= 1 << bit
+			}
+		}
+		return uo[UOSNACK] && selected == number
+	}, outputs...)
+	if err != nil {
+		return err
+	}
+
+	return f.handshake(UIProdStart, true, "program running", func(uo map[int]bool) bool {
+		return uo[UOProgramRunning]
+	}, UOProgramRunning)
+}
+
+// RunRSR requests robot service request n (1-8) by pulsing RSRn and waits
+// for ACKn
+func (f *FanucClient) RunRSR(n int) error {
+	if n < 1 || n > 8 {
+		return fmt.Errorf("RSR number %d out of range 1-8", n)
+	}
+	if err := f.checkRemote(); err != nil {
+		return err
+	}
+
+	ack := UOACK1 + n - 1
+	return f.handshake(UIRSR1+n-1, true, fmt.Sprintf("RSR%d acknowledged", n), func(uo map[int]bool) bool {
+		return uo[ack]
+	}, ack)
+}
+
+// checkRemote returns an error unless the robot accepts remote commands
+func (f *FanucClient) checkRemote() error {
+	uo, err := f.readOutputs(UOCmdEnabled, UOTPEnabled, UOFault)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case uo[UOTPEnabled]:
+		return ErrTPEnabled
+	case uo[UOFault]:
+		return ErrFaultActive
+	case !uo[UOCmdEnabled]:
+		return ErrRemoteDisabled
+	}
+	return nil
+}
+
+// setInput writes one UOP input
+func (f *FanucClient) setInput(signal int, on bool) error {
+	return f.WriteRegister(RegisterTypeUI, signal, on)
+}
+
+// readOutputs reads UOP outputs in one request when the client can batch,
+// so they all come from the same controller scan
+func (f *FanucClient) readOutputs(signals ...int) (map[int]bool, error) {
+	reads := make([]cpppo.TagRead, len(signals))
+	for i, signal := range signals {
+		reads[i] = cpppo.TagRead{Name: buildRegisterTag(RegisterTypeUO, signal), DataType: getRegisterDataType(RegisterTypeUO)}
+	}
+
+	results, err := f.readComponents(reads)
+	if err != nil {
+		return nil, err
+	}
+
+	uo := make(map[int]bool, len(signals))
+	for i, result := range results {
+		if result.Err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", reads[i].Name, result.Err)
+		}
+		on, ok := result.Value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("unexpected type %T for %s", result.Value, reads[i].Name)
+		}
+		uo[signals[i]] = on
+	}
+	return uo, nil
+}
+
+// handshake sets an input to level, sets it back after the pulse time, and
+// reads the given outputs until done reports success. Outputs are read from
+// the moment the input is set, each time in one request, so an
+// acknowledgement that comes and goes during the pulse is not missed. The
+// input is set back on every path, including a failed read and the timeout,
+// in which case the error wraps ErrNoAcknowledge.
+func (f *FanucClient) handshake(signal int, level bool, what string, done func(uo map[int]bool) bool, outputs ...int) (err error) {
+	timing := f.remoteTiming()
+	start := time.Now()
+
+	released := false
+	release := func() error {
+		released = true
+		return f.setInput(signal, !level)
+	}
+	defer func() {
+		if !released {
+			if releaseErr := release(); err == nil {
+				err = releaseErr
+			}
+		}
+	}()
+
+	// A write that failed may still have reached the controller, so the
+	// input is set back even then
+	if err := f.setInput(signal, level); err != nil {
+		return err
+	}
+
+	acknowledged := false
+	for {
+		uo, err := f.readOutputs(outputs...)
+		if err != nil {
+			return err
+		}
+		acknowledged = acknowledged || done(uo)
+
+		// Commands act on either edge, so the pulse is completed even when
+		// the acknowledgement comes first
+		elapsed := time.Since(start)
+		if !released && elapsed >= timing.Pulse {
+			if err := release(); err != nil {
+				return err
+			}
+		}
+		if acknowledged && released {
+			return nil
+		}
+		if elapsed >= timing.Pulse+timing.Timeout {
+			return fmt.Errorf("waiting for %s: %w", what, ErrNoAcknowledge)
+		}
+
+		sleep := timing.Poll
+		if !released && timing.Pulse-elapsed < sleep {
+			sleep = timing.Pulse - elapsed
+		}
+		time.Sleep(sleep)
+	}
+}
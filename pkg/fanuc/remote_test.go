@@ -0,0 +1,253 @@
+package fanuc
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/carun/cpppo-go/pkg/cpppo"
+)
+
+// uopSimulator answers UOP inputs the way a controller in remote mode does
+type uopSimulator struct {
+	mu sync.Mutex
+	ui map[int]bool
+	uo map[int]bool
+
+	ignore      bool // Do not react to commands
+	oneShot     bool // ACK and SNACK stay on for one read request only
+	failWhileOn int  // Reads fail while this input is on
+}
+
+func newUOPSimulator() *uopSimulator {
+	return &uopSimulator{
+		ui: map[int]bool{},
+		uo: map[int]bool{UOCmdEnabled: true, UOSystemReady: true},
+	}
+}
+
+func (s *uopSimulator) ReadTag(tagName string, dataType byte) (interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var index int
+	if _, err := fmt.Sscanf(tagName, "UO[%d]", &index); err != nil {
+		return nil, fmt.Errorf("unexpected read of %s", tagName)
+	}
+	return s.uo[index], nil
+}
+
+// ReadTags reads outputs in one request, as one controller scan
+func (s *uopSimulator) ReadTags(reads []cpppo.TagRead) ([]cpppo.TagResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failWhileOn != 0 && s.ui[s.failWhileOn] {
+		return nil, errors.New("connection lost")
+	}
+
+	results := make([]cpppo.TagResult, len(reads))
+	for i, read := range reads {
+		var index int
+		if _, err := fmt.Sscanf(read.Name, "UO[%d]", &index); err != nil {
+			return nil, fmt.Errorf("unexpected read of %s", read.Name)
+		}
+		results[i].Value = s.uo[index]
+	}
+
+	if s.oneShot {
+		s.uo[UOSNACK] = false
+		for i := 0; i < 8; i++ {
+			s.uo[UOACK1+i] = false
+		}
+	}
+	return results, nil
+}
+
+func (s *uopSimulator) WriteTags(writes []cpppo.TagWrite) ([]error, error) {
+	errs := make([]error, len(writes))
+	for i, write := range writes {
+		errs[i] = s.WriteTag(write.Name, write.DataType, write.Value)
+	}
+	return errs, nil
+}
+
+func (s *uopSimulator) WriteTag(tagName string, dataType byte, value interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var index int
+	if _, err := fmt.Sscanf(tagName, "UI[%d]", &index); err != nil {
+		return fmt.Errorf("unexpected write of %s", tagName)
+	}
+	on := value.(bool)
+	falling := s.ui[index] && !on
+	s.ui[index] = on
+	if s.ignore {
+		return nil
+	}
+
+	switch {
+	case index == UIHold && falling:
+		s.uo[UOProgramRunning], s.uo[UOPaused] = false, true
+	case index == UIReset && falling:
+		s.uo[UOFault] = false
+	case index == UIStart && falling:
+		s.uo[UOProgramRunning], s.uo[UOPaused] = true, false
+	case index == UICycleStop && falling:
+		s.uo[UOProgramRunning], s.uo[UOPaused] = false, false
+	case index == UIPNSStrobe && falling:
+		for bit := 0; bit < 8; bit++ {
+			s.uo[UOSNO1+bit] = s.ui[UIPNS1+bit]
+		}
+		s.uo[UOSNACK] = true
+	case index == UIProdStart && falling:
+		s.uo[UOProgramRunning] = true
+	case index >= UIRSR1 && index < UIRSR1+8 && falling:
+		s.uo[UOACK1+index-UIRSR1] = true
+	}
+	return nil
+}
+
+func (s *uopSimulator) Close() error {
+	return nil
+}
+
+var fastRemoteTiming = RemoteTiming{
+	Pulse:   time.Millisecond,
+	Timeout: 50 * time.Millisecond,
+	Poll:    time.Millisecond,
+}
+
+func TestRemoteControl(t *testing.T) {
+	sim := newUOPSimulator()
+	client := &FanucClient{PLCClient: sim, RemoteTiming: fastRemoteTiming}
+
+	if err := client.EnableRemote(); err != nil {
+		t.Fatalf("EnableRemote returned error: %v", err)
+	}
+	for _, signal := range []int{UIImmediateStop, UIHold, UISafeSpeed, UIEnable} {
+		if !sim.ui[signal] {
+			t.Errorf("Expected UI[%d] on", signal)
+		}
+	}
+
+	if err := client.RunPNS(37); err != nil {
+		t.Fatalf("RunPNS returned error: %v", err)
+	}
+	if !sim.uo[UOProgramRunning] {
+		t.Error("Expected the program to run")
+	}
+	// 37 = PNS1, PNS3 and PNS6
+	for bit := 0; bit < 8; bit++ {
+		if sim.ui[UIPNS1+bit] != (37&(1<<bit) != 0) {
+			t.Errorf("Unexpected PNS%d = %v", bit+1, sim.ui[UIPNS1+bit])
+		}
+	}
+	if sim.ui[UIPNSStrobe] || sim.ui[UIProdStart] {
+		t.Error("Expected strobe and start to be released")
+	}
+
+	if err := client.Hold(); err != nil {
+		t.Fatalf("Hold returned error: %v", err)
+	}
+	if !sim.ui[UIHold] || !sim.uo[UOPaused] {
+		t.Error("Expected *HOLD back on and the program paused")
+	}
+
+	if err := client.Start(); err != nil {
+		t.Fatalf("Start returned error: %v", err)
+	}
+	if err := client.Abort(); err != nil {
+		t.Fatalf("Abort returned error: %v", err)
+	}
+
+	if err := client.RunRSR(3); err != nil {
+		t.Fatalf("RunRSR returned error: %v", err)
+	}
+	if !sim.uo[UOACK1+2] {
+		t.Error("Expected ACK3")
+	}
+
+	sim.uo[UOFault] = true
+	if err := client.Reset(); err != nil {
+		t.Fatalf("Reset returned error: %v", err)
+	}
+}
+
+func TestRemoteControlPreconditions(t *testing.T) {
+	sim := newUOPSimulator()
+	client := &FanucClient{PLCClient: sim, RemoteTiming: fastRemoteTiming}
+
+	sim.uo[UOCmdEnabled] = false
+	if err := client.Start(); !errors.Is(err, ErrRemoteDisabled) {
+		t.Errorf("Expected ErrRemoteDisabled, got %v", err)
+	}
+
+	sim.uo[UOCmdEnabled] = true
+	sim.uo[UOTPEnabled] = true
+	if err := client.RunPNS(1); !errors.Is(err, ErrTPEnabled) {
+		t.Errorf("Expected ErrTPEnabled, got %v", err)
+	}
+
+	sim.uo[UOTPEnabled] = false
+	sim.uo[UOFault] = true
+	if err := client.RunRSR(1); !errors.Is(err, ErrFaultActive) {
+		t.Errorf("Expected ErrFaultActive, got %v", err)
+	}
+	if len(sim.ui) != 0 {
+		t.Errorf("Expected no inputs to change, got %v", sim.ui)
+	}
+
+	// A robot that does not react times out
+	sim.uo[UOFault] = false
+	sim.ignore = true
+	err := client.RunRSR(2)
+	if !errors.Is(err, ErrNoAcknowledge) || !strings.Contains(err.Error(), "RSR2") {
+		t.Errorf("Expected ErrNoAcknowledge for RSR2, got %v", err)
+	}
+
+	if err := client.RunPNS(256); err == nil {
+		t.Error("Expected error for PNS 256")
+	}
+	if err := client.RunRSR(9); err == nil {
+		t.Error("Expected error for RSR 9")
+	}
+}
+
+// TestRemoteControlReleasesInputs tests that acknowledgements lasting one
+// scan are seen and that inputs are released when a handshake fails
+func TestRemoteControlReleasesInputs(t *testing.T) {
+	sim := newUOPSimulator()
+	sim.oneShot = true
+	client := &FanucClient{PLCClient: sim, RemoteTiming: fastRemoteTiming}
+
+	if err := client.RunRSR(3); err != nil {
+		t.Errorf("Expected a one-scan ACK3 to be seen, got %v", err)
+	}
+	if err := client.RunPNS(37); err != nil {
+		t.Errorf("Expected a one-scan SNACK to be seen with SNO1-8, got %v", err)
+	}
+
+	// The input goes back after a timeout
+	sim.ignore = true
+	client.RemoteTiming = RemoteTiming{Pulse: 20 * time.Millisecond, Timeout: time.Millisecond, Poll: time.Millisecond}
+	if err := client.RunRSR(2); !errors.Is(err, ErrNoAcknowledge) {
+		t.Errorf("Expected ErrNoAcknowledge, got %v", err)
+	}
+	if sim.ui[UIRSR1+1] {
+		t.Error("Expected RSR2 to be released after the timeout")
+	}
+
+	// and after a failed read
+	sim.failWhileOn = UIRSR1 + 3
+	if err := client.RunRSR(4); err == nil {
+		t.Error("Expected the failed read to be reported")
+	}
+	if sim.ui[UIRSR1+3] {
+		t.Error("Expected RSR4 to be released after the failed read")
+	}
+}
@@ -0,0 +1,314 @@
+package fanuc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// SnapshotSpec selects the registers and frames a snapshot captures
+type SnapshotSpec struct {
+	Group      int   // Motion group of the positions and frames, 0 means group 1
+	Numeric    []int // R registers
+	Positions  []int // PR registers, captured in the representation they are stored in
+	Strings    []int // SR registers
+	UserFrames []int // User frames 1-9
+	ToolFrames []int // Tool frames 1-10
+}
+
+// Snapshot holds register and frame values read at one time, for example
+// before a changeover. It is saved and loaded as JSON.
+type Snapshot struct {
+	Time       time.Time            `json:"time"`
+	Group      int                  `json:"group"`
+	Numeric    map[int]NumericValue `json:"numeric,omitempty"`
+	Positions  map[int]*Position    `json:"positions,omitempty"`
+	Strings    map[int]string       `json:"strings,omitempty"`
+	UserFrames map[int]*Frame       `json:"user_frames,omitempty"`
+	ToolFrames map[int]*Frame       `json:"tool_frames,omitempty"`
+}
+
+// TakeSnapshot reads every register and frame of spec. Numeric and string
+// registers are read in batches when the client can batch. Any failed read
+// fails the snapshot, so a snapshot is always complete.
+func (f *FanucClient) TakeSnapshot(spec SnapshotSpec) (*Snapshot, error) {
+	group := spec.Group
+	if group == 0 {
+		group = 1
+	}
+	if group < 1 || group > maxMotionGroups {
+		return nil, fmt.Errorf("motion group %d out of range 1-%d", spec.Group, maxMotionGroups)
+	}
+
+	snapshot := &Snapshot{
+		Time:       time.Now(),
+		Group:      group,
+		Numeric:    make(map[int]NumericValue, len(spec.Numeric)),
+		Positions:  make(map[int]*Position, len(spec.Positions)),
+		Strings:    make(map[int]string, len(spec.Strings)),
+		UserFrames: make(map[int]*Frame, len(spec.UserFrames)),
+		ToolFrames: make(map[int]*Frame, len(spec.ToolFrames)),
+	}
+
+	var set RegisterSet
+	for _, index := range spec.Numeric {
+		set = append(set, RegisterRef{Type: RegisterTypeR, Index: index})
+	}
+	for _, index := range spec.Strings {
+		set = append(set, RegisterRef{Type: RegisterTypeSR, Index: index})
+	}
+	results, err := f.ReadRegisterSet(set)
+	if err != nil {
+		return nil, err
+	}
+	for _, result := range results {
+		if result.Err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", result.Ref, result.Err)
+		}
+		if result.Ref.Type == RegisterTypeR {
+			value, err := numericValueOf(result.Value)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", result.Ref, err)
+			}
+			snapshot.Numeric[result.Ref.Index] = value
+			continue
+		}
+		s, ok := result.Value.(string)
+		if !ok {
+			return nil, fmt.Errorf("unexpected type %T for %s", result.Value, result.Ref)
+		}
+		snapshot.Strings[result.Ref.Index] = s
+	}
+
+	for _, index := range spec.Positions {
+		position, err := f.ReadPositionRegisterNative(index, group)
+		if err != nil {
+			return nil, err
+		}
+		snapshot.Positions[index] = position
+	}
+	for _, number := range spec.UserFrames {
+		frame, err := f.ReadUserFrame(group, number)
+		if err != nil {
+			return nil, err
+		}
+		snapshot.UserFrames[number] = frame
+	}
+	for _, number := range spec.ToolFrames {
+		frame, err := f.ReadToolFrame(group, number)
+		if err != nil {
+			return nil, err
+		}
+		snapshot.ToolFrames[number] = frame
+	}
+
+	return snapshot, nil
+}
+
+// Spec returns the spec that captures the registers and frames of the
+// snapshot
+func (s *Snapshot) Spec() SnapshotSpec {
+	return SnapshotSpec{
+		Group:      s.Group,
+		Numeric:    sortedKeys(s.Numeric),
+		Positions:  sortedKeys(s.Positions),
+		Strings:    sortedKeys(s.Strings),
+		UserFrames: sortedKeys(s.UserFrames),
+		ToolFrames: sortedKeys(s.ToolFrames),
+	}
+}
+
+// WriteJSON writes the snapshot as indented JSON
+func (s *Snapshot) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(s)
+}
+
+// ReadSnapshot reads a snapshot written by WriteJSON
+func ReadSnapshot(r io.Reader) (*Snapshot, error) {
+	var s Snapshot
+	if err := json.NewDecoder(r).Decode(&s); err != nil {
+		return nil, fmt.Errorf("failed to decode snapshot: %w", err)
+	}
+	for index, position := range s.Positions {
+		if position == nil {
+			return nil, fmt.Errorf("snapshot has no value for PR[%d]", index)
+		}
+	}
+	for _, frames := range []map[int]*Frame{s.UserFrames, s.ToolFrames} {
+		for number, frame := range frames {
+			if frame == nil {
+				return nil, fmt.Errorf("snapshot has no value for frame %d", number)
+			}
+		}
+	}
+	return &s, nil
+}
+
+// snapshotSection is the part of a snapshot a value belongs to
+type snapshotSection int
+
+const (
+	sectionNumeric snapshotSection = iota
+	sectionPosition
+	sectionString
+	sectionUserFrame
+	sectionToolFrame
+)
+
+// SnapshotDiff is one register or frame that differs between two snapshots
+type SnapshotDiff struct {
+	Item string      // Register or frame, such as "R[1]" or "UTOOL[2]"
+	Old  interface{} // Value in the first snapshot, nil if it is missing
+	New  interface{} // Value in the second snapshot, nil if it is missing
+
+	section snapshotSection
+	index   int
+}
+
+// String formats the difference as "R[1]: 5 -> 7"
+func (d SnapshotDiff) String() string {
+	return fmt.Sprintf("%s: %s -> %s", d.Item, formatSnapshotValue(d.Old), formatSnapshotValue(d.New))
+}
+
+// formatSnapshotValue formats a snapshot value for a SnapshotDiff
+func formatSnapshotValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "(none)"
+	case string:
+		return fmt.Sprintf("%q", v)
+	case *Position:
+		if v.Representation == RepresentationJoint {
+			return fmt.Sprintf("%v", v.Joints)
+		}
+		return fmt.Sprintf("X:%g Y:%g Z:%g W:%g P:%g R:%g %s", v.X, v.Y, v.Z, v.W, v.P, v.R, v.Config)
+	case *Frame:
+		return fmt.Sprintf("X:%g Y:%g Z:%g W:%g P:%g R:%g", v.X, v.Y, v.Z, v.W, v.P, v.R)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// DiffSnapshots returns what differs from a to b: registers and frames whose
+// values differ by more than tolerance, or that only one snapshot has.
+// Numeric registers also differ when one holds an integer and the other a
+// real; a value of unknown kind is compared by value only. The differences are sorted by register type, then by index.
+func DiffSnapshots(a, b *Snapshot, tolerance float64) []SnapshotDiff {
+	var diffs []SnapshotDiff
+	add := func(section snapshotSection, item string, index int, x, y interface{}, same bool) {
+		if !same {
+			diffs = append(diffs, SnapshotDiff{Item: item, Old: x, New: y, section: section, index: index})
+		}
+	}
+
+	for _, index := range unionKeys(a.Numeric, b.Numeric) {
+		x, inA := a.Numeric[index]
+		y, inB := b.Numeric[index]
+		add(sectionNumeric, buildRegisterTag(RegisterTypeR, index), index, present(x, inA), present(y, inB),
+			inA && inB && x.SameKind(y) && withinDeadband(x, y, tolerance))
+	}
+	for _, index := range unionKeys(a.Positions, b.Positions) {
+		x, y := a.Positions[index], b.Positions[index]
+		add(sectionPosition, positionTag(b.Group, index), index, present(x, x != nil), present(y, y != nil),
+			x != nil && y != nil && withinDeadband(x, y, tolerance))
+	}
+	for _, index := range unionKeys(a.Strings, b.Strings) {
+		x, inA := a.Strings[index]
+		y, inB := b.Strings[index]
+		add(sectionString, buildRegisterTag(RegisterTypeSR, index), index, present(x, inA), present(y, inB),
+			inA && inB && x == y)
+	}
+	for _, number := range unionKeys(a.UserFrames, b.UserFrames) {
+		x, y := a.UserFrames[number], b.UserFrames[number]
+		add(sectionUserFrame, fmt.Sprintf("UFRAME[%d]", number), number, present(x, x != nil), present(y, y != nil),
+			x != nil && y != nil && withinDeadband(x, y, tolerance))
+	}
+	for _, number := range unionKeys(a.ToolFrames, b.ToolFrames) {
+		x, y := a.ToolFrames[number], b.ToolFrames[number]
+		add(sectionToolFrame, fmt.Sprintf("UTOOL[%d]", number), number, present(x, x != nil), present(y, y != nil),
+			x != nil && y != nil && withinDeadband(x, y, tolerance))
+	}
+
+	return diffs
+}
+
+// present returns value if ok, otherwise nil
+func present[T any](value T, ok bool) interface{} {
+	if !ok {
+		return nil
+	}
+	return value
+}
+
+// sortedKeys returns the keys of m in increasing order
+func sortedKeys[T any](m map[int]T) []int {
+	keys := make([]int, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Ints(keys)
+	return keys
+}
+
+// unionKeys returns the keys of a and b in increasing order
+func unionKeys[T any](a, b map[int]T) []int {
+	union := make(map[int]struct{}, len(a)+len(b))
+	for key := range a {
+		union[key] = struct{}{}
+	}
+	for key := range b {
+		union[key] = struct{}{}
+	}
+	return sortedKeys(union)
+}
+
+// ApplyOptions configures ApplySnapshot
+type ApplyOptions struct {
+	DryRun    bool    // Only report what would be written
+	Tolerance float64 // Differences up to this are left alone
+}
+
+// ApplySnapshot restores the registers and frames of a snapshot, for
+// example to roll back a changeover. The controller's current values are
+// read first and only those that differ from the snapshot are written. It
+// returns the differences it wrote, or would write with DryRun. If a write
+// fails, the differences written before it are returned with the error.
+// Frames are written only if the client's SysVarWrites list allows them.
+func (f *FanucClient) ApplySnapshot(snapshot *Snapshot, options ApplyOptions) ([]SnapshotDiff, error) {
+	current, err := f.TakeSnapshot(snapshot.Spec())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read current values: %w", err)
+	}
+
+	diffs := DiffSnapshots(current, snapshot, options.Tolerance)
+	if options.DryRun {
+		return diffs, nil
+	}
+
+	group := current.Group
+	for i, diff := range diffs {
+		var err error
+		switch diff.section {
+		case sectionNumeric:
+			err = f.WriteNumericRegister(diff.index, diff.New.(NumericValue))
+		case sectionString:
+			err = f.WriteStringRegister(diff.index, diff.New.(string))
+		case sectionPosition:
+			position := *diff.New.(*Position)
+			position.Group = group
+			err = f.WritePositionRegister(diff.index, &position)
+		case sectionUserFrame:
+			err = f.WriteUserFrame(group, diff.index, diff.New.(*Frame))
+		case sectionToolFrame:
+			err = f.WriteToolFrame(group, diff.index, diff.New.(*Frame))
+		}
+		if err != nil {
+			return diffs[:i], fmt.Errorf("failed to restore %s: %w", diff.Item, err)
+		}
+	}
+	return diffs, nil
+}
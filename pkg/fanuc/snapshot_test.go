@@ -0,0 +1,267 @@
+package fanuc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/carun/cpppo-go/pkg/cpppo"
+)
+
+// setSnapshotValues fills the mock with the registers and frames of
+// snapshotSpec
+func setSnapshotValues(mock *mockPLCClient) {
+	mock.readResponses["R[1]"] = int32(5)
+	mock.readResponses["R[2]"] = float32(1.5)
+	mock.readResponses["SR[1]"] = "PART_A"
+	for i, axis := range positionAxes {
+		mock.readResponses["PR[1]."+axis] = float32(100 * i)
+		mock.readResponses["$MNUFRAME[1,1].$"+axis] = float32(i)
+		mock.readResponses["$MNUTOOL[1,2].$"+axis] = float32(10 * i)
+	}
+	mock.readResponses["PR[1].Config"] = "N U T, 0, 0, 0"
+	mock.readResponses["PR[1].TYPE"] = int32(posRegTypeXYZWPR)
+}
+
+var snapshotSpec = SnapshotSpec{
+	Numeric:    []int{1, 2},
+	Positions:  []int{1},
+	Strings:    []int{1},
+	UserFrames: []int{1},
+	ToolFrames: []int{2},
+}
+
+func TestSnapshotJSON(t *testing.T) {
+	mock := &batchMockPLCClient{mockPLCClient: newMockPLCClient()}
+	client := &FanucClient{PLCClient: mock}
+	setSnapshotValues(mock.mockPLCClient)
+
+	snapshot, err := client.TakeSnapshot(snapshotSpec)
+	if err != nil {
+		t.Fatalf("TakeSnapshot returned error: %v", err)
+	}
+	if snapshot.Numeric[1] != IntegerValue(5) || snapshot.Numeric[2] != RealValue(1.5) {
+		t.Errorf("Unexpected numeric registers %v", snapshot.Numeric)
+	}
+	if snapshot.Strings[1] != "PART_A" || snapshot.Positions[1].Y != 100 || *snapshot.ToolFrames[2] != (Frame{0, 10, 20, 30, 40, 50}) {
+		t.Errorf("Unexpected snapshot %+v", snapshot)
+	}
+	if spec := snapshot.Spec(); !reflect.DeepEqual(spec.Numeric, []int{1, 2}) || spec.Group != 1 {
+		t.Errorf("Unexpected spec %+v", spec)
+	}
+
+	var buf bytes.Buffer
+	if err := snapshot.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON returned error: %v", err)
+	}
+	for _, want := range []string{`"integer": 5`, `"real": 1.5`, `"Config": "N U T, 0, 0, 0"`, `"Representation": "cartesian"`} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Expected %s in %s", want, buf.String())
+		}
+	}
+
+	loaded, err := ReadSnapshot(&buf)
+	if err != nil {
+		t.Fatalf("ReadSnapshot returned error: %v", err)
+	}
+	if !loaded.Time.Equal(snapshot.Time) {
+		t.Errorf("Expected time %v, got %v", snapshot.Time, loaded.Time)
+	}
+	loaded.Time = snapshot.Time
+	if !reflect.DeepEqual(loaded, snapshot) {
+		t.Errorf("Expected %+v, got %+v", snapshot, loaded)
+	}
+
+	unknown, err := ReadSnapshot(strings.NewReader(`{"numeric": {"1": {"number": 10}}}`))
+	if err != nil || unknown.Numeric[1] != UnknownValue(10) {
+		t.Errorf("Expected 10 of unknown kind, got %+v (%v)", unknown, err)
+	}
+	if _, err := ReadSnapshot(strings.NewReader(`{"numeric": {"1": {}}}`)); err == nil {
+		t.Error("Expected error for a numeric value without a kind")
+	}
+	if _, err := ReadSnapshot(strings.NewReader(`{"tool_frames": {"1": null}}`)); err == nil {
+		t.Error("Expected error for a missing frame")
+	}
+}
+
+func TestDiffSnapshots(t *testing.T) {
+	golden := &Snapshot{
+		Group:      1,
+		Numeric:    map[int]NumericValue{1: IntegerValue(5), 2: RealValue(1.5), 3: RealValue(2), 5: RealValue(10)},
+		Strings:    map[int]string{1: "PART_A"},
+		UserFrames: map[int]*Frame{1: {X: 100}},
+	}
+	live := &Snapshot{
+		Group:      1,
+		Numeric:    map[int]NumericValue{1: RealValue(5), 2: RealValue(1.501), 4: IntegerValue(1), 5: UnknownValue(10)},
+		Strings:    map[int]string{1: "PART_B"},
+		UserFrames: map[int]*Frame{1: {X: 100.0005}},
+	}
+
+	diffs := DiffSnapshots(golden, live, 0.01)
+	var got []string
+	for _, diff := range diffs {
+		got = append(got, diff.String())
+	}
+	expected := []string{
+		"R[1]: 5 -> 5", // An integer became a real
+		"R[3]: 2 -> (none)",
+		"R[4]: (none) -> 1", // R[5] read in attribute mode matches either kind
+		`SR[1]: "PART_A" -> "PART_B"`,
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+
+	if diffs := DiffSnapshots(golden, live, 0); len(diffs) != 6 {
+		t.Errorf("Expected every change without tolerance, got %v", diffs)
+	}
+	if diffs := DiffSnapshots(golden, golden, 0); len(diffs) != 0 {
+		t.Errorf("Expected no differences, got %v", diffs)
+	}
+}
+
+// TestSnapshotJointPosition tests that a joint position is captured and
+// restored in joints
+func TestSnapshotJointPosition(t *testing.T) {
+	mock := &batchMockPLCClient{mockPLCClient: newMockPLCClient()}
+	client := &FanucClient{PLCClient: mock}
+
+	mock.readResponses["PR[3].TYPE"] = int32(posRegTypeJoint)
+	for i := 1; i <= 6; i++ {
+		mock.readResponses[fmt.Sprintf("PR[3].J%d", i)] = float32(10 * i)
+	}
+
+	spec := SnapshotSpec{Positions: []int{3}}
+	golden, err := client.TakeSnapshot(spec)
+	if err != nil {
+		t.Fatalf("TakeSnapshot returned error: %v", err)
+	}
+	home := golden.Positions[3]
+	if home.Representation != RepresentationJoint || !reflect.DeepEqual(home.Joints, []float32{10, 20, 30, 40, 50, 60}) {
+		t.Fatalf("Expected PR[3] in joints, got %+v", home)
+	}
+
+	mock.readResponses["PR[3].J5"] = float32(-90)
+	diffs, err := client.ApplySnapshot(golden, ApplyOptions{})
+	if err != nil || len(diffs) != 1 {
+		t.Fatalf("Expected PR[3] to be restored, got %v (%v)", diffs, err)
+	}
+	if mock.writeCalls["PR[3].J5"] != float32(50) {
+		t.Errorf("Expected PR[3] to be written in joints, got %v", mock.writeCalls)
+	}
+	if _, ok := mock.writeCalls["PR[3].X"]; ok {
+		t.Errorf("Expected no Cartesian writes, got %v", mock.writeCalls)
+	}
+
+	mock.readResponses["PR[3].TYPE"] = int32(1)
+	if _, err := client.TakeSnapshot(spec); err == nil {
+		t.Error("Expected error for an unsupported position type")
+	}
+}
+
+// positionObjectClient answers reads of a position register through the
+// object of the other representation with an object state conflict, as the
+// controller does
+type positionObjectClient struct {
+	*mockPLCClient
+}
+
+func (c positionObjectClient) GetAttributeSingle(class uint16, instance uint32, attribute uint16) ([]byte, error) {
+	other := map[uint16]uint16{0x7B: 0x7C, 0x7C: 0x7B}
+	if _, ok := c.attributes[attributeKey{class, instance, attribute}]; !ok && other[class] != 0 {
+		if _, ok := c.attributes[attributeKey{other[class], instance, attribute}]; ok {
+			return nil, cpppo.CIPError{Code: 0x0C, ExtendedMsg: "Object state conflict"}
+		}
+	}
+	return c.mockPLCClient.GetAttributeSingle(class, instance, attribute)
+}
+
+// TestSnapshotAttributeAccess tests that positions are captured and restored
+// in their stored representation through the position objects
+func TestSnapshotAttributeAccess(t *testing.T) {
+	mock := newMockPLCClient()
+	client := &FanucClient{PLCClient: positionObjectClient{mock}, Access: AccessAttribute}
+
+	cartesian := make([]byte, cartesianRecordHeader)
+	binary.LittleEndian.PutUint32(cartesian[8:], math.Float32bits(250))
+	joints := make([]byte, frameRecordLength+6*4)
+	for i := 0; i < 6; i++ {
+		binary.LittleEndian.PutUint32(joints[frameRecordLength+4*i:], math.Float32bits(float32(10*(i+1))))
+	}
+	mock.attributes[attributeKey{0x7B, 1, 1}] = append([]byte(nil), cartesian...)
+	mock.attributes[attributeKey{0x7C, 1, 3}] = append([]byte(nil), joints...)
+
+	golden, err := client.TakeSnapshot(SnapshotSpec{Positions: []int{1, 3}})
+	if err != nil {
+		t.Fatalf("TakeSnapshot returned error: %v", err)
+	}
+	if golden.Positions[1].Representation != RepresentationCartesian || golden.Positions[1].X != 250 {
+		t.Errorf("Expected PR[1] in Cartesian, got %+v", golden.Positions[1])
+	}
+	if golden.Positions[3].Representation != RepresentationJoint || golden.Positions[3].Joints[4] != 50 {
+		t.Errorf("Expected PR[3] in joints, got %+v", golden.Positions[3])
+	}
+	if len(mock.readCalls) != 0 {
+		t.Errorf("Expected no symbolic reads, got %v", mock.readCalls)
+	}
+
+	binary.LittleEndian.PutUint32(mock.attributes[attributeKey{0x7B, 1, 1}][8:], math.Float32bits(0))
+	binary.LittleEndian.PutUint32(mock.attributes[attributeKey{0x7C, 1, 3}][frameRecordLength+16:], math.Float32bits(-90))
+	diffs, err := client.ApplySnapshot(golden, ApplyOptions{})
+	if err != nil || len(diffs) != 2 {
+		t.Fatalf("Expected PR[1] and PR[3] to be restored, got %v (%v)", diffs, err)
+	}
+	if !bytes.Equal(mock.attributes[attributeKey{0x7B, 1, 1}], cartesian) || !bytes.Equal(mock.attributes[attributeKey{0x7C, 1, 3}], joints) {
+		t.Errorf("Expected the records restored, got %v", mock.attributes)
+	}
+	if _, ok := mock.attributes[attributeKey{0x7B, 1, 3}]; ok {
+		t.Error("Expected PR[3] not to be written in Cartesian")
+	}
+}
+
+func TestApplySnapshot(t *testing.T) {
+	mock := &batchMockPLCClient{mockPLCClient: newMockPLCClient()}
+	client := &FanucClient{PLCClient: mock, SysVarWrites: SysVarAllowList{"$MNUTOOL*"}}
+	setSnapshotValues(mock.mockPLCClient)
+
+	golden, err := client.TakeSnapshot(snapshotSpec)
+	if err != nil {
+		t.Fatalf("TakeSnapshot returned error: %v", err)
+	}
+
+	// The changeover changed a register and the tool frame
+	mock.readResponses["R[1]"] = int32(7)
+	mock.readResponses["$MNUTOOL[1,2].$Z"] = float32(25)
+
+	diffs, err := client.ApplySnapshot(golden, ApplyOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("Dry run returned error: %v", err)
+	}
+	if len(diffs) != 2 || diffs[0].String() != "R[1]: 7 -> 5" || diffs[1].Item != "UTOOL[2]" {
+		t.Errorf("Unexpected dry run %v", diffs)
+	}
+	if len(mock.writeCalls) != 0 {
+		t.Errorf("Expected no writes in a dry run, got %v", mock.writeCalls)
+	}
+
+	diffs, err = client.ApplySnapshot(golden, ApplyOptions{})
+	if err != nil || len(diffs) != 2 {
+		t.Fatalf("Expected two restored values, got %v (%v)", diffs, err)
+	}
+	if mock.writeCalls["R[1]"] != int32(5) || mock.writeCalls["$MNUTOOL[1,2].$Z"] != float32(20) {
+		t.Errorf("Unexpected writes %v", mock.writeCalls)
+	}
+	if _, ok := mock.writeCalls["SR[1]"]; ok {
+		t.Error("Expected unchanged registers not to be written")
+	}
+
+	delete(mock.readResponses, "SR[1]")
+	if _, err := client.ApplySnapshot(golden, ApplyOptions{}); err == nil {
+		t.Error("Expected error when the current values cannot be read")
+	}
+}
@@ -0,0 +1,234 @@
+package fanuc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/carun/cpppo-go/pkg/cpppo"
+)
+
+// UOP output signals (UO[n]) in their standard assignment
+const (
+	UOCmdEnabled     = 1  // CMDENBL: the robot accepts remote commands
+	UOSystemReady    = 2  // SYSRDY: servo power is on
+	UOProgramRunning = 3  // PROGRUN: a program is running
+	UOPaused         = 4  // PAUSED: a program is paused
+	UOHeld           = 5  // HELD: the hold input is active
+	UOFault          = 6  // FAULT: an alarm is active
+	UOAtPerch        = 7  // ATPERCH: the robot is at its reference position
+	UOTPEnabled      = 8  // TPENBL: the teach pendant is enabled
+	UOBatteryAlarm   = 9  // BATALM: the encoder battery is low
+	UOBusy           = 10 // BUSY: the controller is processing
+)
+
+// System variables read by Status
+const (
+	sysVarOperatingMode = "$MCR.$OP_MODE"      // 1 = T1, 2 = T2, 3 = AUTO
+	sysVarOverride      = "$MCR.$GENOVERRIDE"  // Speed override in percent
+	sysVarProgramName   = "$TSR[1].$PROG_NAME" // Program of the first task
+	sysVarProgramLine   = "$TSR[1].$CURR_LINE" // Line executing in that program
+)
+
+// OperatingMode is the position of the mode switch on the operator panel
+type OperatingMode int
+
+const (
+	ModeUnknown OperatingMode = iota // Not reported by the controller
+	ModeT1                           // Teach, limited to 250 mm/s
+	ModeT2                           // Teach at full speed
+	ModeAuto                         // Automatic operation
+)
+
+// String returns the mode as the controller shows it
+func (m OperatingMode) String() string {
+	switch m {
+	case ModeT1:
+		return "T1"
+	case ModeT2:
+		return "T2"
+	case ModeAuto:
+		return "AUTO"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// ProgramState tells whether the selected program is running
+type ProgramState int
+
+const (
+	ProgramAborted ProgramState = iota // Not running and not paused
+	ProgramRunning
+	ProgramPaused
+)
+
+// String returns the name of the state
+func (s ProgramState) String() string {
+	switch s {
+	case ProgramAborted:
+		return "aborted"
+	case ProgramRunning:
+		return "running"
+	case ProgramPaused:
+		return "paused"
+	default:
+		return fmt.Sprintf("ProgramState(%d)", int(s))
+	}
+}
+
+// Status is a snapshot of the robot's state, gathered from UOP outputs and
+// system variables
+type Status struct {
+	Mode         OperatingMode
+	Override     int    // Speed override in percent
+	Program      string // Program of the first task, empty if none
+	Line         int    // Line executing in Program
+	ProgramState ProgramState
+
+	ServoReady     bool // Servo power is on (SYSRDY)
+	Fault          bool // An alarm is active (FAULT)
+	Held           bool // The hold input is active (HELD)
+	CommandEnabled bool // Remote commands are accepted (CMDENBL)
+	TPEnabled      bool // The teach pendant is enabled (TPENBL)
+	Busy           bool // The controller is processing (BUSY)
+}
+
+// Status reads the robot's state in one request when the client can batch
+func (f *FanucClient) Status() (*Status, error) {
+	reads := []cpppo.TagRead{
+		{Name: sysVarOperatingMode, DataType: cpppo.CIPDataTypeDINT},
+		{Name: sysVarOverride, DataType: cpppo.CIPDataTypeDINT},
+		{Name: sysVarProgramName, DataType: cpppo.CIPDataTypeSTRING},
+		{Name: sysVarProgramLine, DataType: cpppo.CIPDataTypeDINT},
+	}
+	for i := UOCmdEnabled; i <= UOBusy; i++ {
+		reads = append(reads, cpppo.TagRead{Name: buildRegisterTag(RegisterTypeUO, i), DataType: cpppo.CIPDataTypeBOOL})
+	}
+
+	results, err := f.readComponents(reads)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read status: %w", err)
+	}
+
+	values := make(map[string]interface{}, len(reads))
+	for i, result := range results {
+		if result.Err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", reads[i].Name, result.Err)
+		}
+		values[reads[i].Name] = result.Value
+	}
+
+	// Typed accessors; the first type error is kept in err
+	typeError := func(name string) {
+		if err == nil {
+			err = fmt.Errorf("unexpected type %T for %s", values[name], name)
+		}
+	}
+	integer := func(name string) int32 {
+		value, ok := values[name].(int32)
+		if !ok {
+			typeError(name)
+		}
+		return value
+	}
+	text := func(name string) string {
+		value, ok := values[name].(string)
+		if !ok {
+			typeError(name)
+		}
+		return value
+	}
+	signal := func(index int) bool {
+		name := buildRegisterTag(RegisterTypeUO, index)
+		value, ok := values[name].(bool)
+		if !ok {
+			typeError(name)
+		}
+		return value
+	}
+
+	status := &Status{
+		Override:       int(integer(sysVarOverride)),
+		Program:        text(sysVarProgramName),
+		Line:           int(integer(sysVarProgramLine)),
+		ServoReady:     signal(UOSystemReady),
+		Fault:          signal(UOFault),
+		Held:           signal(UOHeld),
+		CommandEnabled: signal(UOCmdEnabled),
+		TPEnabled:      signal(UOTPEnabled),
+		Busy:           signal(UOBusy),
+	}
+
+	if mode := OperatingMode(integer(sysVarOperatingMode)); mode >= ModeT1 && mode <= ModeAuto {
+		status.Mode = mode
+	}
+
+	switch {
+	case signal(UOProgramRunning):
+		status.ProgramState = ProgramRunning
+	case signal(UOPaused):
+		status.ProgramState = ProgramPaused
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// StatusUpdate is one notification of a status stream
+type StatusUpdate struct {
+	Time   time.Time // When the read completed
+	Status *Status   // Nil if the read failed
+	Err    error
+}
+
+// WatchStatus reads the status every interval and sends an update on the
+// returned channel whenever it changes, starting with the first reading. A
+// failed read is sent once until a read succeeds or fails differently. The
+// channel is closed when ctx is canceled. An interval of 0 means
+// DefaultStreamInterval.
+func (f *FanucClient) WatchStatus(ctx context.Context, interval time.Duration) (<-chan StatusUpdate, error) {
+	if interval == 0 {
+		interval = DefaultStreamInterval
+	}
+	if interval < 0 {
+		return nil, errors.New("watch interval must not be negative")
+	}
+
+	updates := make(chan StatusUpdate, 16)
+
+	go func() {
+		defer close(updates)
+
+		var last *StatusUpdate
+		sampleEvery(ctx, interval, func() bool {
+			status, err := f.Status()
+			update := StatusUpdate{Time: time.Now(), Status: status, Err: err}
+			if last != nil && sameStatus(*last, update) {
+				return true
+			}
+			last = &update
+
+			select {
+			case updates <- update:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		})
+	}()
+
+	return updates, nil
+}
+
+// sameStatus reports whether two updates carry the same status or the same
+// error
+func sameStatus(a, b StatusUpdate) bool {
+	if a.Err != nil || b.Err != nil {
+		return a.Err != nil && b.Err != nil && a.Err.Error() == b.Err.Error()
+	}
+	return *a.Status == *b.Status
+}
@@ -0,0 +1,136 @@
+package fanuc
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// setStatus fills the mock with a running program in AUTO
+func setStatus(mock *mockPLCClient) {
+	mock.readResponses[sysVarOperatingMode] = int32(3)
+	mock.readResponses[sysVarOverride] = int32(50)
+	mock.readResponses[sysVarProgramName] = "MAIN"
+	mock.readResponses[sysVarProgramLine] = int32(12)
+	for i := UOCmdEnabled; i <= UOBusy; i++ {
+		mock.readResponses[buildRegisterTag(RegisterTypeUO, i)] = false
+	}
+	mock.readResponses["UO[1]"] = true
+	mock.readResponses["UO[2]"] = true
+	mock.readResponses["UO[3]"] = true
+}
+
+func TestStatus(t *testing.T) {
+	mock := &batchMockPLCClient{mockPLCClient: newMockPLCClient()}
+	client := &FanucClient{PLCClient: mock}
+	setStatus(mock.mockPLCClient)
+
+	status, err := client.Status()
+	if err != nil {
+		t.Fatalf("Status returned error: %v", err)
+	}
+
+	expected := Status{
+		Mode:           ModeAuto,
+		Override:       50,
+		Program:        "MAIN",
+		Line:           12,
+		ProgramState:   ProgramRunning,
+		ServoReady:     true,
+		CommandEnabled: true,
+	}
+	if *status != expected {
+		t.Errorf("Expected %+v, got %+v", expected, *status)
+	}
+	if mock.batches != 1 {
+		t.Errorf("Expected one request, got %d", mock.batches)
+	}
+
+	// Paused with a fault
+	mock.readResponses["UO[3]"] = false
+	mock.readResponses["UO[4]"] = true
+	mock.readResponses["UO[6]"] = true
+	status, err = client.Status()
+	if err != nil || status.ProgramState != ProgramPaused || !status.Fault {
+		t.Errorf("Expected paused with fault, got %+v (%v)", status, err)
+	}
+
+	delete(mock.readResponses, sysVarProgramName)
+	if _, err := client.Status(); err == nil {
+		t.Error("Expected error for missing program name")
+	}
+}
+
+// statusClient guards the mock so a test can change it while WatchStatus
+// reads it
+type statusClient struct {
+	mu sync.Mutex
+	*mockPLCClient
+}
+
+func (c *statusClient) ReadTag(tagName string, dataType byte) (interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.mockPLCClient.ReadTag(tagName, dataType)
+}
+
+func TestWatchStatus(t *testing.T) {
+	mock := &statusClient{mockPLCClient: newMockPLCClient()}
+	client := &FanucClient{PLCClient: mock}
+	setStatus(mock.mockPLCClient)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	updates, err := client.WatchStatus(ctx, time.Millisecond)
+	if err != nil {
+		t.Fatalf("WatchStatus returned error: %v", err)
+	}
+
+	first := <-updates
+	if first.Err != nil || first.Status.Override != 50 {
+		t.Fatalf("Unexpected first update %+v", first)
+	}
+
+	// Unchanged readings are not sent
+	time.Sleep(10 * time.Millisecond)
+	select {
+	case update := <-updates:
+		t.Fatalf("Expected no update without a change, got %+v", update)
+	default:
+	}
+
+	mock.mu.Lock()
+	mock.readResponses[sysVarOverride] = int32(100)
+	mock.mu.Unlock()
+
+	second := <-updates
+	if second.Err != nil || second.Status.Override != 100 {
+		t.Errorf("Expected override 100, got %+v", second)
+	}
+
+	// One error update, however often the read fails
+	mock.mu.Lock()
+	delete(mock.readResponses, sysVarOverride)
+	mock.mu.Unlock()
+
+	if update := <-updates; update.Err == nil {
+		t.Errorf("Expected error update, got %+v", update)
+	}
+	time.Sleep(10 * time.Millisecond)
+	select {
+	case update := <-updates:
+		t.Fatalf("Expected the error once, got %+v", update)
+	default:
+	}
+
+	cancel()
+	for range updates {
+		// Drain until the stream closes
+	}
+
+	if _, err := client.WatchStatus(ctx, -time.Second); err == nil {
+		t.Error("Expected error for negative interval")
+	}
+}
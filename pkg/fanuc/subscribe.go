@@ -0,0 +1,207 @@
+package fanuc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Subscription asks for the changes of one register or I/O point
+type Subscription struct {
+	Ref      RegisterRef
+	Interval time.Duration // Time between polls, 0 means DefaultStreamInterval
+
+	// Deadband is the largest change of a numeric value, or of any component
+	// of a position or frame, that is not reported. 0 reports every change.
+	Deadband float64
+}
+
+// RegisterChange is sent when a subscribed register changes
+type RegisterChange struct {
+	Time time.Time
+	Ref  RegisterRef
+	Old  interface{} // Last value sent, nil for the first reading
+	New  interface{} // Nil if the read failed
+	Err  error
+}
+
+// Subscribe polls the registers of subs and sends a change on the returned
+// channel whenever a value moves by more than its deadband, starting with the
+// first reading of each register. Subscriptions with the same interval are
+// read together, in batches when the client can batch.
+//
+// A failed read is sent once until the register reads again or fails
+// differently. The client reconnects on its own; the first value read after
+// a failure is always sent, so the receiver knows the register is back. The
+// channel is closed when ctx is canceled.
+func (f *FanucClient) Subscribe(ctx context.Context, subs ...Subscription) (<-chan RegisterChange, error) {
+	if len(subs) == 0 {
+		return nil, errors.New("no subscriptions")
+	}
+
+	// Group the subscriptions by poll interval
+	groups := make(map[time.Duration][]Subscription)
+	for _, sub := range subs {
+		if sub.Interval < 0 {
+			return nil, fmt.Errorf("%s: interval must not be negative", sub.Ref)
+		}
+		if sub.Deadband < 0 || math.IsNaN(sub.Deadband) {
+			return nil, fmt.Errorf("%s: deadband must not be negative", sub.Ref)
+		}
+		if sub.Interval == 0 {
+			sub.Interval = DefaultStreamInterval
+		}
+		groups[sub.Interval] = append(groups[sub.Interval], sub)
+	}
+
+	intervals := make([]time.Duration, 0, len(groups))
+	for interval := range groups {
+		intervals = append(intervals, interval)
+	}
+	sort.Slice(intervals, func(i, j int) bool { return intervals[i] < intervals[j] })
+
+	changes := make(chan RegisterChange, 16)
+
+	var wg sync.WaitGroup
+	for _, interval := range intervals {
+		wg.Add(1)
+		go func(interval time.Duration, subs []Subscription) {
+			defer wg.Done()
+			f.pollSubscriptions(ctx, interval, subs, changes)
+		}(interval, groups[interval])
+	}
+
+	go func() {
+		wg.Wait()
+		close(changes)
+	}()
+
+	return changes, nil
+}
+
+// subscriptionState is what was last sent for one subscription
+type subscriptionState struct {
+	sent  bool        // Something was sent
+	value interface{} // Last value sent
+	err   error       // Last error sent, nil once a value is read again
+}
+
+// pollSubscriptions reads subs every interval and sends their changes until
+// ctx is canceled
+func (f *FanucClient) pollSubscriptions(ctx context.Context, interval time.Duration, subs []Subscription, changes chan<- RegisterChange) {
+	set := make(RegisterSet, len(subs))
+	for i, sub := range subs {
+		set[i] = sub.Ref
+	}
+	states := make([]subscriptionState, len(subs))
+
+	sampleEvery(ctx, interval, func() bool {
+		results, err := f.ReadRegisterSet(set)
+		now := time.Now()
+
+		for i, sub := range subs {
+			change := RegisterChange{Time: now, Ref: sub.Ref, Old: states[i].value}
+			if err != nil {
+				change.Err = err
+			} else {
+				change.New, change.Err = results[i].Value, results[i].Err
+			}
+
+			if !states[i].update(change, sub.Deadband) {
+				continue
+			}
+			select {
+			case changes <- change:
+			case <-ctx.Done():
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// update records a reading and reports whether it must be sent
+func (s *subscriptionState) update(change RegisterChange, deadband float64) bool {
+	if change.Err != nil {
+		if s.err != nil && s.err.Error() == change.Err.Error() {
+			return false
+		}
+		s.err = change.Err
+		return true
+	}
+
+	recovered := s.err != nil
+	s.err = nil
+	if s.sent && !recovered && withinDeadband(s.value, change.New, deadband) {
+		return false
+	}
+	s.sent = true
+	s.value = change.New
+	return true
+}
+
+// withinDeadband reports whether b differs from a by no more than deadband.
+// Numbers, positions and frames are compared component by component; other
+// values must be equal.
+func withinDeadband(a, b interface{}, deadband float64) bool {
+	if x, ok := numberOf(a); ok {
+		y, ok := numberOf(b)
+		return ok && math.Abs(x-y) <= deadband
+	}
+
+	switch a := a.(type) {
+	case *Position:
+		b, ok := b.(*Position)
+		if !ok || a == nil || b == nil {
+			return ok && a == b
+		}
+		if a.Representation != b.Representation || a.Group != b.Group ||
+			a.Config != b.Config || !reflect.DeepEqual(a.Frames, b.Frames) {
+			return false
+		}
+		return componentsWithin([]float32{a.X, a.Y, a.Z, a.W, a.P, a.R}, []float32{b.X, b.Y, b.Z, b.W, b.P, b.R}, deadband) &&
+			componentsWithin(a.Extensions, b.Extensions, deadband) &&
+			componentsWithin(a.Joints, b.Joints, deadband)
+	case *Frame:
+		b, ok := b.(*Frame)
+		if !ok || a == nil || b == nil {
+			return ok && a == b
+		}
+		return componentsWithin([]float32{a.X, a.Y, a.Z, a.W, a.P, a.R}, []float32{b.X, b.Y, b.Z, b.W, b.P, b.R}, deadband)
+	default:
+		return reflect.DeepEqual(a, b)
+	}
+}
+
+// numberOf returns the value of a numeric register or signal
+func numberOf(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int32:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case NumericValue:
+		return v.Float64(), true
+	default:
+		return 0, false
+	}
+}
+
+// componentsWithin reports whether a and b have the same length and no
+// component differs by more than deadband
+func componentsWithin(a, b []float32, deadband float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if math.Abs(float64(a[i])-float64(b[i])) > deadband {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,215 @@
+package fanuc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/carun/cpppo-go/pkg/cpppo"
+)
+
+// subscriptionClient is a batching client whose values and link can change
+// while subscriptions poll it
+type subscriptionClient struct {
+	mu      sync.Mutex
+	values  map[string]interface{}
+	down    bool // Requests fail as if the connection dropped
+	batches int
+}
+
+func (c *subscriptionClient) set(tagName string, value interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values[tagName] = value
+}
+
+func (c *subscriptionClient) setDown(down bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.down = down
+}
+
+func (c *subscriptionClient) ReadTag(tagName string, dataType byte) (interface{}, error) {
+	results, err := c.ReadTags([]cpppo.TagRead{{Name: tagName, DataType: dataType}})
+	if err != nil {
+		return nil, err
+	}
+	return results[0].Value, results[0].Err
+}
+
+func (c *subscriptionClient) ReadTags(reads []cpppo.TagRead) ([]cpppo.TagResult, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.down {
+		return nil, fmt.Errorf("%w: connection reset by peer", cpppo.ErrConnectionLost)
+	}
+
+	c.batches++
+	results := make([]cpppo.TagResult, len(reads))
+	for i, read := range reads {
+		value, ok := c.values[read.Name]
+		if !ok {
+			results[i].Err = cpppo.CIPError{Code: 0x05, ExtendedMsg: "Path destination unknown"}
+			continue
+		}
+		results[i].Value = value
+	}
+	return results, nil
+}
+
+func (c *subscriptionClient) WriteTag(tagName string, dataType byte, value interface{}) error {
+	return errors.New("read-only")
+}
+
+func (c *subscriptionClient) WriteTags(writes []cpppo.TagWrite) ([]error, error) {
+	return nil, errors.New("read-only")
+}
+
+func (c *subscriptionClient) Close() error {
+	return nil
+}
+
+// expectChange waits for the next change
+func expectChange(t *testing.T, changes <-chan RegisterChange) RegisterChange {
+	t.Helper()
+	select {
+	case change := <-changes:
+		return change
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for a change")
+		return RegisterChange{}
+	}
+}
+
+// expectNoChange fails if a change arrives within a few polls
+func expectNoChange(t *testing.T, changes <-chan RegisterChange) {
+	t.Helper()
+	select {
+	case change := <-changes:
+		t.Fatalf("Expected no change, got %+v", change)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	mock := &subscriptionClient{values: map[string]interface{}{
+		"R[1]":  float32(10),
+		"DI[3]": false,
+	}}
+	client := &FanucClient{PLCClient: mock}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	r1 := RegisterRef{Type: RegisterTypeR, Index: 1}
+	di3 := RegisterRef{Type: RegisterTypeDI, Index: 3}
+	changes, err := client.Subscribe(ctx,
+		Subscription{Ref: r1, Interval: time.Millisecond, Deadband: 0.5},
+		Subscription{Ref: di3, Interval: time.Millisecond},
+	)
+	if err != nil {
+		t.Fatalf("Subscribe returned error: %v", err)
+	}
+
+	// Both registers are reported once at first, from one request
+	first := map[RegisterRef]RegisterChange{}
+	for i := 0; i < 2; i++ {
+		change := expectChange(t, changes)
+		first[change.Ref] = change
+	}
+	if first[r1].New != float32(10) || first[r1].Old != nil || first[di3].New != false {
+		t.Errorf("Unexpected first changes %+v", first)
+	}
+	mock.mu.Lock()
+	if mock.batches < 1 {
+		t.Error("Expected batched reads")
+	}
+	mock.mu.Unlock()
+	expectNoChange(t, changes)
+
+	// Changes within the deadband are not reported
+	mock.set("R[1]", float32(10.4))
+	expectNoChange(t, changes)
+
+	mock.set("R[1]", float32(11))
+	change := expectChange(t, changes)
+	if change.Ref != r1 || change.Old != float32(10) || change.New != float32(11) {
+		t.Errorf("Unexpected change %+v", change)
+	}
+
+	mock.set("DI[3]", true)
+	change = expectChange(t, changes)
+	if change.Ref != di3 || change.Old != false || change.New != true {
+		t.Errorf("Unexpected change %+v", change)
+	}
+
+	// A dropped link is reported once per register
+	mock.setDown(true)
+	for i := 0; i < 2; i++ {
+		change = expectChange(t, changes)
+		if !errors.Is(change.Err, cpppo.ErrConnectionLost) || change.New != nil {
+			t.Errorf("Expected connection lost, got %+v", change)
+		}
+	}
+	expectNoChange(t, changes)
+
+	// Once reconnected, every register is reported again
+	mock.setDown(false)
+	back := map[RegisterRef]RegisterChange{}
+	for i := 0; i < 2; i++ {
+		change = expectChange(t, changes)
+		back[change.Ref] = change
+	}
+	if back[r1].New != float32(11) || back[r1].Old != float32(11) || back[di3].New != true || back[di3].Err != nil {
+		t.Errorf("Unexpected changes after reconnecting %+v", back)
+	}
+
+	cancel()
+	for range changes {
+		// Drain until the stream closes
+	}
+}
+
+func TestSubscribeErrors(t *testing.T) {
+	client := &FanucClient{PLCClient: newMockPLCClient()}
+	ref := RegisterRef{Type: RegisterTypeR, Index: 1}
+
+	if _, err := client.Subscribe(context.Background()); err == nil {
+		t.Error("Expected error without subscriptions")
+	}
+	if _, err := client.Subscribe(context.Background(), Subscription{Ref: ref, Interval: -time.Second}); err == nil {
+		t.Error("Expected error for negative interval")
+	}
+	if _, err := client.Subscribe(context.Background(), Subscription{Ref: ref, Deadband: -1}); err == nil {
+		t.Error("Expected error for negative deadband")
+	}
+}
+
+func TestWithinDeadband(t *testing.T) {
+	tests := []struct {
+		a, b     interface{}
+		deadband float64
+		expected bool
+	}{
+		{int32(5), int32(5), 0, true},
+		{int32(5), int32(6), 0, false},
+		{int32(5), float32(5.5), 1, true},
+		{float32(1), "1", 1, false},
+		{"MAIN", "MAIN", 0, true},
+		{true, false, 1, false},
+		{&Frame{X: 1}, &Frame{X: 1.2}, 0.5, true},
+		{&Frame{X: 1}, &Frame{X: 2}, 0.5, false},
+		{&Position{X: 1, Joints: []float32{1}}, &Position{X: 1.1, Joints: []float32{1.1}}, 0.2, true},
+		{&Position{X: 1}, &Position{X: 1, Group: 2}, 1, false},
+		{&Position{Joints: []float32{1}}, &Position{Joints: []float32{1, 2}}, 1, false},
+	}
+
+	for _, test := range tests {
+		if got := withinDeadband(test.a, test.b, test.deadband); got != test.expected {
+			t.Errorf("withinDeadband(%v, %v, %v) = %v, expected %v", test.a, test.b, test.deadband, got, test.expected)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package fanuc
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/carun/cpppo-go/pkg/cpppo"
+)
+
+// maxSysVarPath is the longest path a symbolic segment can carry
+const maxSysVarPath = 255
+
+// sysVarSegment matches one member of a system variable path, such as
+// "$SCR_GRP[1]" or "$MNUFRAME[1,2]"
+var sysVarSegment = regexp.MustCompile(`^\$[A-Z][A-Z0-9_]*(\[[0-9]+(,[0-9]+)?\])?$`)
+
+// ErrSysVarWriteDenied is returned when a system variable write is not
+// allowed by the client's SysVarWrites list
+var ErrSysVarWriteDenied = errors.New("system variable write not allowed")
+
+// NormalizeSysVarPath checks a system variable path such as
+// "$MCR.$GENOVERRIDE" and returns it in upper case
+func NormalizeSysVarPath(path string) (string, error) {
+	normalized := strings.ToUpper(strings.TrimSpace(path))
+	if normalized == "" {
+		return "", errors.New("invalid system variable path: empty")
+	}
+	if len(normalized) > maxSysVarPath {
+		return "", fmt.Errorf("invalid system variable path %q: longer than %d characters", path, maxSysVarPath)
+	}
+
+	for _, segment := range strings.Split(normalized, ".") {
+		if !sysVarSegment.MatchString(segment) {
+			return "", fmt.Errorf("invalid system variable path %q: bad member %q", path, segment)
+		}
+	}
+
+	return normalized, nil
+}
+
+// SysVarAllowList holds the system variables a client may write. An entry is
+// either a full path or a prefix ending in "*", such as "$MCR.*" or
+// "$SCR_GRP[1].$MCH_POS_*". Entries are not case sensitive. The zero value
+// allows nothing.
+type SysVarAllowList []string
+
+// Allows reports whether the list permits writing path, which must already
+// be normalized
+func (l SysVarAllowList) Allows(path string) bool {
+	for _, entry := range l {
+		entry = strings.ToUpper(strings.TrimSpace(entry))
+		if prefix, ok := strings.CutSuffix(entry, "*"); ok {
+			if prefix != "" && strings.HasPrefix(path, prefix) {
+				return true
+			}
+		} else if entry == path {
+			return true
+		}
+	}
+	return false
+}
+
+// checkSysVarWrites returns ErrSysVarWriteDenied unless the client's
+// SysVarWrites list allows every write
+func (f *FanucClient) checkSysVarWrites(writes []cpppo.TagWrite) error {
+	for _, write := range writes {
+		if !f.SysVarWrites.Allows(write.Name) {
+			return fmt.Errorf("%s: %w", write.Name, ErrSysVarWriteDenied)
+		}
+	}
+	return nil
+}
+
+// ReadSysVar reads a system variable, such as "$MCR.$GENOVERRIDE" with
+// cpppo.CIPDataTypeDINT, and returns its value as the Go type of dataType
+func (f *FanucClient) ReadSysVar(path string, dataType byte) (interface{}, error) {
+	normalized, err := NormalizeSysVarPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := f.PLCClient.ReadTag(normalized, dataType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", normalized, err)
+	}
+	return value, nil
+}
+
+// WriteSysVar writes a system variable. System variables configure the
+// controller itself, so writes are refused with ErrSysVarWriteDenied unless
+// the path is in the client's SysVarWrites list. Integers of any Go type are
+// accepted for DINT variables.
+func (f *FanucClient) WriteSysVar(path string, dataType byte, value interface{}) error {
+	normalized, err := NormalizeSysVarPath(path)
+	if err != nil {
+		return err
+	}
+
+	if !f.SysVarWrites.Allows(normalized) {
+		return fmt.Errorf("%s: %w", normalized, ErrSysVarWriteDenied)
+	}
+
+	if dataType == cpppo.CIPDataTypeDINT {
+		v, err := toInt32(value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", normalized, err)
+		}
+		value = v
+	}
+
+	if err := f.PLCClient.WriteTag(normalized, dataType, value); err != nil {
+		return fmt.Errorf("failed to write %s: %w", normalized, err)
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package fanuc
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/carun/cpppo-go/pkg/cpppo"
+)
+
+func TestNormalizeSysVarPath(t *testing.T) {
+	valid := map[string]string{
+		"$MCR.$GENOVERRIDE":           "$MCR.$GENOVERRIDE",
+		"$scr_grp[1].$mch_pos_x":      "$SCR_GRP[1].$MCH_POS_X",
+		" $TP_DEFPROG ":               "$TP_DEFPROG",
+		"$MNUFRAME[1,2].$X":           "$MNUFRAME[1,2].$X",
+		"$PARAM_GROUP[1].$PAYLOAD[2]": "$PARAM_GROUP[1].$PAYLOAD[2]",
+	}
+	for path, expected := range valid {
+		normalized, err := NormalizeSysVarPath(path)
+		if err != nil || normalized != expected {
+			t.Errorf("%q: expected %q, got %q (%v)", path, expected, normalized, err)
+		}
+	}
+
+	for _, path := range []string{
+		"",
+		"MCR",
+		"$MCR.GENOVERRIDE",
+		"$MCR..$X",
+		"$SCR_GRP[].$X",
+		"$SCR_GRP[1",
+		"$1ABC",
+		"$MCR;$X",
+		"$" + strings.Repeat("A", 300),
+	} {
+		if _, err := NormalizeSysVarPath(path); err == nil {
+			t.Errorf("%q: expected error", path)
+		}
+	}
+}
+
+func TestSysVarAllowList(t *testing.T) {
+	list := SysVarAllowList{"$mcr.$genoverride", "$SCR_GRP[1].$MCH_POS_*", "$TP_*"}
+
+	for path, allowed := range map[string]bool{
+		"$MCR.$GENOVERRIDE":      true,
+		"$MCR.$GENOVERRIDE2":     false,
+		"$SCR_GRP[1].$MCH_POS_X": true,
+		"$SCR_GRP[2].$MCH_POS_X": false,
+		"$TP_DEFPROG":            true,
+		"$MNUFRAME[1,1]":         false,
+	} {
+		if list.Allows(path) != allowed {
+			t.Errorf("%s: expected allowed = %v", path, allowed)
+		}
+	}
+
+	// A bare wildcard does not open everything
+	if (SysVarAllowList{"*"}).Allows("$MCR.$GENOVERRIDE") {
+		t.Error("Expected a bare * to allow nothing")
+	}
+	if (SysVarAllowList{}).Allows("$MCR.$GENOVERRIDE") {
+		t.Error("Expected the empty list to allow nothing")
+	}
+}
+
+func TestReadWriteSysVar(t *testing.T) {
+	mock := newMockPLCClient()
+	client := &FanucClient{PLCClient: mock}
+
+	mock.readResponses["$MCR.$GENOVERRIDE"] = int32(50)
+	value, err := client.ReadSysVar("$mcr.$genoverride", cpppo.CIPDataTypeDINT)
+	if err != nil || value != int32(50) {
+		t.Errorf("Expected 50, got %v (%v)", value, err)
+	}
+	if _, err := client.ReadSysVar("MCR.GENOVERRIDE", cpppo.CIPDataTypeDINT); err == nil {
+		t.Error("Expected error for invalid path")
+	}
+
+	// Writes are denied by default
+	err = client.WriteSysVar("$MCR.$GENOVERRIDE", cpppo.CIPDataTypeDINT, 10)
+	if !errors.Is(err, ErrSysVarWriteDenied) {
+		t.Errorf("Expected ErrSysVarWriteDenied, got %v", err)
+	}
+	if len(mock.writeCalls) != 0 {
+		t.Errorf("Expected nothing to be written, got %v", mock.writeCalls)
+	}
+
+	client.SysVarWrites = SysVarAllowList{"$MCR.$GENOVERRIDE"}
+	if err := client.WriteSysVar("$MCR.$GENOVERRIDE", cpppo.CIPDataTypeDINT, 10); err != nil {
+		t.Fatalf("Failed to write allowed variable: %v", err)
+	}
+	if mock.writeCalls["$MCR.$GENOVERRIDE"] != int32(10) {
+		t.Errorf("Expected int32(10) to be written, got %#v", mock.writeCalls["$MCR.$GENOVERRIDE"])
+	}
+
+	mock.writeResponses["$MCR.$GENOVERRIDE"] = cpppo.CIPError{Code: 0x0F, ExtendedMsg: "Privilege violation"}
+	if err := client.WriteSysVar("$MCR.$GENOVERRIDE", cpppo.CIPDataTypeDINT, 20); err == nil {
+		t.Error("Expected error from controller")
+	}
+}
@@ -0,0 +1,389 @@
+package fanuc
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// NumericRegister is an R register with its comment, as found in NUMREG.VA
+type NumericRegister struct {
+	Index   int
+	Value   NumericValue
+	Comment string
+}
+
+// PositionRegister is a PR register with its comment, as found in POSREG.VA
+type PositionRegister struct {
+	Index    int
+	Comment  string
+	Position *Position // Nil if the register is uninitialized
+}
+
+// FrameID identifies a user or tool frame of a motion group
+type FrameID struct {
+	Group  int
+	Number int
+}
+
+// SystemFrames are the frames found in SYSFRAME.VA. Uninitialized frames
+// are left out.
+type SystemFrames struct {
+	UserFrames map[FrameID]*Frame
+	ToolFrames map[FrameID]*Frame
+	Active     map[int]FrameNumbers // Selected frames by motion group
+}
+
+// vaSection is one variable of a .VA file, such as
+//
+//	[*NUMREG*]$NUMREG  Storage: CMOS  Access: RW  : ARRAY[200] OF Numeric Reg
+type vaSection struct {
+	name    string // Variable name with its $
+	entries []vaEntry
+}
+
+// vaEntry is one array element of a section, such as " [1,2] = 'HOME'" and
+// the lines that follow it
+type vaEntry struct {
+	index []int    // Array indexes, one per dimension
+	value string   // Text after "=", trimmed
+	lines []string // Continuation lines, trimmed
+	line  int      // Line of the element, for errors
+}
+
+var (
+	vaHeader  = regexp.MustCompile(`^\[\*[^*]*\*\](\$\S+)`)
+	vaElement = regexp.MustCompile(`^\s*\[([0-9, ]+)\]\s*=\s*(.*)$`)
+)
+
+// parseVA splits a .VA file into sections and array elements. Lines before
+// the first section and blank lines are ignored.
+func parseVA(r io.Reader) ([]vaSection, error) {
+	var sections []vaSection
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimRight(scanner.Text(), " \t\r")
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+
+		if match := vaHeader.FindStringSubmatch(text); match != nil {
+			sections = append(sections, vaSection{name: strings.ToUpper(match[1])})
+			continue
+		}
+		if len(sections) == 0 {
+			continue
+		}
+		section := &sections[len(sections)-1]
+
+		if match := vaElement.FindStringSubmatch(text); match != nil {
+			var index []int
+			for _, field := range strings.Split(match[1], ",") {
+				n, err := strconv.Atoi(strings.TrimSpace(field))
+				if err != nil {
+					return nil, fmt.Errorf("line %d: bad index %q", line, match[1])
+				}
+				index = append(index, n)
+			}
+			section.entries = append(section.entries, vaEntry{index: index, value: strings.TrimSpace(match[2]), line: line})
+			continue
+		}
+
+		if len(section.entries) > 0 {
+			entry := &section.entries[len(section.entries)-1]
+			entry.lines = append(entry.lines, strings.TrimSpace(text))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return sections, nil
+}
+
+// findVASection returns the section of the named variable, or nil
+func findVASection(sections []vaSection, name string) *vaSection {
+	for i := range sections {
+		if sections[i].name == name {
+			return &sections[i]
+		}
+	}
+	return nil
+}
+
+// splitVAComment splits "value  'comment'" into the text around the comment
+// and the comment
+func splitVAComment(text string) (string, string, error) {
+	start := strings.IndexByte(text, '\'')
+	if start < 0 {
+		return text, "", nil
+	}
+	end := strings.LastIndexByte(text, '\'')
+	if end == start {
+		return "", "", fmt.Errorf("unterminated comment in %q", text)
+	}
+	return strings.TrimSpace(text[:start] + " " + text[end+1:]), text[start+1 : end], nil
+}
+
+// ParseNumRegVA parses the $NUMREG variable of a NUMREG.VA file. Values
+// written without a decimal point or exponent are integers.
+func ParseNumRegVA(r io.Reader) ([]NumericRegister, error) {
+	sections, err := parseVA(r)
+	if err != nil {
+		return nil, err
+	}
+	section := findVASection(sections, "$NUMREG")
+	if section == nil {
+		return nil, errors.New("no $NUMREG variable")
+	}
+
+	registers := make([]NumericRegister, 0, len(section.entries))
+	for _, entry := range section.entries {
+		if len(entry.index) != 1 {
+			return nil, fmt.Errorf("line %d: expected one index for $NUMREG", entry.line)
+		}
+		text, comment, err := splitVAComment(entry.value)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", entry.line, err)
+		}
+		value, err := parseVANumber(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", entry.line, err)
+		}
+		registers = append(registers, NumericRegister{Index: entry.index[0], Value: value, Comment: comment})
+	}
+	return registers, nil
+}
+
+// parseVANumber parses an R register value, keeping its kind
+func parseVANumber(text string) (NumericValue, error) {
+	if n, err := strconv.ParseInt(text, 10, 32); err == nil {
+		return IntegerValue(int32(n)), nil
+	}
+	f, err := strconv.ParseFloat(text, 32)
+	if err != nil {
+		return NumericValue{}, fmt.Errorf("bad numeric value %q", text)
+	}
+	return RealValue(float32(f)), nil
+}
+
+// ParsePosRegVA parses the $POSREG variable of a POSREG.VA file. Registers
+// are indexed [group,n]; each Position has its motion group set.
+func ParsePosRegVA(r io.Reader) ([]PositionRegister, error) {
+	sections, err := parseVA(r)
+	if err != nil {
+		return nil, err
+	}
+	section := findVASection(sections, "$POSREG")
+	if section == nil {
+		return nil, errors.New("no $POSREG variable")
+	}
+
+	registers := make([]PositionRegister, 0, len(section.entries))
+	for _, entry := range section.entries {
+		if len(entry.index) != 2 {
+			return nil, fmt.Errorf("line %d: expected [group,index] for $POSREG", entry.line)
+		}
+		_, comment, err := splitVAComment(entry.value)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", entry.line, err)
+		}
+		position, err := parseVAPosition(entry)
+		if err != nil {
+			return nil, err
+		}
+		if position != nil {
+			position.Group = entry.index[0]
+		}
+		registers = append(registers, PositionRegister{Index: entry.index[1], Comment: comment, Position: position})
+	}
+	return registers, nil
+}
+
+// ParseSysFrameVA parses the user frames, tool frames and selected frame
+// numbers of a SYSFRAME.VA file
+func ParseSysFrameVA(r io.Reader) (*SystemFrames, error) {
+	sections, err := parseVA(r)
+	if err != nil {
+		return nil, err
+	}
+
+	frames := &SystemFrames{
+		UserFrames: make(map[FrameID]*Frame),
+		ToolFrames: make(map[FrameID]*Frame),
+		Active:     make(map[int]FrameNumbers),
+	}
+	for _, kind := range []struct {
+		name   string
+		frames map[FrameID]*Frame
+	}{
+		{"$MNUFRAME", frames.UserFrames},
+		{"$MNUTOOL", frames.ToolFrames},
+	} {
+		section := findVASection(sections, kind.name)
+		if section == nil {
+			return nil, fmt.Errorf("no %s variable", kind.name)
+		}
+		for _, entry := range section.entries {
+			if len(entry.index) != 2 {
+				return nil, fmt.Errorf("line %d: expected [group,frame] for %s", entry.line, kind.name)
+			}
+			position, err := parseVAPosition(entry)
+			if err != nil {
+				return nil, err
+			}
+			if position == nil {
+				continue
+			}
+			kind.frames[FrameID{Group: entry.index[0], Number: entry.index[1]}] = &Frame{
+				X: position.X, Y: position.Y, Z: position.Z,
+				W: position.W, P: position.P, R: position.R,
+			}
+		}
+	}
+
+	// The selected frames are optional; older backups do not have them
+	for _, name := range []string{"$MNUFRAMENUM", "$MNUTOOLNUM"} {
+		section := findVASection(sections, name)
+		if section == nil {
+			continue
+		}
+		for _, entry := range section.entries {
+			number, err := strconv.Atoi(entry.value)
+			if len(entry.index) != 1 || err != nil {
+				return nil, fmt.Errorf("line %d: bad %s element", entry.line, name)
+			}
+			group := entry.index[0]
+			active := frames.Active[group]
+			if name == "$MNUFRAMENUM" {
+				active.UF = number
+			} else {
+				active.UT = number
+			}
+			frames.Active[group] = active
+		}
+	}
+
+	return frames, nil
+}
+
+var (
+	vaConfig    = regexp.MustCompile(`Config\s*:\s*(.+?)\s*$`)
+	vaComponent = regexp.MustCompile(`\b([XYZWPR]|E[0-9])\s*:\s*(\S+)`)
+	vaJoint     = regexp.MustCompile(`\bJ([0-9])\s*=\s*(\S+)`)
+)
+
+// parseVAPosition parses the lines of a position element, such as
+//
+//	Group: 1   Config: N U T, 0, 0, 0
+//	X:   1500.000   Y:      0.000   Z:   1200.000
+//	W:    180.000   P:      0.000   R:      0.000
+//
+// or joints given as "J1 =  0.000 deg". It returns nil for an uninitialized
+// position.
+func parseVAPosition(entry vaEntry) (*Position, error) {
+	text, _, err := splitVAComment(entry.value)
+	if err != nil {
+		return nil, fmt.Errorf("line %d: %w", entry.line, err)
+	}
+	if strings.Contains(text, "Uninitialized") {
+		return nil, nil
+	}
+	for _, line := range entry.lines {
+		if strings.Contains(line, "Uninitialized") {
+			return nil, nil
+		}
+	}
+
+	position := &Position{Representation: RepresentationCartesian}
+	components := make(map[string]float32)
+	var joints []float32
+	for _, line := range entry.lines {
+		if match := vaConfig.FindStringSubmatch(line); match != nil {
+			config, err := ParseConfiguration(match[1])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", entry.line, err)
+			}
+			position.Config = config
+		}
+		for _, match := range vaComponent.FindAllStringSubmatch(line, -1) {
+			value, err := strconv.ParseFloat(match[2], 32)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: bad %s value %q", entry.line, match[1], match[2])
+			}
+			components[match[1]] = float32(value)
+		}
+		for _, match := range vaJoint.FindAllStringSubmatch(line, -1) {
+			n, _ := strconv.Atoi(match[1])
+			value, err := strconv.ParseFloat(match[2], 32)
+			if err != nil || n != len(joints)+1 {
+				return nil, fmt.Errorf("line %d: bad J%s value %q", entry.line, match[1], match[2])
+			}
+			joints = append(joints, float32(value))
+		}
+	}
+
+	if len(joints) > 0 {
+		return &Position{Representation: RepresentationJoint, Joints: joints}, nil
+	}
+
+	axes := make([]float32, len(positionAxes))
+	for i, axis := range positionAxes {
+		value, ok := components[axis]
+		if !ok {
+			return nil, fmt.Errorf("line %d: position has no %s", entry.line, axis)
+		}
+		axes[i] = value
+	}
+	position.X, position.Y, position.Z = axes[0], axes[1], axes[2]
+	position.W, position.P, position.R = axes[3], axes[4], axes[5]
+	for i := 1; i <= maxExtensionAxes; i++ {
+		value, ok := components[fmt.Sprintf("E%d", i)]
+		if !ok {
+			break
+		}
+		position.Extensions = append(position.Extensions, value)
+	}
+	return position, nil
+}
+
+// VASnapshot builds a snapshot of one motion group from parsed .VA files, so
+// a backup can be compared with a live controller using DiffSnapshots. Any
+// of the arguments may be nil. Uninitialized positions are left out.
+func VASnapshot(group int, numeric []NumericRegister, positions []PositionRegister, frames *SystemFrames) *Snapshot {
+	if group == 0 {
+		group = 1
+	}
+	snapshot := &Snapshot{
+		Group:      group,
+		Numeric:    make(map[int]NumericValue, len(numeric)),
+		Positions:  make(map[int]*Position, len(positions)),
+		UserFrames: make(map[int]*Frame),
+		ToolFrames: make(map[int]*Frame),
+	}
+
+	for _, register := range numeric {
+		snapshot.Numeric[register.Index] = register.Value
+	}
+	for _, register := range positions {
+		if register.Position != nil && register.Position.Group == group {
+			snapshot.Positions[register.Index] = register.Position
+		}
+	}
+	if frames != nil {
+		for id, frame := range frames.UserFrames {
+			if id.Group == group {
+				snapshot.UserFrames[id.Number] = frame
+			}
+		}
+		for id, frame := range frames.ToolFrames {
+			if id.Group == group {
+				snapshot.ToolFrames[id.Number] = frame
+			}
+		}
+	}
+	return snapshot
+}
@@ -0,0 +1,164 @@
+package fanuc
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const numRegVA = `
+[*NUMREG*]$MAXREGNUM  Storage: CMOS  Access: RW  : INTEGER = 200
+
+[*NUMREG*]$NUMREG  Storage: CMOS  Access: RW  : ARRAY[200] OF Numeric Reg
+ [1] = 12  'Part count'
+ [2] = 1.500000e+00  'Speed'
+ [3] = -4  ''
+ [4] = 0.000000e+00  'Zero'
+`
+
+func TestParseNumRegVA(t *testing.T) {
+	registers, err := ParseNumRegVA(strings.NewReader(numRegVA))
+	if err != nil {
+		t.Fatalf("ParseNumRegVA returned error: %v", err)
+	}
+
+	expected := []NumericRegister{
+		{Index: 1, Value: IntegerValue(12), Comment: "Part count"},
+		{Index: 2, Value: RealValue(1.5), Comment: "Speed"},
+		{Index: 3, Value: IntegerValue(-4)},
+		{Index: 4, Value: RealValue(0), Comment: "Zero"},
+	}
+	if !reflect.DeepEqual(registers, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, registers)
+	}
+
+	for _, bad := range []string{
+		"[*NUMREG*]$MAXREGNUM  Storage: CMOS  Access: RW  : INTEGER = 200\n",
+		"[*NUMREG*]$NUMREG  Storage: CMOS\n [1] = abc  ''\n",
+		"[*NUMREG*]$NUMREG  Storage: CMOS\n [1] = 1  'open\n",
+		"[*NUMREG*]$NUMREG  Storage: CMOS\n [1,1] = 1  ''\n",
+	} {
+		if _, err := ParseNumRegVA(strings.NewReader(bad)); err == nil {
+			t.Errorf("Expected error for %q", bad)
+		}
+	}
+}
+
+const posRegVA = `
+[*POSREG*]$POSREG  Storage: CMOS  Access: RW  : ARRAY[2,100] OF Position Reg
+ [1,1] = 'HOME'
+  Group: 1   Config: N U T, 0, 0, 0
+  X:   1500.000   Y:      0.000   Z:   1200.000
+  W:    180.000   P:      0.000   R:    -90.000
+ [1,2] = ''   Uninitialized
+ [1,3] = 'PERCH'
+  Group: 1   Config: F D B, 1, 0, -1
+  X:    800.500   Y:   -200.000   Z:    300.000
+  W:   -180.000   P:      5.000   R:     45.000
+  E1:   1000.000   E2:     90.000
+ [2,1] = 'TABLE'
+  Group: 2
+  J1 =     0.000 deg   J2 =    45.000 deg
+`
+
+func TestParsePosRegVA(t *testing.T) {
+	registers, err := ParsePosRegVA(strings.NewReader(posRegVA))
+	if err != nil {
+		t.Fatalf("ParsePosRegVA returned error: %v", err)
+	}
+
+	expected := []PositionRegister{
+		{Index: 1, Comment: "HOME", Position: &Position{
+			Group: 1, X: 1500, Z: 1200, W: 180, R: -90,
+		}},
+		{Index: 2},
+		{Index: 3, Comment: "PERCH", Position: &Position{
+			Group: 1, X: 800.5, Y: -200, Z: 300, W: -180, P: 5, R: 45,
+			Config:     Configuration{Flip: true, Down: true, Back: true, Turns: [3]int8{1, 0, -1}},
+			Extensions: []float32{1000, 90},
+		}},
+		{Index: 1, Comment: "TABLE", Position: &Position{
+			Representation: RepresentationJoint, Group: 2, Joints: []float32{0, 45},
+		}},
+	}
+	if !reflect.DeepEqual(registers, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, registers)
+	}
+
+	bad := "[*POSREG*]$POSREG  Storage: CMOS\n [1,1] = ''\n  X: 1 Y: 2 Z: 3\n"
+	if _, err := ParsePosRegVA(strings.NewReader(bad)); err == nil {
+		t.Error("Expected error for a position without W, P and R")
+	}
+}
+
+const sysFrameVA = `
+[*SYSTEM*]$MNUFRAME  Storage: SHADOW  Access: RW  : ARRAY[1,9] OF POSITION
+ [1,1] =
+  Group: 1   Config: N U T, 0, 0, 0
+  X:    100.000   Y:    200.000   Z:      0.000
+  W:      0.000   P:      0.000   R:     90.000
+ [1,2] = Uninitialized
+
+[*SYSTEM*]$MNUFRAMENUM  Storage: SHADOW  Access: RW  : ARRAY[1] OF BYTE
+ [1] = 1
+
+[*SYSTEM*]$MNUTOOL  Storage: SHADOW  Access: RW  : ARRAY[1,10] OF POSITION
+ [1,3] =
+  Group: 1   Config: N U T, 0, 0, 0
+  X:      0.500   Y:     -1.200   Z:    245.800
+  W:    180.000   P:      0.000   R:      0.000
+
+[*SYSTEM*]$MNUTOOLNUM  Storage: SHADOW  Access: RW  : ARRAY[1] OF BYTE
+ [1] = 3
+`
+
+func TestParseSysFrameVA(t *testing.T) {
+	frames, err := ParseSysFrameVA(strings.NewReader(sysFrameVA))
+	if err != nil {
+		t.Fatalf("ParseSysFrameVA returned error: %v", err)
+	}
+
+	expected := &SystemFrames{
+		UserFrames: map[FrameID]*Frame{{Group: 1, Number: 1}: {X: 100, Y: 200, R: 90}},
+		ToolFrames: map[FrameID]*Frame{{Group: 1, Number: 3}: {X: 0.5, Y: -1.2, Z: 245.8, W: 180}},
+		Active:     map[int]FrameNumbers{1: {UF: 1, UT: 3}},
+	}
+	if !reflect.DeepEqual(frames, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, frames)
+	}
+
+	if _, err := ParseSysFrameVA(strings.NewReader(numRegVA)); err == nil {
+		t.Error("Expected error for a file without frames")
+	}
+}
+
+func TestVASnapshot(t *testing.T) {
+	numeric, err := ParseNumRegVA(strings.NewReader(numRegVA))
+	if err != nil {
+		t.Fatal(err)
+	}
+	positions, err := ParsePosRegVA(strings.NewReader(posRegVA))
+	if err != nil {
+		t.Fatal(err)
+	}
+	frames, err := ParseSysFrameVA(strings.NewReader(sysFrameVA))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backup := VASnapshot(1, numeric, positions, frames)
+	if len(backup.Numeric) != 4 || len(backup.Positions) != 2 || backup.Positions[3].X != 800.5 {
+		t.Errorf("Unexpected snapshot %+v", backup)
+	}
+	if backup.UserFrames[1].Y != 200 || backup.ToolFrames[3].Z != 245.8 {
+		t.Errorf("Unexpected frames %+v %+v", backup.UserFrames, backup.ToolFrames)
+	}
+
+	// Compare the backup with a live snapshot
+	live := VASnapshot(1, numeric, positions, frames)
+	live.Numeric[1] = IntegerValue(13)
+	diffs := DiffSnapshots(backup, live, 0.001)
+	if len(diffs) != 1 || diffs[0].String() != "R[1]: 12 -> 13" {
+		t.Errorf("Unexpected differences %v", diffs)
+	}
+}
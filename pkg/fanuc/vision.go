@@ -0,0 +1,128 @@
+package fanuc
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/carun/cpppo-go/pkg/cpppo"
+)
+
+// maxVisionMeasurements is the number of measurement values in a vision
+// register
+const maxVisionMeasurements = 10
+
+// ErrVisionRegisterReadOnly is returned when writing a vision register; only
+// the vision process sets them
+var ErrVisionRegisterReadOnly = errors.New("vision registers are read-only")
+
+// VisionOffsetType tells how a vision result offsets robot positions
+type VisionOffsetType int
+
+const (
+	VisionFixedFrameOffset VisionOffsetType = iota // Offset in a fixed user frame
+	VisionToolOffset                               // Offset of the part in the tool
+	VisionFoundPosition                            // Found position, no offset
+)
+
+// String returns the name of the offset type as iRVision shows it
+func (t VisionOffsetType) String() string {
+	switch t {
+	case VisionFixedFrameOffset:
+		return "Fixed Frame Offset"
+	case VisionToolOffset:
+		return "Tool Offset"
+	case VisionFoundPosition:
+		return "Found Position"
+	default:
+		return fmt.Sprintf("VisionOffsetType(%d)", int(t))
+	}
+}
+
+// VisionResult is the content of a vision register (VR), as stored by an
+// iRVision process
+type VisionResult struct {
+	OffsetType   VisionOffsetType
+	Frame        int       // User or tool frame of the offset
+	ModelID      int       // Model ID of the found part
+	Encoder      int32     // Conveyor encoder count when the image was taken
+	Found        Frame     // Found position of the part
+	Offset       Frame     // Offset to apply to taught positions
+	Measurements []float32 // Measurement values 1-10
+}
+
+// ReadVisionRegister reads a vision register in as few requests as fit the
+// unconnected message limit when the client can batch. Its members do not fit
+// in one Multiple Service Packet, so they are not read in one controller
+// scan; read it only after the vision process has finished.
+func (f *FanucClient) ReadVisionRegister(index int) (*VisionResult, error) {
+	base := buildRegisterTag(RegisterTypeVR, index)
+
+	reads := []cpppo.TagRead{
+		{Name: base + ".TYPE", DataType: cpppo.CIPDataTypeDINT},
+		{Name: base + ".FRAME", DataType: cpppo.CIPDataTypeDINT},
+		{Name: base + ".MODELID", DataType: cpppo.CIPDataTypeDINT},
+		{Name: base + ".ENC", DataType: cpppo.CIPDataTypeDINT},
+	}
+	for _, part := range []string{"FOUND", "OFFSET"} {
+		for _, axis := range positionAxes {
+			reads = append(reads, cpppo.TagRead{Name: base + "." + part + "." + axis, DataType: cpppo.CIPDataTypeREAL})
+		}
+	}
+	for i := 1; i <= maxVisionMeasurements; i++ {
+		reads = append(reads, cpppo.TagRead{Name: fmt.Sprintf("%s.MES[%d]", base, i), DataType: cpppo.CIPDataTypeREAL})
+	}
+
+	results, err := f.readBatches(reads)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", base, err)
+	}
+	for i, result := range results {
+		if result.Err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", reads[i].Name, result.Err)
+		}
+	}
+
+	// Typed accessors; the first type error is kept in err
+	typeError := func(i int) {
+		if err == nil {
+			err = fmt.Errorf("unexpected type %T for %s", results[i].Value, reads[i].Name)
+		}
+	}
+	dint := func(i int) int32 {
+		value, ok := results[i].Value.(int32)
+		if !ok {
+			typeError(i)
+		}
+		return value
+	}
+	float := func(i int) float32 {
+		value, ok := results[i].Value.(float32)
+		if !ok {
+			typeError(i)
+		}
+		return value
+	}
+	frame := func(from int) Frame {
+		return Frame{
+			X: float(from), Y: float(from + 1), Z: float(from + 2),
+			W: float(from + 3), P: float(from + 4), R: float(from + 5),
+		}
+	}
+
+	result := &VisionResult{
+		OffsetType: VisionOffsetType(dint(0)),
+		Frame:      int(dint(1)),
+		ModelID:    int(dint(2)),
+		Encoder:    dint(3),
+		Found:      frame(4),
+		Offset:     frame(4 + len(positionAxes)),
+	}
+	for i := 4 + 2*len(positionAxes); i < len(reads); i++ {
+		result.Measurements = append(result.Measurements, float(i))
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,68 @@
+package fanuc
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestReadVisionRegister(t *testing.T) {
+	mock := &batchMockPLCClient{mockPLCClient: newMockPLCClient()}
+	client := &FanucClient{PLCClient: mock}
+
+	mock.readResponses["VR[2].TYPE"] = int32(VisionFixedFrameOffset)
+	mock.readResponses["VR[2].FRAME"] = int32(3)
+	mock.readResponses["VR[2].MODELID"] = int32(12)
+	mock.readResponses["VR[2].ENC"] = int32(-40000)
+	for i, axis := range positionAxes {
+		mock.readResponses["VR[2].FOUND."+axis] = float32(100 + i)
+		mock.readResponses["VR[2].OFFSET."+axis] = float32(i) / 2
+	}
+	for i := 1; i <= maxVisionMeasurements; i++ {
+		mock.readResponses[fmt.Sprintf("VR[2].MES[%d]", i)] = float32(i) * 1.5
+	}
+
+	value, err := client.ReadRegister(RegisterTypeVR, 2)
+	if err != nil {
+		t.Fatalf("Failed to read VR[2]: %v", err)
+	}
+	// The 26 members take more than one 504 byte packet
+	if mock.batches != 2 {
+		t.Errorf("Expected the register in two requests, got %d", mock.batches)
+	}
+
+	result := value.(*VisionResult)
+	if result.OffsetType != VisionFixedFrameOffset || result.Frame != 3 ||
+		result.ModelID != 12 || result.Encoder != -40000 {
+		t.Errorf("Unexpected header %+v", result)
+	}
+	if result.Found != (Frame{100, 101, 102, 103, 104, 105}) {
+		t.Errorf("Unexpected found position %+v", result.Found)
+	}
+	if result.Offset != (Frame{0, 0.5, 1, 1.5, 2, 2.5}) {
+		t.Errorf("Unexpected offset %+v", result.Offset)
+	}
+	if len(result.Measurements) != maxVisionMeasurements || result.Measurements[9] != 15 {
+		t.Errorf("Unexpected measurements %v", result.Measurements)
+	}
+	if result.OffsetType.String() != "Fixed Frame Offset" {
+		t.Errorf("Unexpected offset type %q", result.OffsetType)
+	}
+
+	// Members of the wrong type are reported
+	mock.readResponses["VR[2].MODELID"] = float32(12)
+	if _, err := client.ReadVisionRegister(2); err == nil {
+		t.Error("Expected error for a REAL model ID")
+	}
+	if _, err := client.ReadVisionRegister(3); err == nil {
+		t.Error("Expected error for missing register")
+	}
+
+	// Vision registers are set by the vision process only
+	if err := client.WriteRegister(RegisterTypeVR, 2, &VisionResult{}); !errors.Is(err, ErrVisionRegisterReadOnly) {
+		t.Errorf("Expected ErrVisionRegisterReadOnly, got %v", err)
+	}
+	if _, err := client.WriteRegisters(RegisterTypeVR, 1, []interface{}{"x"}); !errors.Is(err, ErrVisionRegisterReadOnly) {
+		t.Errorf("Expected ErrVisionRegisterReadOnly from WriteRegisters, got %v", err)
+	}
+}